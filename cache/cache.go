@@ -1,12 +1,16 @@
 package cache
 
 import (
-	"sync"
+	"fmt"
 	"time"
 
 	"github.com/gotd/td/tg"
-	"github.com/karlseguin/ccache/v3"
+	"github.com/rs/zerolog"
+	"github.com/xeptore/flaw/v8"
+	bolt "go.etcd.io/bbolt"
 
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/must"
 	"github.com/xeptore/tgtd/tidal"
 )
 
@@ -15,108 +19,107 @@ var (
 	DefaultAlbumTTL           = 1 * time.Hour
 	DefaultUploadedCoverTTL   = 1 * time.Hour
 	DefaultTrackCreditsTTL    = 1 * time.Hour
+
+	DefaultDownloadedCoversMaxSize = 100
+	DefaultAlbumsMetaMaxSize       = 1000
+	DefaultUploadedCoversMaxSize   = 100
+	DefaultTrackCreditsMaxSize     = 10_000
 )
 
-type Cache struct {
-	AlbumsMeta       AlbumsMetaCache
-	DownloadedCovers DownloadedCoversCache
-	UploadedCovers   UploadedCoversCache
-	TrackCredits     TrackCreditsCache
+// Options configures the cache of a kind of entries.
+type Options struct {
+	TTL time.Duration
+	// MaxSize is the maximum number of entries kept in memory, and on disk.
+	MaxSize int
 }
 
-func New() *Cache {
-	albumsMetaCache := ccache.New(
-		ccache.Configure[*tidal.AlbumMeta]().
-			MaxSize(1000).
-			GetsPerPromote(3).
-			ItemsToPrune(1),
-	)
-
-	downloadedCoversCache := ccache.New(
-		ccache.Configure[[]byte]().
-			MaxSize(100).
-			GetsPerPromote(3).
-			ItemsToPrune(1),
-	)
-
-	uploadedCoversCache := ccache.New(
-		ccache.Configure[tg.InputFileClass]().
-			MaxSize(100).
-			GetsPerPromote(3).
-			ItemsToPrune(1),
-	)
-
-	trackCreditsCache := ccache.New(
-		ccache.Configure[*tidal.TrackCredits]().
-			MaxSize(10_000).
-			GetsPerPromote(3).
-			ItemsToPrune(1),
-	)
+type Config struct {
+	AlbumsMeta       Options
+	DownloadedCovers Options
+	UploadedCovers   Options
+	TrackCredits     Options
+}
 
-	return &Cache{
-		AlbumsMeta: AlbumsMetaCache{
-			c:   albumsMetaCache,
-			mux: sync.Mutex{},
-		},
-		DownloadedCovers: DownloadedCoversCache{
-			c:   downloadedCoversCache,
-			mux: sync.Mutex{},
-		},
-		UploadedCovers: UploadedCoversCache{
-			c:   uploadedCoversCache,
-			mux: sync.Mutex{},
-		},
-		TrackCredits: TrackCreditsCache{
-			c:   trackCreditsCache,
-			mux: sync.Mutex{},
-		},
+func DefaultConfig() Config {
+	return Config{
+		AlbumsMeta:       Options{TTL: DefaultAlbumTTL, MaxSize: DefaultAlbumsMetaMaxSize},
+		DownloadedCovers: Options{TTL: DefaultDownloadedCoverTTL, MaxSize: DefaultDownloadedCoversMaxSize},
+		UploadedCovers:   Options{TTL: DefaultUploadedCoverTTL, MaxSize: DefaultUploadedCoversMaxSize},
+		TrackCredits:     Options{TTL: DefaultTrackCreditsTTL, MaxSize: DefaultTrackCreditsMaxSize},
 	}
 }
 
-type UploadedCoversCache struct {
-	c   *ccache.Cache[tg.InputFileClass]
-	mux sync.Mutex
-}
+type (
+	AlbumsMetaCache       = Store[*tidal.AlbumMeta]
+	DownloadedCoversCache = Store[[]byte]
+	UploadedCoversCache   = Store[tg.InputFileClass]
+	TrackCreditsCache     = Store[*tidal.TrackCredits]
+)
 
-func (c *UploadedCoversCache) Fetch(k string, ttl time.Duration, fetch func() (tg.InputFileClass, error)) (*ccache.Item[tg.InputFileClass], error) {
-	c.mux.Lock()
-	defer c.mux.Unlock()
-	return c.c.Fetch(k, ttl, fetch)
+// Cache holds caches of all kinds of entries, persisted to a single database
+// file, so that they survive restarts.
+type Cache struct {
+	db               *bolt.DB
+	AlbumsMeta       *AlbumsMetaCache
+	DownloadedCovers *DownloadedCoversCache
+	UploadedCovers   *UploadedCoversCache
+	TrackCredits     *TrackCreditsCache
 }
 
-type DownloadedCoversCache struct {
-	c   *ccache.Cache[[]byte]
-	mux sync.Mutex
-}
+// Open opens the cache database file at filePath, creating it if it does not
+// exist.
+func Open(filePath string, cfg Config, logger zerolog.Logger) (*Cache, error) {
+	db, err := bolt.Open(filePath, 0o0600, &bolt.Options{Timeout: 5 * time.Second}) //nolint:exhaustruct
+	if nil != err {
+		flawP := flaw.P{"file_path": filePath, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return nil, flaw.From(fmt.Errorf("failed to open cache database: %v", err)).Append(flawP)
+	}
 
-func (c *DownloadedCoversCache) Fetch(k string, ttl time.Duration, fetch func() ([]byte, error)) (*ccache.Item[[]byte], error) {
-	c.mux.Lock()
-	defer c.mux.Unlock()
-	return c.c.Fetch(k, ttl, fetch)
+	c, err := open(db, cfg, logger)
+	if nil != err {
+		if closeErr := db.Close(); nil != closeErr {
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(closeErr).FlawP()}
+			return nil, must.BeFlaw(err).Join(flaw.From(fmt.Errorf("failed to close cache database: %v", closeErr)).Append(flawP))
+		}
+		return nil, err
+	}
+	return c, nil
 }
 
-type AlbumsMetaCache struct {
-	c   *ccache.Cache[*tidal.AlbumMeta]
-	mux sync.Mutex
-}
+func open(db *bolt.DB, cfg Config, logger zerolog.Logger) (*Cache, error) {
+	albumsMeta, err := newStore(db, "albums_meta", cfg.AlbumsMeta, jsonCodec[tidal.AlbumMeta](), logger)
+	if nil != err {
+		return nil, err
+	}
 
-func (c *AlbumsMetaCache) Fetch(k string, ttl time.Duration, fetch func() (*tidal.AlbumMeta, error)) (*ccache.Item[*tidal.AlbumMeta], error) {
-	c.mux.Lock()
-	defer c.mux.Unlock()
-	return c.c.Fetch(k, ttl, fetch)
-}
+	downloadedCovers, err := newStore(db, "downloaded_covers", cfg.DownloadedCovers, bytesCodec, logger)
+	if nil != err {
+		return nil, err
+	}
 
-type TrackCreditsCache struct {
-	c   *ccache.Cache[*tidal.TrackCredits]
-	mux sync.Mutex
-}
+	uploadedCovers, err := newStore(db, "uploaded_covers", cfg.UploadedCovers, inputFileCodec, logger)
+	if nil != err {
+		return nil, err
+	}
+
+	trackCredits, err := newStore(db, "track_credits", cfg.TrackCredits, jsonCodec[tidal.TrackCredits](), logger)
+	if nil != err {
+		return nil, err
+	}
 
-func (c *TrackCreditsCache) Fetch(k string, ttl time.Duration, fetch func() (*tidal.TrackCredits, error)) (*ccache.Item[*tidal.TrackCredits], error) {
-	c.mux.Lock()
-	defer c.mux.Unlock()
-	return c.c.Fetch(k, ttl, fetch)
+	return &Cache{
+		db:               db,
+		AlbumsMeta:       albumsMeta,
+		DownloadedCovers: downloadedCovers,
+		UploadedCovers:   uploadedCovers,
+		TrackCredits:     trackCredits,
+	}, nil
 }
 
-func (c *TrackCreditsCache) Set(k string, v *tidal.TrackCredits, ttl time.Duration) {
-	c.c.Set(k, v, ttl)
+func (c *Cache) Close() error {
+	if err := c.db.Close(); nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to close cache database: %v", err)).Append(flawP)
+	}
+	return nil
 }
@@ -0,0 +1,213 @@
+package cache_test
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/cache"
+	"github.com/xeptore/tgtd/tidal"
+)
+
+func open(t *testing.T, filePath string, cfg cache.Config) *cache.Cache {
+	t.Helper()
+
+	c, err := cache.Open(filePath, cfg, zerolog.Nop())
+	require.NoError(t, err)
+	return c
+}
+
+func failingFetch[T any](t *testing.T) func() (T, error) {
+	t.Helper()
+
+	return func() (T, error) {
+		var zero T
+		t.Error("unexpected fetch")
+		return zero, errors.New("unexpected fetch")
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	t.Parallel()
+
+	t.Run("entries_survive_reopen", func(t *testing.T) {
+		t.Parallel()
+
+		filePath := filepath.Join(t.TempDir(), "cache.db")
+		album := &tidal.AlbumMeta{
+			Artist:       "Artist",
+			Title:        "Title",
+			ReleaseDate:  time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+			CoverID:      "cover",
+			TotalTracks:  10,
+			TotalVolumes: 1,
+		}
+		cover := &tg.InputFileBig{ID: 42, Parts: 3, Name: "cover.jpg"}
+
+		c := open(t, filePath, cache.DefaultConfig())
+		_, err := c.AlbumsMeta.Fetch("1", time.Hour, func() (*tidal.AlbumMeta, error) { return album, nil })
+		require.NoError(t, err)
+		_, err = c.UploadedCovers.Fetch("cover", time.Hour, func() (tg.InputFileClass, error) { return cover, nil })
+		require.NoError(t, err)
+		c.DownloadedCovers.Set("cover", []byte("image"), time.Hour)
+		require.NoError(t, c.Close())
+
+		c = open(t, filePath, cache.DefaultConfig())
+		defer func() { require.NoError(t, c.Close()) }()
+
+		albumItem, err := c.AlbumsMeta.Fetch("1", time.Hour, failingFetch[*tidal.AlbumMeta](t))
+		require.NoError(t, err)
+		assert.Equal(t, album, albumItem.Value())
+		assert.LessOrEqual(t, albumItem.TTL(), time.Hour)
+
+		coverItem, err := c.UploadedCovers.Fetch("cover", time.Hour, failingFetch[tg.InputFileClass](t))
+		require.NoError(t, err)
+		assert.Equal(t, cover, coverItem.Value())
+
+		bytesItem, err := c.DownloadedCovers.Fetch("cover", time.Hour, failingFetch[[]byte](t))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("image"), bytesItem.Value())
+	})
+
+	t.Run("expired_entries_are_fetched_again", func(t *testing.T) {
+		t.Parallel()
+
+		filePath := filepath.Join(t.TempDir(), "cache.db")
+		c := open(t, filePath, cache.DefaultConfig())
+		c.DownloadedCovers.Set("cover", []byte("old"), time.Millisecond)
+		require.NoError(t, c.Close())
+		time.Sleep(10 * time.Millisecond)
+
+		c = open(t, filePath, cache.DefaultConfig())
+		defer func() { require.NoError(t, c.Close()) }()
+
+		item, err := c.DownloadedCovers.Fetch("cover", time.Hour, func() ([]byte, error) { return []byte("new"), nil })
+		require.NoError(t, err)
+		assert.Equal(t, []byte("new"), item.Value())
+	})
+
+	t.Run("max_size_prunes_soonest_expiring", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := cache.DefaultConfig()
+		cfg.DownloadedCovers.MaxSize = 10
+		filePath := filepath.Join(t.TempDir(), "cache.db")
+
+		c := open(t, filePath, cfg)
+		for i := range 11 {
+			c.DownloadedCovers.Set(string(rune('a'+i)), []byte{byte(i)}, time.Duration(i+1)*time.Hour)
+		}
+		require.NoError(t, c.Close())
+
+		c = open(t, filePath, cfg)
+		defer func() { require.NoError(t, c.Close()) }()
+
+		item, err := c.DownloadedCovers.Fetch("a", time.Hour, func() ([]byte, error) { return []byte("fetched"), nil })
+		require.NoError(t, err)
+		assert.Equal(t, []byte("fetched"), item.Value())
+
+		item, err = c.DownloadedCovers.Fetch("k", time.Hour, failingFetch[[]byte](t))
+		require.NoError(t, err)
+		assert.Equal(t, []byte{10}, item.Value())
+	})
+}
+
+func TestFetchConcurrency(t *testing.T) {
+	t.Parallel()
+
+	t.Run("same_key_shares_fetch", func(t *testing.T) {
+		t.Parallel()
+
+		c := open(t, filepath.Join(t.TempDir(), "cache.db"), cache.DefaultConfig())
+		defer func() { require.NoError(t, c.Close()) }()
+
+		var (
+			calls   atomic.Int32
+			release = make(chan struct{})
+			wg      sync.WaitGroup
+			values  = make([][]byte, 20)
+		)
+		for i := range values {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				item, err := c.DownloadedCovers.Fetch("cover", time.Hour, func() ([]byte, error) {
+					calls.Add(1)
+					<-release
+					return []byte("image"), nil
+				})
+				assert.NoError(t, err)
+				values[i] = item.Value()
+			}()
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), calls.Load())
+		for _, v := range values {
+			assert.Equal(t, []byte("image"), v)
+		}
+	})
+
+	t.Run("different_keys_run_in_parallel", func(t *testing.T) {
+		t.Parallel()
+
+		c := open(t, filepath.Join(t.TempDir(), "cache.db"), cache.DefaultConfig())
+		defer func() { require.NoError(t, c.Close()) }()
+
+		var (
+			aStarted = make(chan struct{})
+			bStarted = make(chan struct{})
+			wg       sync.WaitGroup
+		)
+		// Each fetch waits for the other one to start, which never happens if
+		// fetches of different keys are serialized.
+		fetch := func(started, other chan struct{}) func() ([]byte, error) {
+			return func() ([]byte, error) {
+				close(started)
+				select {
+				case <-other:
+					return []byte("image"), nil
+				case <-time.After(5 * time.Second):
+					return nil, errors.New("fetches were serialized")
+				}
+			}
+		}
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := c.DownloadedCovers.Fetch("a", time.Hour, fetch(aStarted, bStarted))
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := c.DownloadedCovers.Fetch("b", time.Hour, fetch(bStarted, aStarted))
+			assert.NoError(t, err)
+		}()
+		wg.Wait()
+	})
+
+	t.Run("failed_fetch_is_not_cached", func(t *testing.T) {
+		t.Parallel()
+
+		c := open(t, filepath.Join(t.TempDir(), "cache.db"), cache.DefaultConfig())
+		defer func() { require.NoError(t, c.Close()) }()
+
+		_, err := c.DownloadedCovers.Fetch("cover", time.Hour, func() ([]byte, error) { return nil, errors.New("failed") })
+		require.Error(t, err)
+
+		item, err := c.DownloadedCovers.Fetch("cover", time.Hour, func() ([]byte, error) { return []byte("image"), nil })
+		require.NoError(t, err)
+		assert.Equal(t, []byte("image"), item.Value())
+	})
+}
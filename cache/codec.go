@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"slices"
+
+	"github.com/goccy/go-json"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+)
+
+// codec serializes entries of a store for the database.
+type codec[T any] struct {
+	encode func(v T) ([]byte, error)
+	decode func(b []byte) (T, error)
+}
+
+func jsonCodec[T any]() codec[*T] {
+	return codec[*T]{
+		encode: func(v *T) ([]byte, error) {
+			return json.Marshal(v)
+		},
+		decode: func(b []byte) (*T, error) {
+			var v T
+			if err := json.Unmarshal(b, &v); nil != err {
+				return nil, err
+			}
+			return &v, nil
+		},
+	}
+}
+
+var bytesCodec = codec[[]byte]{
+	encode: func(v []byte) ([]byte, error) {
+		return v, nil
+	},
+	decode: func(b []byte) ([]byte, error) {
+		return slices.Clone(b), nil
+	},
+}
+
+var inputFileCodec = codec[tg.InputFileClass]{
+	encode: func(v tg.InputFileClass) ([]byte, error) {
+		var buf bin.Buffer
+		if err := v.Encode(&buf); nil != err {
+			return nil, err
+		}
+		return buf.Raw(), nil
+	},
+	decode: func(b []byte) (tg.InputFileClass, error) {
+		return tg.DecodeInputFile(&bin.Buffer{Buf: slices.Clone(b)})
+	},
+}
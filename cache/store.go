@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/karlseguin/ccache/v3"
+	"github.com/rs/zerolog"
+	"github.com/xeptore/flaw/v8"
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/log"
+)
+
+// expiresSize is the size of the expiration time prefix of stored values.
+const expiresSize = 8
+
+// Store caches entries of a single kind in memory, backed by a bucket of the
+// cache database. Entries missing in memory, e.g., after a restart, are loaded
+// from the database before they are fetched.
+type Store[T any] struct {
+	mem     *ccache.Cache[T]
+	db      *bolt.DB
+	bucket  []byte
+	codec   codec[T]
+	ttl     time.Duration
+	maxSize int
+	// count is the number of entries in the bucket.
+	count  int
+	logger zerolog.Logger
+	group  singleflight.Group
+}
+
+func newStore[T any](db *bolt.DB, name string, opts Options, c codec[T], logger zerolog.Logger) (*Store[T], error) {
+	s := &Store[T]{
+		mem: ccache.New(
+			ccache.Configure[T]().
+				MaxSize(int64(opts.MaxSize)).
+				GetsPerPromote(3).
+				ItemsToPrune(1),
+		),
+		db:      db,
+		bucket:  []byte(name),
+		codec:   c,
+		ttl:     opts.TTL,
+		maxSize: opts.MaxSize,
+		count:   0,
+		logger:  logger.With().Str("bucket", name).Logger(),
+		group:   singleflight.Group{},
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(s.bucket)
+		if nil != err {
+			return err
+		}
+		s.count = b.Stats().KeyN
+		return s.prune(b, time.Now())
+	})
+	if nil != err {
+		flawP := flaw.P{"bucket": name, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return nil, flaw.From(fmt.Errorf("failed to initialize cache bucket: %v", err)).Append(flawP)
+	}
+	return s, nil
+}
+
+// TTL returns the configured time to live of entries.
+func (s *Store[T]) TTL() time.Duration {
+	return s.ttl
+}
+
+// Fetch returns the entry with key k, loading it from the database, or calling
+// fetch if it is missing or expired. Concurrent fetches of the same key share a
+// single call, while fetches of different keys proceed in parallel.
+func (s *Store[T]) Fetch(k string, ttl time.Duration, fetch func() (T, error)) (*ccache.Item[T], error) {
+	if item := s.mem.Get(k); nil != item && !item.Expired() {
+		return item, nil
+	}
+
+	res, err, _ := s.group.Do(k, func() (any, error) {
+		var stored time.Time
+		item, err := s.mem.Fetch(k, ttl, func() (T, error) {
+			if v, expires, ok := s.load(k); ok {
+				stored = expires
+				return v, nil
+			}
+
+			v, err := fetch()
+			if nil != err {
+				return v, err
+			}
+			s.persist(k, v, ttl)
+			return v, nil
+		})
+		if nil != err {
+			return nil, err
+		}
+		if !stored.IsZero() {
+			// Entries loaded from the database only live as long as they were stored for.
+			item.Extend(time.Until(stored))
+		}
+		return item, nil
+	})
+	if nil != err {
+		return nil, err
+	}
+	return res.(*ccache.Item[T]), nil //nolint:forcetypeassert
+}
+
+func (s *Store[T]) Set(k string, v T, ttl time.Duration) {
+	s.mem.Set(k, v, ttl)
+	s.persist(k, v, ttl)
+}
+
+// load returns the unexpired entry stored with key k, and its expiration time.
+// Entries which fail to load are treated as missing.
+func (s *Store[T]) load(k string) (v T, expires time.Time, ok bool) {
+	var b []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b = slices.Clone(tx.Bucket(s.bucket).Get([]byte(k)))
+		return nil
+	})
+	if nil != err {
+		flawP := flaw.P{"key": k, "err_debug_tree": errutil.Tree(err).FlawP()}
+		s.logger.Error().Func(log.Flaw(flaw.From(fmt.Errorf("failed to read cache entry: %v", err)).Append(flawP))).Msg("Failed to load cache entry")
+		return v, expires, false
+	}
+	if len(b) < expiresSize {
+		return v, expires, false
+	}
+
+	expires = time.Unix(0, int64(binary.BigEndian.Uint64(b[:expiresSize]))) //nolint:gosec
+	if !time.Now().Before(expires) {
+		return v, expires, false
+	}
+
+	v, err = s.codec.decode(b[expiresSize:])
+	if nil != err {
+		s.logger.Warn().Err(err).Str("key", k).Msg("Ignoring undecodable cache entry")
+		return v, expires, false
+	}
+	return v, expires, true
+}
+
+// persist stores v with key k in the database. Failing to persist an entry only
+// costs fetching it again after a restart, hence it is logged rather than
+// returned.
+func (s *Store[T]) persist(k string, v T, ttl time.Duration) {
+	encoded, err := s.codec.encode(v)
+	if nil != err {
+		s.logger.Error().Err(err).Str("key", k).Msg("Failed to encode cache entry")
+		return
+	}
+
+	b := make([]byte, expiresSize+len(encoded))
+	binary.BigEndian.PutUint64(b, uint64(time.Now().Add(ttl).UnixNano())) //nolint:gosec
+	copy(b[expiresSize:], encoded)
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(s.bucket)
+		exists := nil != bucket.Get([]byte(k))
+		if err := bucket.Put([]byte(k), b); nil != err {
+			return err
+		}
+		if !exists {
+			s.count++
+		}
+		if s.maxSize > 0 && s.count > s.maxSize {
+			return s.prune(bucket, time.Now())
+		}
+		return nil
+	})
+	if nil != err {
+		flawP := flaw.P{"key": k, "err_debug_tree": errutil.Tree(err).FlawP()}
+		s.logger.Error().Func(log.Flaw(flaw.From(fmt.Errorf("failed to write cache entry: %v", err)).Append(flawP))).Msg("Failed to persist cache entry")
+	}
+}
+
+// prune deletes expired entries, and then the ones expiring the soonest until a
+// tenth of the maximum size is free, so that it does not run on every write.
+func (s *Store[T]) prune(bucket *bolt.Bucket, now time.Time) error {
+	type stored struct {
+		key     []byte
+		expires int64
+	}
+
+	var (
+		entries []stored
+		expired [][]byte
+	)
+	err := bucket.ForEach(func(k, v []byte) error {
+		var expires int64
+		if len(v) >= expiresSize {
+			expires = int64(binary.BigEndian.Uint64(v[:expiresSize])) //nolint:gosec
+		}
+		if expires <= now.UnixNano() {
+			expired = append(expired, slices.Clone(k))
+		} else {
+			entries = append(entries, stored{key: slices.Clone(k), expires: expires})
+		}
+		return nil
+	})
+	if nil != err {
+		return err
+	}
+
+	if s.maxSize > 0 && len(entries) > s.maxSize {
+		slices.SortFunc(entries, func(a, b stored) int { return cmp.Compare(a.expires, b.expires) })
+		n := len(entries) - s.maxSize + s.maxSize/10
+		for _, e := range entries[:n] {
+			expired = append(expired, e.key)
+		}
+		entries = entries[n:]
+	}
+
+	for _, k := range expired {
+		if err := bucket.Delete(k); nil != err {
+			return err
+		}
+	}
+	s.count = len(entries)
+	return nil
+}
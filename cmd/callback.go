@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gotd/td/telegram/message/peer"
+	"github.com/gotd/td/tg"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/log"
+	"github.com/xeptore/tgtd/tidal"
+)
+
+const (
+	// callbackActionDownload is the action of inline buttons that queue a download.
+	callbackActionDownload = "dl"
+	// callbackActionDismiss is the action of inline buttons that delete the
+	// message they are attached to.
+	callbackActionDismiss = "x"
+)
+
+// downloadCallbackData returns data of an inline button that queues downloading
+// link. It is in the form of dl:<kind>/<id>[/<quality>[a]], where quality is
+// the index of link quality in tidal.Qualities, and a stands for Dolby Atmos,
+// so that it fits in the 64 bytes limit of callback data. Links without quality
+// are downloaded with the chat audio quality.
+func downloadCallbackData(link DownloadLink) []byte {
+	data := callbackActionDownload + ":" + link.Kind + "/" + link.ID
+	if link.Quality != "" {
+		data += "/" + strconv.Itoa(slices.Index(tidal.Qualities, link.Quality))
+		if link.Immersive {
+			data += "a"
+		}
+	}
+	return []byte(data)
+}
+
+func parseDownloadCallbackPayload(payload string) (*DownloadLink, error) {
+	parts := strings.Split(payload, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] == "" {
+		return nil, errors.New("invalid download callback payload")
+	}
+	switch parts[0] {
+	case "playlist", "album", "track", "mix", "artist", "video":
+	default:
+		return nil, fmt.Errorf("unsupported kind %q", parts[0])
+	}
+
+	link := DownloadLink{Kind: parts[0], ID: parts[1], Quality: "", Immersive: false, Selection: nil}
+	if len(parts) == 3 {
+		q, immersive := strings.CutSuffix(parts[2], "a")
+		idx, err := strconv.Atoi(q)
+		if nil != err || idx < 0 || idx >= len(tidal.Qualities) {
+			return nil, fmt.Errorf("invalid quality %q", parts[2])
+		}
+		link.Quality = tidal.Qualities[idx]
+		link.Immersive = immersive
+	}
+	return &link, nil
+}
+
+func parseCallbackData(data []byte) (action, payload string) {
+	action, payload, _ = strings.Cut(string(data), ":")
+	return action, payload
+}
+
+func (w *Worker) processCallback(ctx context.Context, e tg.Entities, q *tg.UpdateBotCallbackQuery) {
+	if !slices.Contains(w.config.FromIDs, q.UserID) {
+		w.answerCallback(ctx, q.QueryID, "You are not allowed to use this bot.")
+		return
+	}
+
+	inputPeer, err := peer.EntitiesFromUpdate(e).ExtractPeer(q.Peer)
+	if nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to extract callback query peer")
+		w.answerCallback(ctx, q.QueryID, "")
+		return
+	}
+	jobPeer, err := jobPeerFrom(inputPeer)
+	if nil != err {
+		w.logger.Error().Func(log.Flaw(err)).Msg("Failed to convert callback query peer")
+		w.answerCallback(ctx, q.QueryID, "")
+		return
+	}
+	reply := w.sender.To(inputPeer)
+
+	switch action, payload := parseCallbackData(q.Data); action {
+	case callbackActionDownload:
+		link, err := parseDownloadCallbackPayload(payload)
+		if nil != err {
+			w.logger.Warn().Err(err).Str("data", string(q.Data)).Msg("Invalid download callback data received")
+			w.answerCallback(ctx, q.QueryID, "Invalid button.")
+			return
+		}
+		w.answerCallback(ctx, q.QueryID, "")
+		w.enqueue(ctx, reply, QueuedJob{Link: *link, Peer: *jobPeer, SubscriptionID: ""})
+	case callbackActionDismiss:
+		w.answerCallback(ctx, q.QueryID, "")
+		if _, err := reply.Revoke().Messages(ctx, q.MsgID); nil != err {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return
+			}
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to delete message")
+		}
+	default:
+		w.logger.Warn().Str("data", string(q.Data)).Msg("Unsupported callback query received")
+		w.answerCallback(ctx, q.QueryID, "Unsupported button.")
+	}
+}
+
+// answerCallback stops the loading indicator of the tapped button, showing text
+// as a notification if it is not empty.
+func (w *Worker) answerCallback(ctx context.Context, queryID int64, text string) {
+	//nolint:exhaustruct
+	req := &tg.MessagesSetBotCallbackAnswerRequest{
+		QueryID: queryID,
+		Message: text,
+	}
+	if _, err := w.client.API().MessagesSetBotCallbackAnswer(ctx, req); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to answer callback query")
+	}
+}
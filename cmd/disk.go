@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/message/styling"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/log"
+	tidalfs "github.com/xeptore/tgtd/tidal/fs"
+)
+
+func (w *Worker) downloadDir() tidalfs.DownloadDir {
+	return tidalfs.DownloadDirFrom(w.config.DownloadBaseDir)
+}
+
+func (w *Worker) retentionPolicy() tidalfs.Policy {
+	return tidalfs.Policy{
+		MaxSize: w.config.DownloadMaxSizeMB * 1024 * 1024,
+		MaxAge:  w.config.DownloadMaxAge,
+	}
+}
+
+// queuedJobIDs returns IDs of jobs in the queue, including the running one.
+func (w *Worker) queuedJobIDs() []string {
+	items := w.queue.Items()
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+// sweepDownloads removes leftovers of interrupted downloads. Part files are only
+// kept if there are queued jobs which may resume them. It must only be called
+// before the worker loop is started.
+func (w *Worker) sweepDownloads() {
+	reclaimed, err := w.downloadDir().SweepIncomplete(len(w.queuedJobIDs()) > 0)
+	if nil != err {
+		w.logger.Error().Func(log.Flaw(err)).Msg("Failed to sweep incomplete downloads")
+		return
+	}
+	w.logger.Info().Int("files", reclaimed.Entries).Int64("bytes", reclaimed.Bytes).Msg("Swept incomplete downloads")
+}
+
+// evictDownloads removes finished job workspaces, and stored tracks exceeding
+// the retention policy. It must not be called while a job is running.
+func (w *Worker) evictDownloads() {
+	reclaimed, err := w.downloadDir().Evict(w.retentionPolicy(), w.queuedJobIDs(), time.Now())
+	if nil != err {
+		w.logger.Error().Func(log.Flaw(err)).Msg("Failed to evict downloads")
+		return
+	}
+	if reclaimed.Entries > 0 {
+		w.logger.Info().Int("entries", reclaimed.Entries).Int64("bytes", reclaimed.Bytes).Msg("Evicted downloads")
+	}
+}
+
+// removeJobFiles removes the job workspace, and tracks uploaded by the job from
+// the track store.
+func (w *Worker) removeJobFiles(jobID string, jobDir tidalfs.JobDir, trackKeys []string) {
+	if err := jobDir.RemoveTracks(trackKeys); nil != err {
+		w.logger.Error().Func(log.Flaw(err)).Str("job_id", jobID).Msg("Failed to remove uploaded tracks")
+	}
+	if err := jobDir.Remove(); nil != err {
+		w.logger.Error().Func(log.Flaw(err)).Str("job_id", jobID).Msg("Failed to remove job directory")
+	}
+}
+
+func (w *Worker) processDisk(ctx context.Context, reply *message.RequestBuilder) {
+	var lines []styling.StyledTextOption
+	usage, err := w.downloadDir().Usage()
+	if nil != err {
+		w.logger.Error().Func(log.Flaw(err)).Msg("Failed to get download directory usage")
+		lines = append(lines, styling.Plain("Failed to get download directory usage."))
+	} else {
+		policy := w.retentionPolicy()
+		total := formatBytes(usage.Total())
+		if policy.MaxSize > 0 {
+			total += " of " + formatBytes(policy.MaxSize)
+		}
+		maxAge := "unlimited"
+		if policy.MaxAge > 0 {
+			maxAge = policy.MaxAge.String()
+		}
+		deleteAfterUpload := "off"
+		if w.config.DeleteAfterUpload {
+			deleteAfterUpload = "on"
+		}
+
+		lines = append(
+			lines,
+			styling.Bold("Download directory usage: "+total),
+			styling.Plain("\n"),
+			styling.Plain(fmt.Sprintf("Tracks: %d (%s)", usage.Tracks.Count, formatBytes(usage.Tracks.Bytes))),
+			styling.Plain("\n"),
+			styling.Plain(fmt.Sprintf("Incomplete tracks: %d (%s)", usage.Incomplete.Count, formatBytes(usage.Incomplete.Bytes))),
+			styling.Plain("\n"),
+			styling.Plain(fmt.Sprintf("Job workspaces: %d (%s)", usage.Jobs.Count, formatBytes(usage.Jobs.Bytes))),
+			styling.Plain("\n"),
+			styling.Plain("Maximum age: "+maxAge),
+			styling.Plain("\n"),
+			styling.Plain("Delete after upload: "+deleteAfterUpload),
+		)
+	}
+
+	if _, err := reply.StyledText(ctx, lines...); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+		return
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/message/styling"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/config"
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/log"
+	"github.com/xeptore/tgtd/subscription"
+	"github.com/xeptore/tgtd/tidal"
+	"github.com/xeptore/tgtd/tidal/api"
+	"github.com/xeptore/tgtd/tidal/auth"
+)
+
+// followCheckInterval is the interval followed playlists are checked for being
+// due to be polled.
+const followCheckInterval = time.Minute
+
+// playlistSnapshot returns title, and IDs of streamable tracks of the playlist,
+// refreshing the access token once if it has expired.
+func (w *Worker) playlistSnapshot(ctx context.Context, id string) (string, []string, error) {
+	dl := w.metaDownloader(w.config.AudioQuality, false)
+	title, trackIDs, err := dl.PlaylistSnapshot(ctx, id)
+	if errors.Is(err, auth.ErrUnauthorized) {
+		if err := w.tidalAuth.RefreshToken(ctx); nil != err {
+			return "", nil, err
+		}
+		title, trackIDs, err = dl.PlaylistSnapshot(ctx, id)
+	}
+	return title, trackIDs, err
+}
+
+func (w *Worker) processFollow(ctx context.Context, reply *message.RequestBuilder, p JobPeer, args string) {
+	lines := w.followLines(ctx, p, args)
+	if nil == lines {
+		return
+	}
+
+	if _, err := reply.StyledText(ctx, lines...); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+	}
+}
+
+// followLines follows the playlist in args for p, and returns the reply to
+// /follow args. It returns nil lines if the context ends.
+func (w *Worker) followLines(ctx context.Context, p JobPeer, args string) []styling.StyledTextOption {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 || !tidal.IsLink(fields[0]) {
+		return []styling.StyledTextOption{
+			styling.Plain("Follow a playlist with "),
+			styling.Code("/follow <playlist link> [interval]"),
+		}
+	}
+
+	link, err := parseLink(fields[0])
+	if nil != err {
+		return []styling.StyledTextOption{
+			styling.Plain("Failed to parse link:"),
+			styling.Plain("\n"),
+			styling.Code(err.Error()),
+		}
+	}
+	if link.Kind != "playlist" {
+		return []styling.StyledTextOption{styling.Plain("Only playlists can be followed.")}
+	}
+
+	interval := w.config.FollowInterval
+	if len(fields) == 2 {
+		interval, err = time.ParseDuration(fields[1])
+		if nil != err || interval < config.MinFollowInterval {
+			return []styling.StyledTextOption{
+				styling.Plain(fmt.Sprintf("Invalid interval. It must be a duration of at least %s, e.g., ", config.MinFollowInterval)),
+				styling.Code("30m"),
+				styling.Plain(", or "),
+				styling.Code("6h"),
+				styling.Plain("."),
+			}
+		}
+	}
+
+	subs := w.subscriptions.List()
+	if idx := slices.IndexFunc(subs, func(sub subscription.Subscription[JobPeer]) bool {
+		return sub.PlaylistID == link.ID && sub.Target.key() == p.key()
+	}); idx != -1 {
+		sub := subs[idx]
+		return []styling.StyledTextOption{
+			styling.Plain("This chat already follows "),
+			styling.Bold(sub.Title),
+			styling.Plain(" as "),
+			styling.Code("#" + sub.ID),
+			styling.Plain("."),
+		}
+	}
+
+	// Tracks already in the playlist are not posted, only the ones added later.
+	title, trackIDs, err := w.playlistSnapshot(ctx, link.ID)
+	if nil != err {
+		var text string
+		switch {
+		case errutil.IsContext(ctx):
+			return nil
+		case errors.Is(err, auth.ErrUnauthorized):
+			text = "TIDAL authentication expired. Please reauthorize the application."
+		case errors.Is(err, context.DeadlineExceeded):
+			text = "Fetching playlist timed out."
+		case errors.Is(err, api.ErrTooManyRequests):
+			text = "TIDAL is rate limiting requests. Try again later."
+		case errutil.IsFlaw(err):
+			w.logger.Error().Func(log.Flaw(err)).Str("playlist_id", link.ID).Msg("Failed to fetch playlist to follow")
+			text = "Failed to fetch playlist."
+		default:
+			panic(errutil.UnknownError(err))
+		}
+		return []styling.StyledTextOption{styling.Plain(text)}
+	}
+
+	sub, err := w.subscriptions.Add(subscription.Subscription[JobPeer]{
+		ID:         "",
+		CreatedAt:  time.Time{},
+		PlaylistID: link.ID,
+		Title:      title,
+		Target:     p,
+		Interval:   interval,
+		TrackIDs:   trackIDs,
+		PolledAt:   time.Now(),
+	})
+	if nil != err {
+		w.logger.Error().Func(log.Flaw(err)).Str("playlist_id", link.ID).Msg("Failed to store playlist subscription")
+		return []styling.StyledTextOption{styling.Plain("Failed to follow playlist.")}
+	}
+	w.logger.Info().Str("subscription_id", sub.ID).Str("playlist_id", sub.PlaylistID).Str("interval", interval.String()).Msg("Playlist followed")
+
+	return []styling.StyledTextOption{
+		styling.Plain("Following "),
+		styling.Bold(sub.Title),
+		styling.Plain(" as "),
+		styling.Code("#" + sub.ID),
+		styling.Plain("."),
+		styling.Plain("\n"),
+		styling.Plain(fmt.Sprintf("Tracks added to it are posted to this chat, checking every %s.", interval)),
+		styling.Plain("\n"),
+		styling.Plain("Unfollow with "),
+		styling.BotCommand("/unfollow " + sub.ID),
+	}
+}
+
+func (w *Worker) processFollowing(ctx context.Context, reply *message.RequestBuilder, p JobPeer) {
+	subs := slices.DeleteFunc(w.subscriptions.List(), func(sub subscription.Subscription[JobPeer]) bool {
+		return sub.Target.key() != p.key()
+	})
+
+	var lines []styling.StyledTextOption
+	if len(subs) == 0 {
+		lines = append(
+			lines,
+			styling.Plain("This chat does not follow any playlist."),
+			styling.Plain("\n"),
+			styling.Plain("Follow one with "),
+			styling.Code("/follow <playlist link> [interval]"),
+		)
+	} else {
+		lines = append(lines, styling.Bold("Followed playlists:"))
+		for i, sub := range subs {
+			lines = append(
+				lines,
+				styling.Plain("\n"),
+				styling.Plain(fmt.Sprintf("%d. ", i+1)),
+				styling.Code("#"+sub.ID),
+				styling.Plain(fmt.Sprintf(" %s, every %s", sub.Title, sub.Interval)),
+			)
+		}
+	}
+
+	if _, err := reply.StyledText(ctx, lines...); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+	}
+}
+
+func (w *Worker) processUnfollow(ctx context.Context, reply *message.RequestBuilder, p JobPeer, id string) {
+	var lines []styling.StyledTextOption
+	switch idx := slices.IndexFunc(w.subscriptions.List(), func(sub subscription.Subscription[JobPeer]) bool {
+		return sub.ID == id && sub.Target.key() == p.key()
+	}); {
+	case id == "":
+		lines = []styling.StyledTextOption{
+			styling.Plain("Unfollow a playlist with "),
+			styling.Code("/unfollow <id>"),
+			styling.Plain(", listed by "),
+			styling.BotCommand("/following"),
+		}
+	case idx == -1:
+		lines = []styling.StyledTextOption{styling.Plain(fmt.Sprintf("Subscription #%s was not found in this chat.", id))}
+	default:
+		sub, err := w.subscriptions.Remove(id)
+		switch {
+		case nil == err:
+			w.logger.Info().Str("subscription_id", sub.ID).Str("playlist_id", sub.PlaylistID).Msg("Playlist unfollowed")
+			lines = []styling.StyledTextOption{
+				styling.Plain("Unfollowed "),
+				styling.Bold(sub.Title),
+				styling.Plain("."),
+			}
+		case errors.Is(err, subscription.ErrNotFound):
+			lines = []styling.StyledTextOption{styling.Plain(fmt.Sprintf("Subscription #%s was not found in this chat.", id))}
+		default:
+			w.logger.Error().Func(log.Flaw(err)).Str("subscription_id", id).Msg("Failed to remove playlist subscription")
+			lines = []styling.StyledTextOption{styling.Plain(fmt.Sprintf("Failed to unfollow #%s.", id))}
+		}
+	}
+
+	if _, err := reply.StyledText(ctx, lines...); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+	}
+}
+
+// followLoop polls followed playlists as they become due, until ctx ends.
+func (w *Worker) followLoop(ctx context.Context) {
+	ticker := time.NewTicker(followCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		w.pollDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollDue polls followed playlists which are due, until ctx ends.
+func (w *Worker) pollDue(ctx context.Context) {
+	w.followMux.Lock()
+	defer w.followMux.Unlock()
+
+	for _, sub := range w.subscriptions.Due(time.Now()) {
+		if errutil.IsContext(ctx) {
+			return
+		}
+		w.poll(ctx, sub)
+	}
+}
+
+// poll queues downloading tracks added to the followed playlist since its last
+// poll. As snapshots only hold streamable tracks, tracks which become streamable
+// later are considered added then. Added tracks are left out of the snapshot
+// until followedJobDone records them as posted, so that tracks of jobs which are
+// canceled, or fail are considered added again by later polls.
+func (w *Worker) poll(ctx context.Context, sub subscription.Subscription[JobPeer]) {
+	logger := w.logger.With().Str("subscription_id", sub.ID).Str("playlist_id", sub.PlaylistID).Logger()
+	polledAt := time.Now()
+
+	jobID := sub.JobID
+	if jobID != "" && !slices.Contains(w.queuedJobIDs(), jobID) {
+		// The job was removed from the queue before it was run, e.g., by /cancel.
+		jobID = ""
+	}
+
+	title, trackIDs, err := w.playlistSnapshot(ctx, sub.PlaylistID)
+	if nil != err {
+		switch {
+		case errutil.IsContext(ctx):
+			return
+		case errors.Is(err, auth.ErrUnauthorized):
+			logger.Error().Msg("Failed to poll followed playlist due to expired TIDAL authentication")
+		case errors.Is(err, context.DeadlineExceeded):
+			logger.Error().Msg("Polling followed playlist timed out")
+		case errors.Is(err, api.ErrTooManyRequests):
+			logger.Warn().Msg("Polling followed playlist was rate limited")
+		case errutil.IsFlaw(err):
+			logger.Error().Func(log.Flaw(err)).Msg("Failed to poll followed playlist")
+		default:
+			panic(errutil.UnknownError(err))
+		}
+		// Keep the previous snapshot, and retry after the interval.
+		title, trackIDs = sub.Title, sub.TrackIDs
+	} else {
+		var added []string
+		trackIDs, added = diffTracks(sub.TrackIDs, trackIDs)
+		switch {
+		case len(added) == 0:
+		case jobID != "":
+			logger.Info().Int("tracks", len(added)).Str("job_id", jobID).Msg("Tracks were added to followed playlist while its previous job is queued")
+		default:
+			logger.Info().Int("tracks", len(added)).Msg("Tracks were added to followed playlist")
+			link := DownloadLink{
+				Kind:      "playlist",
+				ID:        sub.PlaylistID,
+				Quality:   "",
+				Immersive: false,
+				Selection: &tidal.Selection{Ranges: nil, Disc: 0, TrackIDs: added},
+			}
+			jobID = w.enqueue(ctx, w.sender.To(sub.Target.inputPeer()), QueuedJob{Link: link, Peer: sub.Target, SubscriptionID: sub.ID})
+		}
+	}
+
+	if err := w.subscriptions.Polled(sub.ID, title, trackIDs, jobID, polledAt); nil != err {
+		if errors.Is(err, subscription.ErrNotFound) {
+			// Unfollowed while being polled.
+			return
+		}
+		logger.Error().Func(log.Flaw(err)).Msg("Failed to store followed playlist snapshot")
+	}
+}
+
+// followedJobDone records tracks posted by the job of a followed playlist, given
+// their track store keys, as part of its snapshot.
+func (w *Worker) followedJobDone(subscriptionID, jobID string, trackKeys []string) {
+	w.followMux.Lock()
+	defer w.followMux.Unlock()
+
+	trackIDs := make([]string, len(trackKeys))
+	for i, key := range trackKeys {
+		// Keys of cut tracks are suffixed with their offsets, see tidalfs.TrackKey.
+		trackIDs[i], _, _ = strings.Cut(key, "_")
+	}
+	if err := w.subscriptions.Posted(subscriptionID, jobID, trackIDs); nil != err {
+		if errors.Is(err, subscription.ErrNotFound) {
+			// Unfollowed while the job was running.
+			return
+		}
+		w.logger.Error().Func(log.Flaw(err)).Str("subscription_id", subscriptionID).Str("job_id", jobID).Msg("Failed to record posted tracks of followed playlist")
+	}
+}
+
+// diffTracks returns IDs in current which are in previous, and ones which are
+// not, i.e., added, without duplicates.
+func diffTracks(previous, current []string) (kept, added []string) {
+	seen := make(map[string]bool, len(previous)+len(current))
+	for _, id := range previous {
+		seen[id] = true
+	}
+
+	done := make(map[string]struct{}, len(current))
+	for _, id := range current {
+		if _, ok := done[id]; ok {
+			continue
+		}
+		done[id] = struct{}{}
+		if seen[id] {
+			kept = append(kept, id)
+		} else {
+			added = append(added, id)
+		}
+	}
+	return kept, added
+}
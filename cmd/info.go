@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/message/markup"
+	"github.com/gotd/td/telegram/message/styling"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/log"
+	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/tidal"
+	"github.com/xeptore/tgtd/tidal/api"
+	"github.com/xeptore/tgtd/tidal/auth"
+	tidaldl "github.com/xeptore/tgtd/tidal/download"
+	tidalfs "github.com/xeptore/tgtd/tidal/fs"
+)
+
+// maxPreviewUnavailableTracks is the number of unavailable tracks listed by
+// /info, beyond which only their count is mentioned.
+const maxPreviewUnavailableTracks = 10
+
+// metaDownloader returns a downloader which is only used to fetch metadata, as
+// it neither has a job directory to write to, nor reports progress.
+func (w *Worker) metaDownloader(quality tidal.Quality, immersive bool) *tidaldl.Downloader {
+	return tidaldl.NewDownloader(
+		tidalfs.JobDir{}, //nolint:exhaustruct
+		w.tidalAuth,
+		api.NewClient(w.tidalAuth, w.region()),
+		w.cache.AlbumsMeta,
+		w.cache.DownloadedCovers,
+		w.cache.TrackCredits,
+		quality,
+		immersive,
+		nil,
+		nil,
+	)
+}
+
+// preview returns preview of link, refreshing the access token once if it has
+// expired.
+func (w *Worker) preview(ctx context.Context, link DownloadLink) (*tidaldl.Preview, error) {
+	dl := w.metaDownloader(link.Quality, link.Immersive)
+	preview, err := dl.Preview(ctx, link.Kind, link.ID)
+	if errors.Is(err, auth.ErrUnauthorized) {
+		if err := w.tidalAuth.RefreshToken(ctx); nil != err {
+			return nil, err
+		}
+		preview, err = dl.Preview(ctx, link.Kind, link.ID)
+	}
+	return preview, err
+}
+
+func (w *Worker) processInfo(ctx context.Context, reply *message.RequestBuilder, p JobPeer, arg string) {
+	lines, link := w.infoLines(ctx, p, arg)
+	if nil == lines {
+		return
+	}
+
+	builder := reply.CloneBuilder()
+	if nil != link {
+		builder = builder.Markup(markup.InlineRow(
+			markup.Callback("Download", downloadCallbackData(*link)),
+			markup.Callback("Cancel", []byte(callbackActionDismiss)),
+		))
+	}
+	if _, err := builder.StyledText(ctx, lines...); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+	}
+}
+
+// infoLines returns the reply to /info arg, and the link to offer downloading,
+// if arg is previewed successfully. It returns nil lines if the context ends.
+// As previews, and their download buttons cover whole links, arg must only be
+// a link, rather than one followed by a track selection.
+func (w *Worker) infoLines(ctx context.Context, p JobPeer, arg string) ([]styling.StyledTextOption, *DownloadLink) {
+	if fields := strings.Fields(arg); len(fields) != 1 || !tidal.IsLink(fields[0]) {
+		return []styling.StyledTextOption{
+			styling.Plain("Preview a link with "),
+			styling.Code("/info <link>"),
+			styling.Plain("\n"),
+			styling.Plain("Track selections are not supported, send them along with the link to download instead."),
+		}, nil
+	}
+
+	link, err := parseLink(arg)
+	if nil != err {
+		return []styling.StyledTextOption{
+			styling.Plain("Failed to parse link:"),
+			styling.Plain("\n"),
+			styling.Code(err.Error()),
+		}, nil
+	}
+	switch link.Kind {
+	case "album", "playlist", "mix", "track":
+	default:
+		return []styling.StyledTextOption{
+			styling.Plain(fmt.Sprintf("Previewing %s links is not supported.", link.Kind)),
+		}, nil
+	}
+	if link.Quality == "" {
+		link.Quality = w.chatQuality(p)
+	}
+
+	preview, err := w.preview(ctx, *link)
+	if nil != err {
+		var text string
+		switch {
+		case errutil.IsContext(ctx):
+			return nil, nil
+		case errors.Is(err, auth.ErrUnauthorized):
+			text = "TIDAL authentication expired. Please reauthorize the application."
+		case errors.Is(err, context.DeadlineExceeded):
+			text = "Preview timed out."
+		case errors.Is(err, api.ErrTooManyRequests):
+			text = "TIDAL is rate limiting requests. Try again later."
+		case errutil.IsFlaw(err):
+			flawP := flaw.P{"kind": link.Kind, "id": link.ID, "quality": link.Quality, "immersive": link.Immersive}
+			w.logger.Error().Func(log.Flaw(must.BeFlaw(err).Append(flawP))).Msg("Failed to preview link")
+			text = "Failed to preview link."
+		default:
+			panic(errutil.UnknownError(err))
+		}
+		return []styling.StyledTextOption{styling.Plain(text)}, nil
+	}
+
+	lines := []styling.StyledTextOption{
+		styling.Bold(preview.Title),
+		styling.Plain("\n"),
+		styling.Plain(fmt.Sprintf("Tracks: %d", preview.Tracks)),
+		styling.Plain("\n"),
+		styling.Plain("Duration: " + preview.Duration.String()),
+	}
+	if nil != preview.Format {
+		quality := string(preview.Format.Quality) + ", " + preview.Format.Codec
+		if preview.Format.IsDolbyAtmos() {
+			quality += ", Dolby Atmos"
+		}
+		if preview.Format.Quality != link.Quality {
+			quality += fmt.Sprintf(" (%s is not available)", link.Quality)
+		}
+		lines = append(
+			lines,
+			styling.Plain("\n"),
+			styling.Plain("Quality: "+quality),
+			styling.Plain("\n"),
+			styling.Plain("Estimated size: "+formatBytes(preview.EstimatedSize)),
+		)
+	}
+	if n := len(preview.Unavailable); n > 0 {
+		lines = append(lines, styling.Plain("\n\n"), styling.Bold(fmt.Sprintf("Unavailable tracks: %d", n)))
+		for _, t := range preview.Unavailable[:min(n, maxPreviewUnavailableTracks)] {
+			lines = append(lines, styling.Plain("\n"), styling.Plain("- "+strings.TrimSpace(t.Title)))
+		}
+		if n > maxPreviewUnavailableTracks {
+			lines = append(lines, styling.Plain("\n"), styling.Italic(fmt.Sprintf("and %d more", n-maxPreviewUnavailableTracks)))
+		}
+	}
+
+	if preview.Tracks == 0 {
+		return lines, nil
+	}
+	return lines, link
+}
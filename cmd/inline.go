@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/gotd/td/tg"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/log"
+	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/tidal"
+	"github.com/xeptore/tgtd/tidal/api"
+	"github.com/xeptore/tgtd/tidal/auth"
+)
+
+const (
+	// inlineResultsLimit is the number of results of each type listed in inline mode.
+	inlineResultsLimit = 5
+	// inlineResultsCacheTime is the number of seconds Telegram may cache inline
+	// query results for.
+	inlineResultsCacheTime = 300
+	inlineThumbSize        = 320
+)
+
+// processInlineQuery answers inline queries, i.e., @bot <query>, with TIDAL
+// search results. Choosing a result sends its link, which the bot then handles
+// as any other link sent to it. Queries of users who are not allowed to use the
+// bot are answered with no results.
+func (w *Worker) processInlineQuery(ctx context.Context, q *tg.UpdateBotInlineQuery) {
+	query := strings.TrimSpace(q.Query)
+	if query == "" || !slices.Contains(w.config.FromIDs, q.UserID) {
+		w.answerInlineQuery(ctx, q.QueryID, nil, inlineResultsCacheTime)
+		return
+	}
+
+	res, err := w.search(ctx, query, inlineResultsLimit)
+	if nil != err {
+		switch {
+		case errutil.IsContext(ctx):
+			return
+		case errors.Is(err, auth.ErrUnauthorized):
+			w.logger.Error().Msg("TIDAL authentication expired while answering inline query")
+		case errors.Is(err, context.DeadlineExceeded):
+			w.logger.Warn().Str("query", query).Msg("Inline query search timed out")
+		case errors.Is(err, api.ErrTooManyRequests):
+			w.logger.Warn().Str("query", query).Msg("Inline query search was rate limited")
+		case errutil.IsFlaw(err):
+			w.logger.Error().Func(log.Flaw(must.BeFlaw(err).Append(flaw.P{"query": query}))).Msg("Failed to search TIDAL for inline query")
+		default:
+			panic(errutil.UnknownError(err))
+		}
+		w.answerInlineQuery(ctx, q.QueryID, nil, 0)
+		return
+	}
+
+	items := searchItems(res)
+	results := make([]tg.InputBotInlineResultClass, len(items))
+	for i, item := range items {
+		results[i] = inlineResult(item)
+	}
+	w.answerInlineQuery(ctx, q.QueryID, results, inlineResultsCacheTime)
+}
+
+func inlineResult(item searchItem) *tg.InputBotInlineResult {
+	link := tidal.Link(item.kind, item.id)
+	description := item.label()
+	if item.subtitle != "" {
+		description += " · " + item.subtitle
+	}
+
+	//nolint:exhaustruct
+	result := &tg.InputBotInlineResult{
+		ID:          item.kind + ":" + item.id,
+		Type:        "article",
+		Title:       item.title,
+		Description: description,
+		URL:         link,
+		SendMessage: &tg.InputBotInlineMessageText{Message: link}, //nolint:exhaustruct
+	}
+	if item.imageID != "" {
+		result.Thumb = tg.InputWebDocument{
+			URL:        api.ImageURL(item.imageID, inlineThumbSize),
+			Size:       0,
+			MimeType:   "image/jpeg",
+			Attributes: []tg.DocumentAttributeClass{},
+		}
+	}
+	return result
+}
+
+func (w *Worker) answerInlineQuery(ctx context.Context, queryID int64, results []tg.InputBotInlineResultClass, cacheTime int) {
+	//nolint:exhaustruct
+	req := &tg.MessagesSetInlineBotResultsRequest{
+		Private:   true,
+		QueryID:   queryID,
+		Results:   results,
+		CacheTime: cacheTime,
+	}
+	if _, err := w.client.API().MessagesSetInlineBotResults(ctx, req); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to answer inline query")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/gotd/td/telegram"
 	"github.com/gotd/td/telegram/message"
 	"github.com/gotd/td/telegram/message/html"
+	"github.com/gotd/td/telegram/message/peer"
 	"github.com/gotd/td/telegram/message/styling"
 	"github.com/gotd/td/telegram/updates"
 	"github.com/gotd/td/telegram/uploader"
@@ -28,6 +29,7 @@ import (
 	"github.com/iyear/tdl/core/dcpool"
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog"
+	"github.com/samber/lo"
 	"github.com/urfave/cli/v2"
 	"github.com/xeptore/flaw/v8"
 	"gopkg.in/matryer/try.v1"
@@ -39,8 +41,14 @@ import (
 	"github.com/xeptore/tgtd/errutil"
 	"github.com/xeptore/tgtd/log"
 	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/progress"
+	"github.com/xeptore/tgtd/queue"
+	"github.com/xeptore/tgtd/registry"
+	"github.com/xeptore/tgtd/settings"
+	"github.com/xeptore/tgtd/subscription"
 	"github.com/xeptore/tgtd/tgutil"
 	"github.com/xeptore/tgtd/tidal"
+	"github.com/xeptore/tgtd/tidal/api"
 	"github.com/xeptore/tgtd/tidal/auth"
 	tidaldl "github.com/xeptore/tgtd/tidal/download"
 	tidalfs "github.com/xeptore/tgtd/tidal/fs"
@@ -150,6 +158,36 @@ func run(cliCtx *cli.Context) (err error) {
 		logger.Info().Msg("Credentials directory created")
 	}
 
+	jobQueue, err := queue.Load[QueuedJob](queue.FileFrom(cfg.CredsDir))
+	if nil != err {
+		return fmt.Errorf("failed to load job queue: %v", err)
+	}
+
+	chatSettings, err := settings.Load(settings.FileFrom(cfg.CredsDir))
+	if nil != err {
+		return fmt.Errorf("failed to load chat settings: %v", err)
+	}
+
+	documents, err := registry.Load(registry.FileFrom(cfg.CredsDir))
+	if nil != err {
+		return fmt.Errorf("failed to load uploaded documents registry: %v", err)
+	}
+
+	subscriptions, err := subscription.Load[JobPeer](subscription.FileFrom(cfg.CredsDir))
+	if nil != err {
+		return fmt.Errorf("failed to load playlist subscriptions: %v", err)
+	}
+
+	persistentCache, err := cache.Open(filepath.Join(cfg.CredsDir, "cache.db"), cfg.Cache.Options(), logger.With().Str("module", "cache").Logger())
+	if nil != err {
+		return fmt.Errorf("failed to open cache: %v", err)
+	}
+	defer func() {
+		if closeErr := persistentCache.Close(); nil != closeErr {
+			logger.Error().Func(log.Flaw(closeErr)).Msg("Failed to close cache")
+		}
+	}()
+
 	handler := func(ctx context.Context, u tg.UpdatesClass) error { return nil }
 	//nolint:exhaustruct
 	updatesConfig := updates.Config{
@@ -171,15 +209,21 @@ func run(cliCtx *cli.Context) (err error) {
 	logger.Debug().Msg("Telegram client initialized.")
 
 	w := &Worker{
-		mutex:      sync.Mutex{},
-		config:     cfg,
-		client:     client,
-		sender:     nil,
-		tidalAuth:  nil,
-		currentJob: nil,
-		cache:      cache.New(),
-		logger:     logger.With().Str("module", "worker").Logger(),
-		uploader:   nil,
+		mutex:           sync.Mutex{},
+		config:          cfg,
+		client:          client,
+		sender:          nil,
+		tidalAuth:       nil,
+		currentJob:      nil,
+		pendingCancelID: "",
+		followMux:       sync.Mutex{},
+		queue:           jobQueue,
+		settings:        chatSettings,
+		registry:        documents,
+		subscriptions:   subscriptions,
+		cache:           persistentCache,
+		logger:          logger.With().Str("module", "worker").Logger(),
+		uploader:        nil,
 	}
 
 	clientCtx, cancel := ctxutil.WithDelayedTimeout(ctx, 5*time.Second)
@@ -355,12 +399,27 @@ func run(cliCtx *cli.Context) (err error) {
 			}
 		}
 
-		logger.Debug().Msg("TIDAL access token verified")
+		logger.Debug().Str("country_code", tidlAuth.CountryCode()).Msg("TIDAL access token verified")
 		w.tidalAuth = tidlAuth
 		handler = buildHandler(w)
 
+		loopDone := make(chan struct{})
+		w.sweepDownloads()
+		w.evictDownloads()
+		go func() {
+			defer close(loopDone)
+			w.loop(ctx)
+		}()
+		followDone := make(chan struct{})
+		go func() {
+			defer close(followDone)
+			w.followLoop(ctx)
+		}()
+
 		logger.Info().Msg("Bot is running")
 		<-ctx.Done()
+		<-loopDone
+		<-followDone
 
 		logger.Debug().Msg("Stopping bot due to received signal")
 		if _, err = fatherChat.StyledText(clientCtx, styling.Italic("Bot is shutting down...")); nil != err {
@@ -398,6 +457,10 @@ func buildHandler(w *Worker) telegram.UpdateHandlerFunc {
 				w.process(ctx, entities, us)
 			case *tg.UpdateNewChannelMessage:
 				w.process(ctx, entities, us)
+			case *tg.UpdateBotCallbackQuery:
+				w.processCallback(ctx, entities, us)
+			case *tg.UpdateBotInlineQuery:
+				w.processInlineQuery(ctx, us)
 			default:
 				w.logger.Info().Str("type", fmt.Sprintf("%T", us)).Msg("Unsupported update type received")
 			}
@@ -560,6 +623,17 @@ func (w *Worker) process(ctx context.Context, e tg.Entities, m message.Answerabl
 		}
 
 		w.tidalAuth = res.Unwrap()
+		if err := w.tidalAuth.VerifyAccessToken(ctx); nil != err {
+			// Not fatal, as it is only needed to detect the account country code.
+			switch {
+			case errors.Is(ctx.Err(), context.Canceled):
+				return
+			case errutil.IsFlaw(err):
+				w.logger.Error().Func(log.Flaw(err)).Msg("Failed to verify TIDAL access token")
+			default:
+				w.logger.Error().Err(err).Msg("Failed to verify TIDAL access token")
+			}
+		}
 
 		lines = []styling.StyledTextOption{
 			styling.Plain("TIDAL authentication was successful!"),
@@ -577,35 +651,84 @@ func (w *Worker) process(ctx context.Context, e tg.Entities, m message.Answerabl
 		return
 	}
 
-	if msg.Message == "/cancel" {
-		if err := w.cancelCurrentJob(); nil != err {
-			if !errors.Is(err, os.ErrProcessDone) {
-				panic(fmt.Sprintf("unexpected error of type %T: %v", err, err))
-			}
-			if _, err := reply.StyledText(ctx, styling.Plain("No job was running.")); nil != err {
-				if errors.Is(ctx.Err(), context.Canceled) {
-					return
-				}
-				flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-				w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
-				return
-			}
+	if cmd, args, _ := strings.Cut(msg.Message, " "); cmd == "/cancel" {
+		w.processCancel(ctx, reply, strings.TrimSpace(args))
+		return
+	}
+
+	if msg.Message == "/queue" {
+		w.processQueue(ctx, reply)
+		return
+	}
+
+	if msg.Message == "/disk" {
+		w.processDisk(ctx, reply)
+		return
+	}
+
+	if cmd, args, _ := strings.Cut(msg.Message, " "); cmd == "/info" {
+		inputPeer, err := peer.EntitiesFromUpdate(e).ExtractPeer(msg.PeerID)
+		if nil != err {
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to extract message peer")
+			return
+		}
+		jobPeer, err := jobPeerFrom(inputPeer)
+		if nil != err {
+			w.logger.Error().Func(log.Flaw(err)).Msg("Failed to convert message peer")
 			return
 		}
+		w.processInfo(ctx, reply, *jobPeer, strings.TrimSpace(args))
+		return
+	}
 
-		if _, err := reply.StyledText(ctx, styling.Plain("Job was canceled.")); nil != err {
-			if errors.Is(ctx.Err(), context.Canceled) {
-				return
-			}
+	if cmd, args, _ := strings.Cut(msg.Message, " "); cmd == "/search" {
+		w.processSearch(ctx, reply, strings.TrimSpace(args))
+		return
+	}
+
+	if cmd, args, _ := strings.Cut(msg.Message, " "); cmd == "/follow" || cmd == "/following" || cmd == "/unfollow" {
+		inputPeer, err := peer.EntitiesFromUpdate(e).ExtractPeer(msg.PeerID)
+		if nil != err {
 			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to extract message peer")
+			return
+		}
+		jobPeer, err := jobPeerFrom(inputPeer)
+		if nil != err {
+			w.logger.Error().Func(log.Flaw(err)).Msg("Failed to convert message peer")
+			return
+		}
+		switch cmd {
+		case "/follow":
+			w.processFollow(ctx, reply, *jobPeer, strings.TrimSpace(args))
+		case "/following":
+			w.processFollowing(ctx, reply, *jobPeer)
+		case "/unfollow":
+			w.processUnfollow(ctx, reply, *jobPeer, strings.TrimSpace(args))
+		}
+		return
+	}
+
+	if cmd, args, _ := strings.Cut(msg.Message, " "); cmd == "/quality" {
+		inputPeer, err := peer.EntitiesFromUpdate(e).ExtractPeer(msg.PeerID)
+		if nil != err {
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to extract message peer")
+			return
+		}
+		jobPeer, err := jobPeerFrom(inputPeer)
+		if nil != err {
+			w.logger.Error().Func(log.Flaw(err)).Msg("Failed to convert message peer")
 			return
 		}
+		w.processQuality(ctx, reply, *jobPeer, strings.TrimSpace(args))
+		return
 	}
 
-	if tidal.IsLink(msg.Message) {
+	if text, selection, _ := strings.Cut(strings.TrimSpace(msg.Message), " "); tidal.IsLink(text) {
 		// Assuming it's the default type of command, i.e., download
-		link, err := parseLink(msg.Message)
+		link, err := parseLink(text)
 		if nil != err {
 			if errInvalidLink := new(InvalidLinkError); errors.As(err, &errInvalidLink) {
 				lines := []styling.StyledTextOption{
@@ -625,52 +748,13 @@ func (w *Worker) process(ctx context.Context, e tg.Entities, m message.Answerabl
 			}
 		}
 
-		if err := w.run(ctx, reply, *link); nil != err {
-			switch {
-			case errutil.IsContext(ctx):
-				// Parent context is canceled. Nothing that we need to do.
-				return
-			case errors.Is(err, context.Canceled):
-				// As we checked in the previous case that the parent context is not canceled,
-				// we can safely assume that the context was canceled by the user cancellation request.
-				w.logger.Info().Msg("Job canceled by the /cancel command")
-				if _, err := reply.StyledText(ctx, styling.Plain("Job canceled by the /cancel command")); nil != err {
-					if errors.Is(ctx.Err(), context.Canceled) {
-						return
-					}
-					flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-					w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
-					return
-				}
-				return
-			case errors.Is(err, context.DeadlineExceeded):
-				if _, err := reply.StyledText(ctx, styling.Plain("Job has timed out.")); nil != err {
-					if errors.Is(ctx.Err(), context.Canceled) {
-						return
-					}
-					flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-					w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
-					return
-				}
-				return
-			case errors.Is(err, tidaldl.ErrTooManyRequests):
-				if _, err := reply.StyledText(ctx, styling.Plain("Received too many requests error while downloading from TIDAL.")); nil != err {
-					if errors.Is(ctx.Err(), context.Canceled) {
-						return
-					}
-					flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-					w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
-					return
-				}
-				return
-			}
-			// handling the rest of possible error types that are not supported by switch/case syntactically.
-			if errAlreadyRunning := new(JobAlreadyRunningError); errors.As(err, &errAlreadyRunning) {
+		if selection = strings.TrimSpace(selection); selection != "" {
+			link.Selection, err = parseSelection(link.Kind, selection)
+			if nil != err {
 				lines := []styling.StyledTextOption{
-					styling.Plain("Another job is still running."),
+					styling.Plain("Invalid track selection:"),
 					styling.Plain("\n"),
-					styling.Plain("Cancel with "),
-					styling.BotCommand("/cancel"),
+					styling.Code(err.Error()),
 				}
 				if _, err := reply.StyledText(ctx, lines...); nil != err {
 					if errors.Is(ctx.Err(), context.Canceled) {
@@ -678,47 +762,331 @@ func (w *Worker) process(ctx context.Context, e tg.Entities, m message.Answerabl
 					}
 					flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
 					w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
-					return
 				}
 				return
 			}
+		}
 
-			w.logger.Error().Func(log.Flaw(err)).Msg("Failed to run job")
-			flawBytes, err := errutil.FlawToYAML(must.BeFlaw(err))
-			if nil != err {
-				w.logger.Error().Func(log.Flaw(err)).Msg("Failed to convert flaw to TOML")
-				return
+		inputPeer, err := peer.EntitiesFromUpdate(e).ExtractPeer(msg.PeerID)
+		if nil != err {
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to extract message peer")
+			return
+		}
+		jobPeer, err := jobPeerFrom(inputPeer)
+		if nil != err {
+			w.logger.Error().Func(log.Flaw(err)).Msg("Failed to convert message peer")
+			return
+		}
+
+		w.enqueue(ctx, reply, QueuedJob{Link: *link, Peer: *jobPeer, SubscriptionID: ""})
+	}
+}
+
+// enqueue queues job, and replies with the queued job ID unless the job is
+// quiet. It returns the ID, or an empty string if the job was not queued.
+func (w *Worker) enqueue(ctx context.Context, reply *message.RequestBuilder, job QueuedJob) string {
+	link := job.Link
+	if link.Quality == "" {
+		link.Quality = w.chatQuality(job.Peer)
+		job.Link = link
+	}
+
+	item, pos, err := w.queue.Push(job)
+	if nil != err {
+		w.logger.Error().Func(log.Flaw(err)).Msg("Failed to enqueue job")
+		if job.quiet() {
+			return ""
+		}
+		if _, err := reply.StyledText(ctx, styling.Plain("Failed to enqueue job.")); nil != err {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return ""
+			}
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+		}
+		return ""
+	}
+	w.logger.Info().Str("job_id", item.ID).Str("id", link.ID).Str("kind", link.Kind).Str("quality", string(link.Quality)).Bool("immersive", link.Immersive).Int("position", pos).Msg("Job enqueued")
+	if job.quiet() {
+		return item.ID
+	}
+
+	lines := []styling.StyledTextOption{
+		styling.Plain("Job "),
+		styling.Code("#" + item.ID),
+		styling.Plain(fmt.Sprintf(" was queued at position %d.", pos)),
+		styling.Plain("\n"),
+		styling.Plain("Cancel with "),
+		styling.BotCommand("/cancel " + item.ID),
+	}
+	if _, err := reply.StyledText(ctx, lines...); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return item.ID
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+	}
+	return item.ID
+}
+
+func (w *Worker) processCancel(ctx context.Context, reply *message.RequestBuilder, id string) {
+	var text string
+	if id == "" {
+		if err := w.cancelCurrentJob(); nil != err {
+			if !errors.Is(err, os.ErrProcessDone) {
+				panic(errutil.UnknownError(err))
 			}
+			text = "No job was running."
+		} else {
+			text = "Job was canceled."
+		}
+	} else {
+		switch err := w.cancelJob(id); {
+		case nil == err:
+			text = fmt.Sprintf("Job #%s was canceled.", id)
+		case errors.Is(err, queue.ErrNotFound):
+			text = fmt.Sprintf("Job #%s was not found in the queue.", id)
+		case errutil.IsFlaw(err):
+			w.logger.Error().Func(log.Flaw(err)).Str("job_id", id).Msg("Failed to remove job from queue")
+			text = fmt.Sprintf("Failed to remove job #%s from the queue.", id)
+		default:
+			panic(errutil.UnknownError(err))
+		}
+	}
 
-			upload, err := w.uploader.FromReader(ctx, "flaw.yaml", bytes.NewReader(flawBytes))
-			if nil != err {
-				flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-				w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to upload flaw to YAML")
+	if _, err := reply.StyledText(ctx, styling.Plain(text)); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+		return
+	}
+}
+
+func (w *Worker) processQuality(ctx context.Context, reply *message.RequestBuilder, p JobPeer, arg string) {
+	var lines []styling.StyledTextOption
+	switch arg {
+	case "":
+		lines = []styling.StyledTextOption{
+			styling.Plain("Audio quality for this chat is "),
+			styling.Code(string(w.chatQuality(p))),
+			styling.Plain("."),
+			styling.Plain("\n"),
+			styling.Plain("Change it with "),
+			styling.Code("/quality low|high|lossless|hi_res_lossless|default"),
+		}
+	case "default":
+		if err := w.settings.SetChat(p.key(), settings.Chat{Quality: ""}); nil != err {
+			w.logger.Error().Func(log.Flaw(err)).Msg("Failed to reset chat audio quality")
+			lines = []styling.StyledTextOption{styling.Plain("Failed to reset audio quality.")}
+			break
+		}
+		lines = []styling.StyledTextOption{
+			styling.Plain("Audio quality for this chat was reset to "),
+			styling.Code(string(w.config.AudioQuality)),
+			styling.Plain("."),
+		}
+	default:
+		quality, err := tidal.ParseQuality(arg)
+		if nil != err {
+			lines = []styling.StyledTextOption{
+				styling.Plain("Invalid audio quality:"),
+				styling.Plain("\n"),
+				styling.Code(err.Error()),
+			}
+			break
+		}
+		if err := w.settings.SetChat(p.key(), settings.Chat{Quality: quality}); nil != err {
+			w.logger.Error().Func(log.Flaw(err)).Msg("Failed to set chat audio quality")
+			lines = []styling.StyledTextOption{styling.Plain("Failed to set audio quality.")}
+			break
+		}
+		lines = []styling.StyledTextOption{
+			styling.Plain("Audio quality for this chat was set to "),
+			styling.Code(string(quality)),
+			styling.Plain("."),
+		}
+	}
+
+	if _, err := reply.StyledText(ctx, lines...); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+		return
+	}
+}
+
+func (w *Worker) processQueue(ctx context.Context, reply *message.RequestBuilder) {
+	var (
+		items   = w.queue.Items()
+		running = w.queue.Running()
+		lines   []styling.StyledTextOption
+	)
+	if len(items) == 0 {
+		lines = append(lines, styling.Plain("Queue is empty."))
+	} else {
+		lines = append(lines, styling.Bold("Jobs in queue:"))
+		for i, item := range items {
+			lines = append(
+				lines,
+				styling.Plain("\n"),
+				styling.Plain(fmt.Sprintf("%d. ", i+1)),
+				styling.Code("#"+item.ID),
+				styling.Plain(fmt.Sprintf(" %s %s", item.Payload.Link.Kind, item.Payload.Link.ID)),
+			)
+			if item.Payload.Link.Quality != "" {
+				lines = append(lines, styling.Plain(fmt.Sprintf(" [%s]", item.Payload.Link.variant())))
+			}
+			if nil != item.Payload.Link.Selection {
+				lines = append(lines, styling.Plain(fmt.Sprintf(" (%s)", item.Payload.Link.Selection)))
+			}
+			if item.ID == running {
+				lines = append(lines, styling.Italic(" (running)"))
+			}
+		}
+	}
+
+	if _, err := reply.StyledText(ctx, lines...); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+		return
+	}
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		item, err := w.queue.Next(ctx)
+		if nil != err {
+			// Context has ended, as it is the only error Next returns.
+			return
+		}
+
+		reply := w.sender.To(item.Payload.Peer.inputPeer())
+		err = w.run(ctx, reply, item.ID, item.Payload)
+		if errutil.IsContext(ctx) {
+			// Parent context is canceled. Keep the job in the queue so it is resumed after restart.
+			return
+		}
+
+		if err := w.finishJob(item.ID); nil != err {
+			w.logger.Error().Func(log.Flaw(err)).Str("job_id", item.ID).Msg("Failed to remove finished job from queue")
+		}
+		w.evictDownloads()
+
+		if nil != err {
+			if item.Payload.quiet() {
+				w.logJobError(item.ID, err)
+				continue
+			}
+			w.handleJobError(ctx, reply, item.ID, err)
+			continue
+		}
+		w.logger.Info().Str("job_id", item.ID).Msg("Job succeeded")
+	}
+}
+
+// logJobError logs err of the quiet job with jobID, in place of replying with it.
+func (w *Worker) logJobError(jobID string, err error) {
+	logger := w.logger.With().Str("job_id", jobID).Logger()
+	switch {
+	case errors.Is(err, context.Canceled):
+		logger.Info().Msg("Job canceled by the /cancel command")
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Error().Msg("Job has timed out")
+	case errors.Is(err, auth.ErrUnauthorized):
+		logger.Error().Msg("Job failed due to expired TIDAL authentication")
+	case errors.Is(err, tidaldl.ErrTooManyRequests):
+		logger.Error().Msg("Job received too many requests error while downloading from TIDAL")
+	default:
+		logger.Error().Func(log.Flaw(err)).Msg("Failed to run job")
+	}
+}
+
+func (w *Worker) handleJobError(ctx context.Context, reply *message.RequestBuilder, jobID string, err error) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		// As the caller checks that the parent context is not canceled, we can safely
+		// assume that the context was canceled by the user cancellation request.
+		w.logger.Info().Str("job_id", jobID).Msg("Job canceled by the /cancel command")
+		if _, err := reply.StyledText(ctx, styling.Plain(fmt.Sprintf("Job #%s canceled by the /cancel command", jobID))); nil != err {
+			if errors.Is(ctx.Err(), context.Canceled) {
 				return
 			}
-			document := message.UploadedDocument(upload)
-			document.
-				MIME("application/yaml").
-				Attributes(
-					&tg.DocumentAttributeFilename{
-						FileName: filepath.Base(
-							fmt.Sprintf("flaw-%s.yaml", time.Now().Format("2006-01-02-15-04-05")),
-						),
-					},
-				).
-				ForceFile(true)
-			if _, err := reply.Media(ctx, document); nil != err {
-				if errors.Is(ctx.Err(), context.Canceled) {
-					return
-				}
-				flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-				w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+			return
+		}
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		if _, err := reply.StyledText(ctx, styling.Plain(fmt.Sprintf("Job #%s has timed out.", jobID))); nil != err {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return
+			}
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+			return
+		}
+		return
+	case errors.Is(err, auth.ErrUnauthorized):
+		if _, err := reply.StyledText(ctx, styling.Plain("TIDAL authentication expired. Please reauthorize the application.")); nil != err {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return
+			}
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+			return
+		}
+		return
+	case errors.Is(err, tidaldl.ErrTooManyRequests):
+		if _, err := reply.StyledText(ctx, styling.Plain("Received too many requests error while downloading from TIDAL.")); nil != err {
+			if errors.Is(ctx.Err(), context.Canceled) {
 				return
 			}
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
 			return
 		}
+		return
+	}
+
+	w.logger.Error().Func(log.Flaw(err)).Str("job_id", jobID).Msg("Failed to run job")
+	flawBytes, err := errutil.FlawToYAML(must.BeFlaw(err))
+	if nil != err {
+		w.logger.Error().Func(log.Flaw(err)).Msg("Failed to convert flaw to TOML")
+		return
+	}
 
-		w.logger.Info().Msg("Job succeeded")
+	upload, err := w.uploader.FromReader(ctx, "flaw.yaml", bytes.NewReader(flawBytes))
+	if nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to upload flaw to YAML")
+		return
+	}
+	document := message.UploadedDocument(upload)
+	document.
+		MIME("application/yaml").
+		Attributes(
+			&tg.DocumentAttributeFilename{
+				FileName: filepath.Base(
+					fmt.Sprintf("flaw-%s.yaml", time.Now().Format("2006-01-02-15-04-05")),
+				),
+			},
+		).
+		ForceFile(true)
+	if _, err := reply.Media(ctx, document); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+		return
 	}
 }
 
@@ -729,9 +1097,19 @@ type Worker struct {
 	sender     *message.Sender
 	tidalAuth  *auth.Auth
 	currentJob *Job
-	cache      *cache.Cache
-	logger     zerolog.Logger
-	uploader   *uploader.Uploader
+	// pendingCancelID is ID of the job canceled after it was taken from the
+	// queue, but before run has started it.
+	pendingCancelID string
+	// followMux serializes polls of followed playlists with recording tracks
+	// posted by their jobs.
+	followMux     sync.Mutex
+	queue         *queue.Queue[QueuedJob]
+	settings      *settings.Store
+	registry      *registry.Registry
+	subscriptions *subscription.Store[JobPeer]
+	cache         *cache.Cache
+	logger        zerolog.Logger
+	uploader      *uploader.Uploader
 }
 
 func newUploader(ctx context.Context, client *telegram.Client) (*uploader.Uploader, func() error) {
@@ -741,27 +1119,81 @@ func newUploader(ctx context.Context, client *telegram.Client) (*uploader.Upload
 
 type Job struct {
 	ID        string
+	Link      DownloadLink
 	CreatedAt time.Time
 	cancel    context.CancelFunc
+	progress  *progress.Tracker
 }
 
 func (j *Job) flawP() flaw.P {
 	return flaw.P{
 		"id":         j.ID,
+		"link":       flaw.P{"kind": j.Link.Kind, "id": j.Link.ID, "quality": j.Link.Quality, "immersive": j.Link.Immersive},
 		"created_at": j.CreatedAt,
 	}
 }
 
-type JobAlreadyRunningError struct {
-	ID string
+type QueuedJob struct {
+	Link DownloadLink `json:"link"`
+	Peer JobPeer      `json:"peer"`
+	// SubscriptionID is ID of the followed playlist subscription the job posts
+	// added tracks of, if any.
+	SubscriptionID string `json:"subscription_id,omitempty"`
 }
 
-func (e *JobAlreadyRunningError) Error() string {
-	return fmt.Sprintf("job %q is already running", e.ID)
+// quiet reports whether the job posts only media to its peer, without any
+// replies, status, or summaries. Jobs of followed playlists are, so that their
+// targets only mirror the playlist.
+func (j QueuedJob) quiet() bool {
+	return j.SubscriptionID != ""
 }
 
-type InvalidLinkError struct {
-	Link string
+const (
+	jobPeerKindUser    = "user"
+	jobPeerKindChat    = "chat"
+	jobPeerKindChannel = "channel"
+)
+
+// JobPeer is the persistable form of the input peer a queued job reports back to.
+type JobPeer struct {
+	Kind       string `json:"kind"`
+	ID         int64  `json:"id"`
+	AccessHash int64  `json:"access_hash"`
+}
+
+func jobPeerFrom(p tg.InputPeerClass) (*JobPeer, error) {
+	switch p := p.(type) {
+	case *tg.InputPeerUser:
+		return &JobPeer{Kind: jobPeerKindUser, ID: p.UserID, AccessHash: p.AccessHash}, nil
+	case *tg.InputPeerChat:
+		return &JobPeer{Kind: jobPeerKindChat, ID: p.ChatID, AccessHash: 0}, nil
+	case *tg.InputPeerChannel:
+		return &JobPeer{Kind: jobPeerKindChannel, ID: p.ChannelID, AccessHash: p.AccessHash}, nil
+	default:
+		return nil, flaw.From(fmt.Errorf("unsupported input peer type %T", p))
+	}
+}
+
+// key uniquely identifies the peer among all peer kinds.
+func (p JobPeer) key() string {
+	return p.Kind + ":" + strconv.FormatInt(p.ID, 10)
+}
+
+func (p JobPeer) inputPeer() tg.InputPeerClass {
+	switch p.Kind {
+	case jobPeerKindUser:
+		return &tg.InputPeerUser{UserID: p.ID, AccessHash: p.AccessHash}
+	case jobPeerKindChat:
+		return &tg.InputPeerChat{ChatID: p.ID}
+	case jobPeerKindChannel:
+		return &tg.InputPeerChannel{ChannelID: p.ID, AccessHash: p.AccessHash}
+	default:
+		panic(fmt.Sprintf("unexpected job peer kind %q", p.Kind))
+	}
+}
+
+type InvalidLinkError struct {
+	Link string
 	Err  error
 }
 
@@ -770,8 +1202,21 @@ func (e *InvalidLinkError) Error() string {
 }
 
 type DownloadLink struct {
-	Kind string
-	ID   string
+	Kind      string        `json:"kind"`
+	ID        string        `json:"id"`
+	Quality   tidal.Quality `json:"quality,omitempty"`
+	Immersive bool          `json:"immersive,omitempty"`
+	// Selection limits album, playlist, and mix links to a subset of tracks, or
+	// is nil if all tracks are requested.
+	Selection *tidal.Selection `json:"selection,omitempty"`
+}
+
+// variant describes the requested audio variant, e.g., LOSSLESS or LOSSLESS, Dolby Atmos.
+func (l DownloadLink) variant() string {
+	if l.Immersive {
+		return string(l.Quality) + ", Dolby Atmos"
+	}
+	return string(l.Quality)
 }
 
 func parseLink(link string) (*DownloadLink, error) {
@@ -798,302 +1243,542 @@ func parseLink(link string) (*DownloadLink, error) {
 	}
 
 	switch kind {
-	case "playlist", "album", "track", "mix":
-		return &DownloadLink{Kind: kind, ID: id}, nil
+	case "playlist", "album", "track", "mix", "artist", "video":
 	default:
 		return nil, &InvalidLinkError{Link: link, Err: fmt.Errorf("unsupported kind %q", kind)}
 	}
+
+	query := parsedURL.Query()
+
+	var quality tidal.Quality
+	if q := query.Get("q"); q != "" {
+		quality, err = tidal.ParseQuality(q)
+		if nil != err {
+			return nil, &InvalidLinkError{Link: link, Err: err}
+		}
+	}
+
+	var immersive bool
+	if query.Has("atmos") {
+		switch kind {
+		case "playlist", "album", "track", "mix", "artist":
+		default:
+			return nil, &InvalidLinkError{Link: link, Err: fmt.Errorf("atmos is not supported for %s links", kind)}
+		}
+		if v := query.Get("atmos"); v == "" {
+			immersive = true
+		} else if immersive, err = strconv.ParseBool(v); nil != err {
+			return nil, &InvalidLinkError{Link: link, Err: fmt.Errorf("invalid atmos value %q", v)}
+		}
+	}
+
+	return &DownloadLink{Kind: kind, ID: id, Quality: quality, Immersive: immersive, Selection: nil}, nil
+}
+
+// parseSelection parses selection of tracks of a link of kind, which is only
+// supported for albums, playlists, and mixes, with discs being only supported
+// for albums.
+func parseSelection(kind, selection string) (*tidal.Selection, error) {
+	switch kind {
+	case "album", "playlist", "mix":
+	default:
+		return nil, fmt.Errorf("selecting tracks of %s links is not supported", kind)
+	}
+
+	sel, err := tidal.ParseSelection(selection)
+	if nil != err {
+		return nil, err
+	}
+	if sel.Disc != 0 && kind != "album" {
+		return nil, fmt.Errorf("--disc is not supported for %s links", kind)
+	}
+	return sel, nil
+}
+
+// chatQuality returns audio quality set for the chat, or the globally configured
+// one if the chat has not overridden it.
+func (w *Worker) chatQuality(p JobPeer) tidal.Quality {
+	if q := w.settings.Chat(p.key()).Quality; q != "" {
+		return q
+	}
+	return w.config.AudioQuality
+}
+
+// region returns the TIDAL region requests should be made for. The configured
+// country code takes precedence over the one detected from the account session.
+func (w *Worker) region() tidal.Region {
+	countryCode := w.config.CountryCode
+	if countryCode == "" {
+		countryCode = w.tidalAuth.CountryCode()
+	}
+	if countryCode == "" {
+		countryCode = tidal.DefaultCountryCode
+	}
+	return tidal.Region{CountryCode: countryCode, Locale: w.config.Locale}
+}
+
+// currentProgress returns progress tracker of the running job, or nil if there
+// is no running job.
+func (w *Worker) currentProgress() *progress.Tracker {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if nil == w.currentJob {
+		return nil
+	}
+	return w.currentJob.progress
 }
 
 func (w *Worker) cancelCurrentJob() error {
-	if !w.mutex.TryLock() {
-		w.currentJob.cancel()
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if nil == w.currentJob {
+		return os.ErrProcessDone
+	}
+	w.currentJob.cancel()
+	return nil
+}
+
+// finishJob clears the current job, and removes it from the queue at once, so
+// that cancelJob never finds it running without it being current.
+func (w *Worker) finishJob(id string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.currentJob = nil
+	return w.queue.Done(id)
+}
+
+func (w *Worker) cancelJob(id string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	running, err := w.queue.Remove(id)
+	if nil != err {
+		return err
+	}
+	if running {
+		if nil != w.currentJob && w.currentJob.ID == id {
+			w.currentJob.cancel()
+		} else {
+			// The job is taken from the queue, but run has not started it yet.
+			w.pendingCancelID = id
+		}
+	}
+	return nil
+}
+
+// maxStepAttempts is the number of times a job step is attempted before its
+// error is reported.
+const maxStepAttempts = 3
+
+// retryStep runs step, and retries it on timeouts, rate limiting, and expired
+// access tokens, which are refreshed before retrying. Steps passing batches to
+// upload should be run with uploadStep instead, so that retrying them does not
+// post tracks twice.
+func (w *Worker) retryStep(ctx context.Context, flawP flaw.P, step func() error) error {
+	return try.Do(func(attempt int) (retry bool, err error) {
+		attemptRemained := attempt < maxStepAttempts
+
+		if err := step(); nil != err {
+			switch {
+			case errutil.IsContext(ctx):
+				return false, err
+			case errors.Is(err, auth.ErrUnauthorized):
+				if err := w.tidalAuth.RefreshToken(ctx); nil != err {
+					return false, err
+				}
+				return attemptRemained, auth.ErrUnauthorized
+			case errors.Is(err, context.DeadlineExceeded):
+				return attemptRemained, context.DeadlineExceeded
+			case errors.Is(err, tidaldl.ErrTooManyRequests):
+				return attemptRemained, tidaldl.ErrTooManyRequests
+			case errutil.IsFlaw(err):
+				return false, must.BeFlaw(err).Append(flawP)
+			default:
+				panic(errutil.UnknownError(err))
+			}
+		}
+		return false, nil
+	})
+}
+
+// postedTracks posts batches of a job step via upload, keeping track of posted
+// tracks, so that neither retrying the step, nor passing over it again posts
+// them twice.
+type postedTracks struct {
+	upload tidaldl.BatchFunc
+	keys   map[string]struct{}
+	// deferred reports whether a batch was left unposted, as uploaded documents
+	// of some of its tracks were no longer available.
+	deferred bool
+}
+
+func newPostedTracks(upload tidaldl.BatchFunc) *postedTracks {
+	return &postedTracks{upload: upload, keys: make(map[string]struct{}), deferred: false}
+}
+
+// Upload posts tracks of batch which are not posted yet. Batches with no track
+// left to post are skipped, and ones with unavailable uploaded documents are
+// deferred rather than failing the step.
+func (p *postedTracks) Upload(ctx context.Context, batch tidaldl.TrackBatch) error {
+	batch.TrackKeys = slices.DeleteFunc(slices.Clone(batch.TrackKeys), func(k string) bool {
+		_, ok := p.keys[k]
+		return ok
+	})
+	if len(batch.TrackKeys) == 0 {
 		return nil
 	}
-	w.mutex.Unlock()
-	return os.ErrProcessDone
+	if err := p.upload(ctx, batch); nil != err {
+		var unavailableErr *UnavailableDocumentsError
+		if errors.As(err, &unavailableErr) {
+			p.deferred = true
+			return nil
+		}
+		return err
+	}
+	for _, k := range batch.TrackKeys {
+		p.keys[k] = struct{}{}
+	}
+	return nil
 }
 
-func (w *Worker) run(ctx context.Context, reply *message.RequestBuilder, link DownloadLink) error {
-	if !w.mutex.TryLock() {
-		return &JobAlreadyRunningError{ID: w.currentJob.ID}
+// uploadStep runs step, which passes batches of tracks to the given upload
+// function, with retryStep, so that tracks are posted once even if the step is
+// retried. If batches are deferred as uploaded documents of some of their tracks
+// are no longer available, the step is passed over once more, in which only the
+// unregistered tracks are downloaded, and the deferred batches are posted. It
+// returns tracks skipped by the last pass.
+func (w *Worker) uploadStep(
+	ctx context.Context,
+	flawP flaw.P,
+	upload tidaldl.BatchFunc,
+	step func(upload tidaldl.BatchFunc) ([]tidaldl.SkippedTrack, error),
+) ([]tidaldl.SkippedTrack, error) {
+	var (
+		posted  = newPostedTracks(upload)
+		skipped []tidaldl.SkippedTrack
+	)
+	pass := func() error {
+		return w.retryStep(ctx, flawP, func() (err error) {
+			skipped, err = step(posted.Upload)
+			return err
+		})
+	}
+
+	if err := pass(); nil != err {
+		return nil, err
 	}
-	defer func() {
-		w.currentJob = nil
-		w.mutex.Unlock()
-	}()
+	if posted.deferred {
+		w.logger.Info().Msg("Uploading anew tracks whose uploaded documents are no longer available")
+		posted.deferred = false
+		if err := pass(); nil != err {
+			return nil, err
+		}
+		if posted.deferred {
+			w.logger.Warn().Msg("Uploaded documents of tracks were unavailable again, leaving their batches unposted")
+		}
+	}
+	return skipped, nil
+}
 
-	flawP := flaw.P{"id": link.ID, "kind": link.Kind}
+func (w *Worker) run(ctx context.Context, reply *message.RequestBuilder, jobID string, queued QueuedJob) error {
+	link := queued.Link
+	if link.Quality == "" {
+		link.Quality = w.config.AudioQuality
+	}
+	flawP := flaw.P{"id": link.ID, "kind": link.Kind, "quality": link.Quality, "immersive": link.Immersive}
 
+	parentCtx := ctx
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	tracker := progress.New()
 	job := Job{
-		ID:        link.ID,
+		ID:        jobID,
+		Link:      link,
 		CreatedAt: time.Now(),
 		cancel:    cancel,
+		progress:  tracker,
 	}
 	flawP["job"] = job.flawP()
+
+	// The job stays current until it is done, see finishJob.
+	w.mutex.Lock()
 	w.currentJob = &job
+	if w.pendingCancelID == jobID {
+		w.pendingCancelID = ""
+		cancel()
+	}
+	w.mutex.Unlock()
 
-	downloadBaseDir := tidalfs.DownloadDirFrom("downloads")
+	w.uploader.WithProgress(tracker)
+	defer w.uploader.WithProgress(nil)
+
+	title := fmt.Sprintf("Job #%s: %s %s [%s]", jobID, link.Kind, link.ID, link.variant())
+	if nil != link.Selection {
+		title += " (" + link.Selection.String() + ")"
+	}
+	if !queued.quiet() {
+		status, err := w.newStatusMessage(ctx, reply, tracker, title)
+		if nil != err {
+			if errutil.IsContext(ctx) {
+				return ctx.Err()
+			}
+			return must.BeFlaw(err).Append(flawP)
+		}
+		defer status.Close(ctx)
+	}
+
+	// Tracks are stored per variant, so that a track downloaded in one quality or
+	// audio mode is not reused for a job requesting another one.
+	variantDir := strings.ToLower(string(link.Quality))
+	if link.Immersive {
+		variantDir += "_atmos"
+	}
+	jobDir := tidalfs.DownloadDirFrom(w.config.DownloadBaseDir).Job(jobID, variantDir)
+	if err := jobDir.Create(); nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	}
+	// With delete after upload enabled, the workspace, and tracks uploaded by the
+	// job are removed once it is finished. Otherwise, they are left to be evicted
+	// by the retention policy.
+	var uploadedTrackKeys []string
+	defer func() {
+		// A job interrupted by shutdown is resumed after restart, reusing its files.
+		if w.config.DeleteAfterUpload && !errutil.IsContext(parentCtx) {
+			w.removeJobFiles(jobID, jobDir, uploadedTrackKeys)
+		}
+	}()
+	if queued.SubscriptionID != "" {
+		defer func() {
+			// A job interrupted by shutdown posts its tracks once it is resumed.
+			if !errutil.IsContext(parentCtx) {
+				w.followedJobDone(queued.SubscriptionID, jobID, uploadedTrackKeys)
+			}
+		}()
+	}
 
 	dl := tidaldl.NewDownloader(
-		downloadBaseDir,
+		jobDir,
 		w.tidalAuth,
-		&w.cache.AlbumsMeta,
-		&w.cache.DownloadedCovers,
-		&w.cache.TrackCredits,
+		api.NewClient(w.tidalAuth, w.region()),
+		w.cache.AlbumsMeta,
+		w.cache.DownloadedCovers,
+		w.cache.TrackCredits,
+		link.Quality,
+		link.Immersive,
+		tracker,
+		func(trackKey string) bool {
+			_, ok := w.registry.Document(registry.Key(variantDir, trackKey))
+			return ok
+		},
 	)
 
 	switch link.Kind {
 	case "playlist":
 		w.logger.Info().Str("id", link.ID).Msg("Starting download playlist")
-		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Downloading playlist...</em></b>")); nil != err {
-			if errors.Is(ctx.Err(), context.Canceled) {
-				return ctx.Err()
-			}
-			return flaw.From(fmt.Errorf("failed to send message: %v", err))
-		}
+		tracker.SetPhase("Downloading and uploading playlist")
 
-		err := try.Do(func(attempt int) (retry bool, err error) {
-			const maxAttempts = 3
-			attemptRemained := attempt < maxAttempts
-			time.Sleep(time.Duration(attempt-1) * 3 * time.Second)
-
-			if err := dl.Playlist(ctx, link.ID); nil != err {
-				switch {
-				case errutil.IsContext(ctx):
-					return false, err
-				case errors.Is(err, auth.ErrUnauthorized):
-					if err := w.tidalAuth.RefreshToken(ctx); nil != err {
-						return false, err
-					}
-					return attemptRemained, nil
-				case errors.Is(err, context.DeadlineExceeded):
-					return attemptRemained, context.DeadlineExceeded
-				case errors.Is(err, tidaldl.ErrTooManyRequests):
-					return attemptRemained, tidaldl.ErrTooManyRequests
-				case errutil.IsFlaw(err):
-					return false, must.BeFlaw(err).Append(flawP)
-				default:
-					panic(errutil.UnknownError(err))
-				}
+		uploadBatch := func(ctx context.Context, batch tidaldl.TrackBatch) error {
+			if err := w.uploadPlaylistBatch(ctx, reply, jobDir, link.ID, link.Selection, batch); nil != err {
+				return err
 			}
-			return false, nil
+			uploadedTrackKeys = append(uploadedTrackKeys, batch.TrackKeys...)
+			return nil
+		}
+		skipped, err := w.uploadStep(ctx, flawP, uploadBatch, func(upload tidaldl.BatchFunc) ([]tidaldl.SkippedTrack, error) {
+			return dl.Playlist(ctx, link.ID, link.Selection, upload)
 		})
 		if nil != err {
 			return err
 		}
 
-		w.logger.Info().Str("id", link.ID).Msg("Download finished. Starting playlist upload")
-		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Download finished. Starting playlist upload...</em></b>")); nil != err {
-			if errors.Is(ctx.Err(), context.Canceled) {
-				return ctx.Err()
-			}
-			return flaw.From(fmt.Errorf("failed to send message: %v", err))
-		}
-
-		if err := w.uploadPlaylist(ctx, reply, downloadBaseDir); nil != err {
-			switch {
-			case errutil.IsContext(ctx), errors.Is(err, context.DeadlineExceeded):
-				return err
-			case errutil.IsFlaw(err):
-				return must.BeFlaw(err).Append(flawP)
-			default:
-				panic(errutil.UnknownError(err))
-			}
+		w.logger.Info().Str("id", link.ID).Int("skipped", len(skipped)).Msg("Playlist upload finished")
+		if queued.quiet() {
+			w.logSkippedTracks(skipped)
+			break
 		}
-
-		w.logger.Info().Str("id", link.ID).Msg("Playlist upload finished")
 		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Playlist uploaded successfully.</em></b>")); nil != err {
 			if errors.Is(ctx.Err(), context.Canceled) {
 				return ctx.Err()
 			}
 			return flaw.From(fmt.Errorf("failed to send message: %v", err))
 		}
-	case "album":
-		w.logger.Info().Str("id", link.ID).Msg("Starting download album")
-		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Downloading album...</em></b>")); nil != err {
-			if errors.Is(ctx.Err(), context.Canceled) {
+		if err := w.sendSkippedTracks(ctx, reply, skipped); nil != err {
+			if errutil.IsContext(ctx) {
 				return ctx.Err()
 			}
-			return flaw.From(fmt.Errorf("failed to send message: %v", err))
+			return must.BeFlaw(err).Append(flawP)
 		}
+	case "album":
+		w.logger.Info().Str("id", link.ID).Msg("Starting download album")
+		tracker.SetPhase("Downloading and uploading album")
 
-		err := try.Do(func(attempt int) (retry bool, err error) {
-			const maxAttempts = 3
-			attemptRemained := attempt < maxAttempts
-			time.Sleep(time.Duration(attempt-1) * 3 * time.Second)
-
-			if err := dl.Album(ctx, link.ID); nil != err {
-				switch {
-				case errutil.IsContext(ctx):
-					return false, err
-				case errors.Is(err, auth.ErrUnauthorized):
-					if err := w.tidalAuth.RefreshToken(ctx); nil != err {
-						return false, err
-					}
-					return attemptRemained, nil
-				case errors.Is(err, context.DeadlineExceeded):
-					return attemptRemained, context.DeadlineExceeded
-				case errors.Is(err, tidaldl.ErrTooManyRequests):
-					return attemptRemained, tidaldl.ErrTooManyRequests
-				case errutil.IsFlaw(err):
-					return false, must.BeFlaw(err).Append(flawP)
-				default:
-					panic(errutil.UnknownError(err))
-				}
+		uploadBatch := func(ctx context.Context, batch tidaldl.TrackBatch) error {
+			if err := w.uploadAlbumBatch(ctx, reply, jobDir, link.ID, link.Selection, batch); nil != err {
+				return err
 			}
-			return false, nil
+			uploadedTrackKeys = append(uploadedTrackKeys, batch.TrackKeys...)
+			return nil
+		}
+		skipped, err := w.uploadStep(ctx, flawP, uploadBatch, func(upload tidaldl.BatchFunc) ([]tidaldl.SkippedTrack, error) {
+			return dl.Album(ctx, link.ID, link.Selection, upload)
 		})
 		if nil != err {
 			return err
 		}
 
-		w.logger.Info().Str("id", link.ID).Msg("Download finished. Starting album upload")
-		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Download finished. Starting album upload...</em></b>")); nil != err {
+		w.logger.Info().Str("id", link.ID).Msg("Album upload finished")
+		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Album uploaded successfully.</em></b>")); nil != err {
 			if errors.Is(ctx.Err(), context.Canceled) {
 				return ctx.Err()
 			}
 			return flaw.From(fmt.Errorf("failed to send message: %v", err))
 		}
-
-		if err := w.uploadAlbum(ctx, reply, downloadBaseDir); nil != err {
+		if err := w.sendSkippedTracks(ctx, reply, skipped); nil != err {
 			if errutil.IsContext(ctx) {
 				return ctx.Err()
 			}
-			return flaw.From(fmt.Errorf("failed to send message: %v", err))
+			return must.BeFlaw(err).Append(flawP)
 		}
+	case "track":
+		w.logger.Info().Str("id", link.ID).Msg("Starting download track")
+		tracker.SetPhase("Downloading track")
 
-		w.logger.Info().Str("id", link.ID).Msg("Album upload finished")
-		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Album uploaded successfully.</em></b>")); nil != err {
-			if errors.Is(ctx.Err(), context.Canceled) {
+		err := w.retryStep(ctx, flawP, func() error {
+			return dl.Single(ctx, link.ID)
+		})
+		if nil != err {
+			return err
+		}
+
+		w.logger.Info().Str("id", link.ID).Msg("Download finished. Starting track upload")
+		tracker.SetPhase("Uploading track")
+
+		if err := w.uploadSingle(ctx, reply, jobDir, link.ID); nil != err {
+			if errutil.IsContext(ctx) {
 				return ctx.Err()
 			}
 			return flaw.From(fmt.Errorf("failed to send message: %v", err))
 		}
-	case "track":
-		w.logger.Info().Str("id", link.ID).Msg("Starting download track")
-		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Downloading track...</em></b>")); nil != err {
+		uploadedTrackKeys = append(uploadedTrackKeys, tidalfs.TrackKey(link.ID, 0, 0))
+
+		w.logger.Info().Str("id", link.ID).Msg("Track upload finished")
+		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Track uploaded successfully.</em></b>")); nil != err {
 			if errors.Is(ctx.Err(), context.Canceled) {
 				return ctx.Err()
 			}
 			return flaw.From(fmt.Errorf("failed to send message: %v", err))
 		}
+	case "mix":
+		w.logger.Info().Str("id", link.ID).Msg("Starting download mix")
+		tracker.SetPhase("Downloading and uploading mix")
 
-		err := try.Do(func(attempt int) (retry bool, err error) {
-			const maxAttempts = 3
-			attemptRemained := attempt < maxAttempts
-			time.Sleep(time.Duration(attempt-1) * 3 * time.Second)
-
-			if err := dl.Single(ctx, link.ID); nil != err {
-				switch {
-				case errutil.IsContext(ctx):
-					return false, err
-				case errors.Is(err, auth.ErrUnauthorized):
-					if err := w.tidalAuth.RefreshToken(ctx); nil != err {
-						return false, err
-					}
-					return attemptRemained, nil
-				case errors.Is(err, context.DeadlineExceeded):
-					return attemptRemained, context.DeadlineExceeded
-				case errors.Is(err, tidaldl.ErrTooManyRequests):
-					return attemptRemained, tidaldl.ErrTooManyRequests
-				case errutil.IsFlaw(err):
-					return false, must.BeFlaw(err).Append(flawP)
-				default:
-					panic(errutil.UnknownError(err))
-				}
+		uploadBatch := func(ctx context.Context, batch tidaldl.TrackBatch) error {
+			if err := w.uploadMixBatch(ctx, reply, jobDir, link.ID, link.Selection, batch); nil != err {
+				return err
 			}
-			return false, nil
+			uploadedTrackKeys = append(uploadedTrackKeys, batch.TrackKeys...)
+			return nil
+		}
+		skipped, err := w.uploadStep(ctx, flawP, uploadBatch, func(upload tidaldl.BatchFunc) ([]tidaldl.SkippedTrack, error) {
+			return dl.Mix(ctx, link.ID, link.Selection, upload)
 		})
 		if nil != err {
 			return err
 		}
 
-		w.logger.Info().Str("id", link.ID).Msg("Download finished. Starting track upload")
-		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Download finished. Starting track upload...</em></b>")); nil != err {
+		w.logger.Info().Str("id", link.ID).Msg("Mix upload finished")
+		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Mix uploaded successfully.</em></b>")); nil != err {
 			if errors.Is(ctx.Err(), context.Canceled) {
 				return ctx.Err()
 			}
 			return flaw.From(fmt.Errorf("failed to send message: %v", err))
 		}
-
-		if err := w.uploadSingle(ctx, reply, downloadBaseDir); nil != err {
+		if err := w.sendSkippedTracks(ctx, reply, skipped); nil != err {
 			if errutil.IsContext(ctx) {
 				return ctx.Err()
 			}
-			return flaw.From(fmt.Errorf("failed to send message: %v", err))
+			return must.BeFlaw(err).Append(flawP)
 		}
+	case "video":
+		w.logger.Info().Str("id", link.ID).Msg("Starting download video")
+		tracker.SetPhase("Downloading video")
 
-		w.logger.Info().Str("id", link.ID).Msg("Track upload finished")
-		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Track uploaded successfully.</em></b>")); nil != err {
-			if errors.Is(ctx.Err(), context.Canceled) {
+		err := w.retryStep(ctx, flawP, func() error {
+			return dl.Video(ctx, link.ID, w.config.VideoMaxResolution)
+		})
+		if nil != err {
+			return err
+		}
+
+		w.logger.Info().Str("id", link.ID).Msg("Download finished. Starting video upload")
+		tracker.SetPhase("Uploading video")
+
+		if err := w.uploadVideo(ctx, reply, jobDir, link.ID); nil != err {
+			if errutil.IsContext(ctx) {
 				return ctx.Err()
 			}
 			return flaw.From(fmt.Errorf("failed to send message: %v", err))
 		}
-	case "mix":
-		w.logger.Info().Str("id", link.ID).Msg("Starting download mix")
-		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Downloading mix...</em></b>")); nil != err {
+
+		w.logger.Info().Str("id", link.ID).Msg("Video upload finished")
+		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Video uploaded successfully.</em></b>")); nil != err {
 			if errors.Is(ctx.Err(), context.Canceled) {
 				return ctx.Err()
 			}
 			return flaw.From(fmt.Errorf("failed to send message: %v", err))
 		}
+	case "artist":
+		w.logger.Info().Str("id", link.ID).Msg("Starting download artist")
+		tracker.SetPhase("Listing artist releases")
 
-		err := try.Do(func(attempt int) (retry bool, err error) {
-			const maxAttempts = 3
-			attemptRemained := attempt < maxAttempts
-			time.Sleep(time.Duration(attempt-1) * 3 * time.Second)
-
-			if err := dl.Mix(ctx, link.ID); nil != err {
-				switch {
-				case errutil.IsContext(ctx):
-					return false, err
-				case errors.Is(err, auth.ErrUnauthorized):
-					if err := w.tidalAuth.RefreshToken(ctx); nil != err {
-						return false, err
-					}
-					return attemptRemained, nil
-				case errors.Is(err, context.DeadlineExceeded):
-					return attemptRemained, context.DeadlineExceeded
-				case errors.Is(err, tidaldl.ErrTooManyRequests):
-					return attemptRemained, tidaldl.ErrTooManyRequests
-				case errutil.IsFlaw(err):
-					return false, must.BeFlaw(err).Append(flawP)
-				default:
-					panic(errutil.UnknownError(err))
-				}
-			}
-			return false, nil
+		var albums []tidaldl.ArtistAlbumMeta
+		err := w.retryStep(ctx, flawP, func() (err error) {
+			albums, err = dl.Artist(ctx, link.ID, w.config.ArtistIncludeCompilations)
+			return err
 		})
 		if nil != err {
 			return err
 		}
 
-		w.logger.Info().Str("id", link.ID).Msg("Download finished. Starting mix upload")
-		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Download finished. Starting mix upload...</em></b>")); nil != err {
-			if errors.Is(ctx.Err(), context.Canceled) {
-				return ctx.Err()
+		var skipped []tidaldl.SkippedTrack
+		for i, album := range albums {
+			albumFlawP := flaw.P{"album_id": album.ID, "album_index": i}
+			w.logger.Info().Str("id", link.ID).Str("album_id", album.ID).Msg("Starting download artist album")
+			release := fmt.Sprintf("release %d/%d: %s (%s)", i+1, len(albums), album.Title, album.ReleaseDate.Format(tidal.ReleaseDateLayout))
+			tracker.SetPhase("Downloading and uploading " + release)
+
+			uploadBatch := func(ctx context.Context, batch tidaldl.TrackBatch) error {
+				if err := w.uploadAlbumBatch(ctx, reply, jobDir, album.ID, nil, batch); nil != err {
+					return err
+				}
+				uploadedTrackKeys = append(uploadedTrackKeys, batch.TrackKeys...)
+				return nil
 			}
-			return flaw.From(fmt.Errorf("failed to send message: %v", err))
+			albumSkipped, err := w.uploadStep(ctx, lo.Assign(flawP, albumFlawP), uploadBatch, func(upload tidaldl.BatchFunc) ([]tidaldl.SkippedTrack, error) {
+				return dl.Album(ctx, album.ID, nil, upload)
+			})
+			if nil != err {
+				return err
+			}
+			skipped = append(skipped, albumSkipped...)
 		}
 
-		if err := w.uploadMix(ctx, reply, downloadBaseDir); nil != err {
-			if errutil.IsContext(ctx) {
+		w.logger.Info().Str("id", link.ID).Msg("Artist upload finished")
+		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Artist releases uploaded successfully.</em></b>")); nil != err {
+			if errors.Is(ctx.Err(), context.Canceled) {
 				return ctx.Err()
 			}
 			return flaw.From(fmt.Errorf("failed to send message: %v", err))
 		}
-
-		w.logger.Info().Str("id", link.ID).Msg("Mix upload finished")
-		if _, err := reply.StyledText(ctx, html.Format(nil, "<b><em>Mix uploaded successfully.</em></b>")); nil != err {
-			if errors.Is(ctx.Err(), context.Canceled) {
+		if err := w.sendSkippedTracks(ctx, reply, skipped); nil != err {
+			if errutil.IsContext(ctx) {
 				return ctx.Err()
 			}
-			return flaw.From(fmt.Errorf("failed to send message: %v", err))
+			return must.BeFlaw(err).Append(flawP)
 		}
 	default:
 		if _, err := reply.StyledText(ctx, html.Format(nil, "<em>Unsupported media kind: <b>%s</b>.</em>", link.Kind)); nil != err {
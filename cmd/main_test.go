@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tidaldl "github.com/xeptore/tgtd/tidal/download"
+)
+
+func newBatch(index int, keys ...string) tidaldl.TrackBatch {
+	return tidaldl.TrackBatch{Volume: 0, Index: index, Total: 2, TrackKeys: keys}
+}
+
+func TestPostedTracks(t *testing.T) {
+	t.Parallel()
+
+	t.Run("skips_posted", func(t *testing.T) {
+		t.Parallel()
+
+		var uploaded [][]string
+		posted := newPostedTracks(func(_ context.Context, batch tidaldl.TrackBatch) error {
+			uploaded = append(uploaded, batch.TrackKeys)
+			return nil
+		})
+		require.NoError(t, posted.Upload(t.Context(), newBatch(0, "1", "2")))
+		require.NoError(t, posted.Upload(t.Context(), newBatch(0, "1", "2")))
+		require.NoError(t, posted.Upload(t.Context(), newBatch(1, "2", "3")))
+		assert.Exactly(t, [][]string{{"1", "2"}, {"3"}}, uploaded)
+		assert.False(t, posted.deferred)
+	})
+
+	t.Run("defers_deleted_origin", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			uploaded [][]string
+			deleted  = true
+		)
+		posted := newPostedTracks(func(_ context.Context, batch tidaldl.TrackBatch) error {
+			if batch.Index == 0 && deleted {
+				return &UnavailableDocumentsError{RegistryKeys: []string{"lossless/2"}}
+			}
+			uploaded = append(uploaded, batch.TrackKeys)
+			return nil
+		})
+
+		// The batch with a deleted origin message does not fail the step.
+		require.NoError(t, posted.Upload(t.Context(), newBatch(0, "1", "2")))
+		require.NoError(t, posted.Upload(t.Context(), newBatch(1, "3")))
+		assert.True(t, posted.deferred)
+		assert.Exactly(t, [][]string{{"3"}}, uploaded)
+
+		// Passing over the step again posts the deferred batch as a whole once its
+		// tracks are uploaded anew, and leaves out the already posted one.
+		deleted, posted.deferred = false, false
+		require.NoError(t, posted.Upload(t.Context(), newBatch(0, "1", "2")))
+		require.NoError(t, posted.Upload(t.Context(), newBatch(1, "3")))
+		assert.False(t, posted.deferred)
+		assert.Exactly(t, [][]string{{"3"}, {"1", "2"}}, uploaded)
+	})
+
+	t.Run("returns_other_errors", func(t *testing.T) {
+		t.Parallel()
+
+		errUpload := errors.New("upload failed")
+		posted := newPostedTracks(func(context.Context, tidaldl.TrackBatch) error { return errUpload })
+		require.ErrorIs(t, posted.Upload(t.Context(), newBatch(0, "1")), errUpload)
+		assert.False(t, posted.deferred)
+	})
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/log"
+	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/registry"
+)
+
+// errDocumentUnavailable is returned when the message a registered document was
+// sent in is no longer available, e.g., as it is deleted.
+var errDocumentUnavailable = errors.New("document is no longer available")
+
+// UnavailableDocumentsError is returned when registered documents of some tracks
+// of a batch are no longer available. The documents are unregistered, so that
+// the tracks are downloaded, and uploaded anew when the batch is passed again.
+type UnavailableDocumentsError struct {
+	// RegistryKeys are keys the unavailable documents were registered with.
+	RegistryKeys []string
+}
+
+func (e *UnavailableDocumentsError) Error() string {
+	return fmt.Sprintf("%d uploaded track(s) are no longer available", len(e.RegistryKeys))
+}
+
+func isFileReferenceError(err error) bool {
+	rpcErr, ok := tgerr.As(err)
+	return ok && strings.HasPrefix(rpcErr.Type, "FILE_REFERENCE_")
+}
+
+// refreshDocuments refreshes file references of registered documents of batch
+// from messages they were sent in. Documents which are no longer available are
+// unregistered, and reported by an UnavailableDocumentsError once the rest are
+// refreshed.
+func (w *Worker) refreshDocuments(ctx context.Context, batch []TrackUploadInfo) error {
+	var unavailable []string
+	for _, item := range batch {
+		if nil == item.Document {
+			continue
+		}
+
+		flawP := flaw.P{"registry_key": item.RegistryKey}
+		doc, err := w.originDocument(ctx, *item.Document)
+		if nil != err {
+			switch {
+			case errutil.IsContext(ctx):
+				return ctx.Err()
+			case errors.Is(err, errDocumentUnavailable):
+				unavailable = append(unavailable, item.RegistryKey)
+				continue
+			case errutil.IsFlaw(err):
+				return must.BeFlaw(err).Append(flawP)
+			default:
+				panic(errutil.UnknownError(err))
+			}
+		}
+		item.Document.FileReference = doc.FileReference
+	}
+
+	if len(unavailable) == 0 {
+		return nil
+	}
+	if err := w.registry.Remove(unavailable...); nil != err {
+		return must.BeFlaw(err).Append(flaw.P{"registry_keys": unavailable})
+	}
+	return &UnavailableDocumentsError{RegistryKeys: unavailable}
+}
+
+// originDocument returns doc as it is in the message it was sent in, with a
+// fresh file reference.
+func (w *Worker) originDocument(ctx context.Context, doc registry.Document) (*tg.Document, error) {
+	var (
+		api = w.client.API()
+		ids = []tg.InputMessageClass{&tg.InputMessageID{ID: doc.Origin.MessageID}}
+		res tg.MessagesMessagesClass
+		err error
+	)
+	if doc.Origin.PeerKind == jobPeerKindChannel {
+		res, err = api.ChannelsGetMessages(ctx, &tg.ChannelsGetMessagesRequest{
+			Channel: &tg.InputChannel{ChannelID: doc.Origin.PeerID, AccessHash: doc.Origin.PeerAccessHash},
+			ID:      ids,
+		})
+	} else {
+		res, err = api.MessagesGetMessages(ctx, ids)
+	}
+	if nil != err {
+		if errutil.IsContext(ctx) {
+			return nil, ctx.Err()
+		}
+		flawP := flaw.P{"message_id": doc.Origin.MessageID, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return nil, flaw.From(fmt.Errorf("failed to get message of uploaded document: %v", err)).Append(flawP)
+	}
+
+	messages, ok := res.AsModified()
+	if !ok {
+		return nil, errDocumentUnavailable
+	}
+	for _, sent := range messageDocuments(messages.GetMessages()) {
+		if sent.document.ID == doc.ID {
+			return sent.document, nil
+		}
+	}
+	return nil, errDocumentUnavailable
+}
+
+// registerDocuments registers documents of batch tracks sent in updates, so that
+// they are sent again instead of being downloaded, and uploaded anew. Failing to
+// register only costs uploading tracks again, hence it is logged rather than
+// returned.
+func (w *Worker) registerDocuments(ctx context.Context, reply *message.RequestBuilder, batch []TrackUploadInfo, updates tg.UpdatesClass) {
+	inputPeer, err := reply.AsInputPeer(ctx)
+	if nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to resolve peer of uploaded documents")
+		return
+	}
+	peer, err := jobPeerFrom(inputPeer)
+	if nil != err {
+		w.logger.Error().Func(log.Flaw(err)).Msg("Failed to convert peer of uploaded documents")
+		return
+	}
+
+	var messages []tg.MessageClass
+	if u, ok := updates.(interface{ GetUpdates() []tg.UpdateClass }); ok {
+		for _, update := range u.GetUpdates() {
+			switch update := update.(type) {
+			case *tg.UpdateNewMessage:
+				messages = append(messages, update.Message)
+			case *tg.UpdateNewChannelMessage:
+				messages = append(messages, update.Message)
+			}
+		}
+	}
+	sent := messageDocuments(messages)
+	if len(sent) != len(batch) {
+		w.logger.Warn().Int("sent", len(sent)).Int("batch", len(batch)).Msg("Sent documents do not match uploaded tracks, skipping registration")
+		return
+	}
+
+	documents := make(map[string]registry.Document, len(batch))
+	for i, item := range batch {
+		documents[item.RegistryKey] = registry.Document{
+			ID:            sent[i].document.ID,
+			AccessHash:    sent[i].document.AccessHash,
+			FileReference: sent[i].document.FileReference,
+			Format:        item.Format,
+			Origin: registry.Origin{
+				PeerKind:       peer.Kind,
+				PeerID:         peer.ID,
+				PeerAccessHash: peer.AccessHash,
+				MessageID:      sent[i].messageID,
+			},
+		}
+	}
+	if err := w.registry.Put(documents); nil != err {
+		w.logger.Error().Func(log.Flaw(err)).Msg("Failed to register uploaded documents")
+	}
+}
+
+type sentDocument struct {
+	messageID int
+	document  *tg.Document
+}
+
+// messageDocuments returns documents of messages in order they were sent.
+func messageDocuments(messages []tg.MessageClass) []sentDocument {
+	var out []sentDocument
+	for _, msg := range messages {
+		m, ok := msg.(*tg.Message)
+		if !ok {
+			continue
+		}
+		media, ok := m.Media.(*tg.MessageMediaDocument)
+		if !ok {
+			continue
+		}
+		docClass, ok := media.GetDocument()
+		if !ok {
+			continue
+		}
+		doc, ok := docClass.AsNotEmpty()
+		if !ok {
+			continue
+		}
+		out = append(out, sentDocument{messageID: m.ID, document: doc})
+	}
+	slices.SortFunc(out, func(a, b sentDocument) int { return cmp.Compare(a.messageID, b.messageID) })
+	return out
+}
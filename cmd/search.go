@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/message/markup"
+	"github.com/gotd/td/telegram/message/styling"
+	"github.com/gotd/td/tg"
+	"github.com/samber/lo"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/log"
+	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/tidal/api"
+	"github.com/xeptore/tgtd/tidal/auth"
+)
+
+const (
+	// searchResultsLimit is the number of results of each type listed by /search.
+	searchResultsLimit = 3
+	// maxButtonTextLength is the maximum length of result button texts, beyond
+	// which they are truncated.
+	maxButtonTextLength = 60
+)
+
+// search searches TIDAL for query, refreshing the access token once if it has
+// expired.
+func (w *Worker) search(ctx context.Context, query string, limit int) (*api.SearchResult, error) {
+	client := api.NewClient(w.tidalAuth, w.region())
+	res, err := client.Search(ctx, query, limit)
+	if errors.Is(err, auth.ErrUnauthorized) {
+		if err := w.tidalAuth.RefreshToken(ctx); nil != err {
+			return nil, err
+		}
+		res, err = client.Search(ctx, query, limit)
+	}
+	return res, err
+}
+
+func (w *Worker) processSearch(ctx context.Context, reply *message.RequestBuilder, query string) {
+	if query == "" {
+		lines := []styling.StyledTextOption{
+			styling.Plain("Search with "),
+			styling.Code("/search <query>"),
+		}
+		if _, err := reply.StyledText(ctx, lines...); nil != err {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return
+			}
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+		}
+		return
+	}
+
+	res, err := w.search(ctx, query, searchResultsLimit)
+	if nil != err {
+		var text string
+		switch {
+		case errutil.IsContext(ctx):
+			return
+		case errors.Is(err, auth.ErrUnauthorized):
+			text = "TIDAL authentication expired. Please reauthorize the application."
+		case errors.Is(err, context.DeadlineExceeded):
+			text = "Search timed out."
+		case errors.Is(err, api.ErrTooManyRequests):
+			text = "TIDAL is rate limiting requests. Try again later."
+		case errutil.IsFlaw(err):
+			w.logger.Error().Func(log.Flaw(must.BeFlaw(err).Append(flaw.P{"query": query}))).Msg("Failed to search TIDAL")
+			text = "Failed to search TIDAL."
+		default:
+			panic(errutil.UnknownError(err))
+		}
+		if _, err := reply.StyledText(ctx, styling.Plain(text)); nil != err {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return
+			}
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+		}
+		return
+	}
+
+	rows := searchResultRows(res)
+	if len(rows) == 0 {
+		if _, err := reply.StyledText(ctx, styling.Plain("No results were found for "), styling.Code(query), styling.Plain(".")); nil != err {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return
+			}
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+		}
+		return
+	}
+
+	lines := []styling.StyledTextOption{
+		styling.Plain("Results for "),
+		styling.Code(query),
+		styling.Plain(". Tap one to download it."),
+	}
+	if _, err := reply.Markup(markup.InlineKeyboard(rows...)).StyledText(ctx, lines...); nil != err {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		w.logger.Error().Func(log.Flaw(flaw.From(err).Append(flawP))).Msg("Failed to send reply")
+	}
+}
+
+// searchItem is a search result of any type.
+type searchItem struct {
+	kind     string
+	id       string
+	title    string
+	subtitle string
+	// imageID is the ID of the cover, or picture of the item, if it has any.
+	imageID string
+}
+
+func (i searchItem) label() string {
+	switch i.kind {
+	case "track":
+		return "Track"
+	case "album":
+		return "Album"
+	case "playlist":
+		return "Playlist"
+	case "artist":
+		return "Artist"
+	default:
+		panic("unexpected search item kind: " + i.kind)
+	}
+}
+
+// searchItems flattens res into tracks, albums, playlists, and artists in order.
+func searchItems(res *api.SearchResult) []searchItem {
+	var items []searchItem
+	for _, t := range res.Tracks.Items {
+		items = append(items, searchItem{kind: "track", id: strconv.Itoa(t.ID), title: t.Title, subtitle: t.Artist.Name, imageID: t.Album.CoverID})
+	}
+	for _, a := range res.Albums.Items {
+		items = append(items, searchItem{kind: "album", id: strconv.Itoa(a.ID), title: a.Title, subtitle: albumArtist(a.Artists), imageID: lo.FromPtr(a.CoverID)})
+	}
+	for _, p := range res.Playlists.Items {
+		items = append(items, searchItem{kind: "playlist", id: p.UUID, title: p.Title, subtitle: fmt.Sprintf("%d tracks", p.TotalTracks), imageID: lo.FromPtr(p.SquareImage)})
+	}
+	for _, a := range res.Artists.Items {
+		items = append(items, searchItem{kind: "artist", id: strconv.Itoa(a.ID), title: a.Name, subtitle: "", imageID: lo.FromPtr(a.Picture)})
+	}
+	return items
+}
+
+// searchResultRows returns a row with a download button for each result.
+func searchResultRows(res *api.SearchResult) []tg.KeyboardButtonRow {
+	items := searchItems(res)
+	rows := make([]tg.KeyboardButtonRow, len(items))
+	for i, item := range items {
+		text := item.label() + ": " + item.title
+		if item.subtitle != "" {
+			text = item.label() + ": " + item.subtitle + " - " + item.title
+		}
+		link := DownloadLink{Kind: item.kind, ID: item.id, Quality: "", Immersive: false, Selection: nil}
+		rows[i] = markup.Row(markup.Callback(truncate(text, maxButtonTextLength), downloadCallbackData(link)))
+	}
+	return rows
+}
+
+// albumArtist returns name of the first artist of artists, i.e., the main one.
+func albumArtist(artists []api.Artist) string {
+	if len(artists) == 0 {
+		return "Unknown"
+	}
+	return artists[0].Name
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return strings.TrimSpace(string(r[:n-1])) + "…"
+	}
+	return s
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/message/styling"
+	"github.com/rs/zerolog"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/config"
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/progress"
+	"github.com/xeptore/tgtd/tgutil"
+)
+
+// StatusMessage is a single message that is periodically edited in place to reflect
+// progress of the job it was created for. Edits are throttled, skipped if nothing
+// has changed since the last one, and paused while Telegram asks to wait.
+type StatusMessage struct {
+	reply   *message.RequestBuilder
+	tracker *progress.Tracker
+	logger  zerolog.Logger
+	title   string
+	msgID   int
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	last    uint64
+}
+
+func (w *Worker) newStatusMessage(ctx context.Context, reply *message.RequestBuilder, tracker *progress.Tracker, title string) (*StatusMessage, error) {
+	s := &StatusMessage{
+		reply:   reply,
+		tracker: tracker,
+		logger:  w.logger.With().Str("module", "status").Logger(),
+		title:   title,
+		msgID:   0,
+		cancel:  nil,
+		wg:      sync.WaitGroup{},
+		last:    0,
+	}
+
+	snapshot := tracker.Snapshot()
+	u, err := reply.StyledText(ctx, s.render(snapshot)...)
+	if nil != err {
+		if errutil.IsContext(ctx) {
+			return nil, ctx.Err()
+		}
+
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return nil, flaw.From(fmt.Errorf("failed to send status message: %v", err)).Append(flawP)
+	}
+	msgID, ok := tgutil.SentMessageID(u)
+	if !ok {
+		return nil, flaw.From(fmt.Errorf("failed to extract status message ID from updates of type %T", u))
+	}
+	s.msgID = msgID
+	s.last = snapshot.Version
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(ctx)
+	}()
+
+	return s, nil
+}
+
+func (s *StatusMessage) loop(ctx context.Context) {
+	ticker := time.NewTicker(config.StatusMessageUpdateInterval)
+	defer ticker.Stop()
+
+	var waitUntil time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if now.Before(waitUntil) {
+				continue
+			}
+			if timeout, ok := s.update(ctx); !ok {
+				waitUntil = now.Add(timeout)
+			}
+		}
+	}
+}
+
+// update edits the message with the latest snapshot, if it has changed. It reports
+// false along with the duration to wait before the next edit if Telegram has asked
+// to slow down.
+func (s *StatusMessage) update(ctx context.Context) (time.Duration, bool) {
+	snapshot := s.tracker.Snapshot()
+	if snapshot.Version == s.last {
+		return 0, true
+	}
+
+	if _, err := s.reply.Edit(s.msgID).StyledText(ctx, s.render(snapshot)...); nil != err {
+		if errutil.IsContext(ctx) {
+			return 0, true
+		}
+		if timeout, ok := telegram.AsFloodWait(err); ok {
+			s.logger.Warn().Dur("duration", timeout).Msg("Hit FLOOD_WAIT error while updating status message")
+			return timeout, false
+		}
+		s.logger.Error().Err(err).Msg("Failed to update status message")
+		return 0, true
+	}
+	s.last = snapshot.Version
+
+	return 0, true
+}
+
+// Close stops periodic updates and makes a final edit with the latest progress.
+func (s *StatusMessage) Close(ctx context.Context) {
+	s.cancel()
+	s.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), config.StatusMessageEditTimeout)
+	defer cancel()
+	s.update(ctx)
+}
+
+func (s *StatusMessage) render(snapshot progress.Snapshot) []styling.StyledTextOption {
+	phase := snapshot.Phase
+	if phase == "" {
+		phase = "Starting"
+	}
+
+	lines := []styling.StyledTextOption{
+		styling.Bold(s.title),
+		styling.Plain("\n"),
+		styling.Italic(phase),
+		styling.Plain("\n\n"),
+		styling.Plain(fmt.Sprintf("Downloaded: %d/%d tracks (%s)", snapshot.DownloadedTracks, snapshot.TotalTracks, formatBytes(snapshot.DownloadedBytes))),
+		styling.Plain("\n"),
+		styling.Plain(fmt.Sprintf("Uploaded: %d/%d tracks", snapshot.UploadedTracks, snapshot.TotalTracks)),
+	}
+	if snapshot.UploadTotalBytes > 0 {
+		lines = append(
+			lines,
+			styling.Plain(fmt.Sprintf(" (%s/%s)", formatBytes(snapshot.UploadedBytes), formatBytes(snapshot.UploadTotalBytes))),
+		)
+	}
+	return lines
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
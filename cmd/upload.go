@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
@@ -19,185 +21,192 @@ import (
 
 	"github.com/xeptore/tgtd/cache"
 	"github.com/xeptore/tgtd/errutil"
-	"github.com/xeptore/tgtd/iterutil"
-	"github.com/xeptore/tgtd/mathutil"
+	"github.com/xeptore/tgtd/log"
 	"github.com/xeptore/tgtd/must"
 	"github.com/xeptore/tgtd/ratelimit"
+	"github.com/xeptore/tgtd/registry"
 	"github.com/xeptore/tgtd/tidal"
+	tidaldl "github.com/xeptore/tgtd/tidal/download"
 	tidalfs "github.com/xeptore/tgtd/tidal/fs"
 )
 
-func (w *Worker) uploadAlbum(ctx context.Context, reply *message.RequestBuilder, dir tidalfs.DownloadDir) error {
-	albumFs := dir.Album(w.currentJob.ID)
+func (w *Worker) uploadAlbumBatch(ctx context.Context, reply *message.RequestBuilder, dir tidalfs.JobDir, id string, sel *tidal.Selection, batch tidaldl.TrackBatch) error {
+	albumFs := dir.Album(id)
 
 	info, err := albumFs.InfoFile.Read()
 	if nil != err {
 		return err
 	}
 
-	for volIdx, trackIDs := range info.VolumeTrackIDs {
-		var (
-			volNum     = volIdx + 1
-			batchSize  = mathutil.OptimalAlbumSize(len(trackIDs))
-			numBatches = mathutil.CeilInts(len(trackIDs), batchSize)
-			loopFlawPs = make([]flaw.P, numBatches)
-			flawP      = flaw.P{"loop_payloads": loopFlawPs}
-			batches    = iterutil.WithIndex(slices.Chunk(trackIDs, batchSize))
-		)
-		for i, trackIDs := range batches {
-			caption := []styling.StyledTextOption{
-				styling.Plain(info.Caption),
-				styling.Plain("\n"),
-				styling.Italic(fmt.Sprintf("Part: %d/%d", i+1, numBatches)),
-			}
+	caption := batchCaption(info.Caption, sel, batch)
 
-			items := make([]TrackUploadInfo, len(trackIDs))
-			for i, trackID := range trackIDs {
-				trackFs := albumFs.Track(volNum, trackID)
-				track, err := trackFs.InfoFile.Read()
-				if nil != err {
-					return err
-				}
-				info := TrackUploadInfo{
-					FilePath:   trackFs.Path,
-					ArtistName: tidal.JoinArtists(track.Artists),
-					Title:      track.Title,
-					Version:    track.Version,
-					Duration:   track.Duration,
-					Format:     track.Format,
-					CoverID:    track.CoverID,
-					CoverPath:  albumFs.Cover.Path,
-				}
-				items[i] = info
-			}
+	items := make([]TrackUploadInfo, len(batch.TrackKeys))
+	for i, key := range batch.TrackKeys {
+		trackFs := albumFs.Track(key)
+		info, err := w.batchTrackUploadInfo(dir.Variant(), key, trackFs.Path, trackFs.InfoFile, albumFs.Cover.Path)
+		if nil != err {
+			return err
+		}
+		items[i] = *info
+	}
 
-			if err := w.uploadTracksBatch(ctx, reply, items, caption); nil != err {
-				if errutil.IsContext(ctx) {
-					return ctx.Err()
-				}
-				return must.BeFlaw(err).Append(flawP)
-			}
+	if err := w.uploadTracksBatch(ctx, reply, items, caption); nil != err {
+		var unavailableErr *UnavailableDocumentsError
+		switch {
+		case errutil.IsContext(ctx):
+			return ctx.Err()
+		case errors.As(err, &unavailableErr):
+			return unavailableErr
+		case errutil.IsFlaw(err):
+			return must.BeFlaw(err).Append(flaw.P{"volume": batch.Volume, "batch_index": batch.Index})
+		default:
+			panic(errutil.UnknownError(err))
 		}
 	}
 	return nil
 }
 
-func (w *Worker) uploadPlaylist(ctx context.Context, reply *message.RequestBuilder, dir tidalfs.DownloadDir) error {
-	playlistFs := dir.Playlist(w.currentJob.ID)
+func (w *Worker) uploadPlaylistBatch(ctx context.Context, reply *message.RequestBuilder, dir tidalfs.JobDir, id string, sel *tidal.Selection, batch tidaldl.TrackBatch) error {
+	playlistFs := dir.Playlist(id)
 
 	info, err := playlistFs.InfoFile.Read()
 	if nil != err {
 		return err
 	}
-
-	var (
-		batchSize  = mathutil.OptimalAlbumSize(len(info.TrackIDs))
-		batches    = iterutil.WithIndex(slices.Chunk(info.TrackIDs, batchSize))
-		numBatches = mathutil.CeilInts(len(info.TrackIDs), batchSize)
-	)
-	for i, trackIDs := range batches {
-		caption := []styling.StyledTextOption{
-			styling.Plain(info.Caption),
-			styling.Plain("\n"),
-			styling.Italic(fmt.Sprintf("Part: %d/%d", i+1, numBatches)),
-		}
-
-		items := make([]TrackUploadInfo, len(trackIDs))
-		for i, trackID := range trackIDs {
-			trackFs := playlistFs.Track(trackID)
-			track, err := trackFs.InfoFile.Read()
-			if nil != err {
-				return err
-			}
-			info := TrackUploadInfo{
-				FilePath:   trackFs.Path,
-				ArtistName: tidal.JoinArtists(track.Artists),
-				Title:      track.Title,
-				Version:    track.Version,
-				Duration:   track.Duration,
-				Format:     track.Format,
-				CoverID:    track.CoverID,
-				CoverPath:  trackFs.Cover.Path,
-			}
-			items[i] = info
-		}
-
-		if err := w.uploadTracksBatch(ctx, reply, items, caption); nil != err {
-			if errutil.IsContext(ctx) {
-				return ctx.Err()
-			}
-			return must.BeFlaw(err)
-		}
-	}
-	return nil
+	return w.uploadListBatch(ctx, reply, batchCaption(info.Caption, sel, batch), dir.Variant(), playlistFs.Track, batch)
 }
 
-func (w *Worker) uploadMix(ctx context.Context, reply *message.RequestBuilder, dir tidalfs.DownloadDir) error {
-	mixFs := dir.Mix(w.currentJob.ID)
+func (w *Worker) uploadMixBatch(ctx context.Context, reply *message.RequestBuilder, dir tidalfs.JobDir, id string, sel *tidal.Selection, batch tidaldl.TrackBatch) error {
+	mixFs := dir.Mix(id)
 
 	info, err := mixFs.InfoFile.Read()
 	if nil != err {
 		return err
 	}
+	return w.uploadListBatch(ctx, reply, batchCaption(info.Caption, sel, batch), dir.Variant(), mixFs.Track, batch)
+}
 
-	var (
-		batchSize  = mathutil.OptimalAlbumSize(len(info.TrackIDs))
-		batches    = iterutil.WithIndex(slices.Chunk(info.TrackIDs, batchSize))
-		numBatches = mathutil.CeilInts(len(info.TrackIDs), batchSize)
-	)
-	for i, trackIDs := range batches {
-		caption := []styling.StyledTextOption{
-			styling.Plain(info.Caption),
-			styling.Plain("\n"),
-			styling.Italic(fmt.Sprintf("Part: %d/%d", i+1, numBatches)),
-		}
+// batchCaption returns caption of a batch of album, playlist, or mix tracks, which
+// mentions sel if only a subset of tracks is selected.
+func batchCaption(caption string, sel *tidal.Selection, batch tidaldl.TrackBatch) []styling.StyledTextOption {
+	lines := []styling.StyledTextOption{
+		styling.Plain(caption),
+		styling.Plain("\n"),
+	}
+	if nil != sel {
+		lines = append(lines, styling.Italic("Selection: "+sel.String()), styling.Plain("\n"))
+	}
+	return append(lines, styling.Italic(fmt.Sprintf("Part: %d/%d", batch.Index+1, batch.Total)))
+}
 
-		items := make([]TrackUploadInfo, len(trackIDs))
-		for i, trackID := range trackIDs {
-			trackFs := mixFs.Track(trackID)
-			track, err := trackFs.InfoFile.Read()
-			if nil != err {
-				return err
-			}
-			info := TrackUploadInfo{
-				FilePath:   trackFs.Path,
-				ArtistName: tidal.JoinArtists(track.Artists),
-				Title:      track.Title,
-				Version:    track.Version,
-				Duration:   track.Duration,
-				Format:     track.Format,
-				CoverID:    track.CoverID,
-				CoverPath:  trackFs.Cover.Path,
-			}
-			items[i] = info
+// uploadListBatch uploads a batch of playlist, or mix tracks, which are stored
+// at paths returned by trackFs.
+func (w *Worker) uploadListBatch(
+	ctx context.Context,
+	reply *message.RequestBuilder,
+	caption []styling.StyledTextOption,
+	variant string,
+	trackFs func(key string) tidalfs.SingleTrack,
+	batch tidaldl.TrackBatch,
+) error {
+	items := make([]TrackUploadInfo, len(batch.TrackKeys))
+	for i, key := range batch.TrackKeys {
+		trackFs := trackFs(key)
+		info, err := w.batchTrackUploadInfo(variant, key, trackFs.Path, trackFs.InfoFile, trackFs.Cover.Path)
+		if nil != err {
+			return err
 		}
+		items[i] = *info
+	}
 
-		if err := w.uploadTracksBatch(ctx, reply, items, caption); nil != err {
-			if errutil.IsContext(ctx) {
-				return ctx.Err()
-			}
-			return must.BeFlaw(err)
+	if err := w.uploadTracksBatch(ctx, reply, items, caption); nil != err {
+		var unavailableErr *UnavailableDocumentsError
+		switch {
+		case errutil.IsContext(ctx):
+			return ctx.Err()
+		case errors.As(err, &unavailableErr):
+			return unavailableErr
+		case errutil.IsFlaw(err):
+			return must.BeFlaw(err).Append(flaw.P{"batch_index": batch.Index})
+		default:
+			panic(errutil.UnknownError(err))
 		}
 	}
 	return nil
 }
 
+// batchTrackUploadInfo returns upload info of the track stored with key in the
+// track store of variant, which is sent by its registered document if it is
+// already uploaded.
+func (w *Worker) batchTrackUploadInfo(
+	variant string,
+	key string,
+	trackPath string,
+	infoFile tidalfs.InfoFile[tidalfs.StoredSingleTrack],
+	coverPath string,
+) (*TrackUploadInfo, error) {
+	registryKey := registry.Key(variant, key)
+	if doc, ok := w.registry.Document(registryKey); ok {
+		return &TrackUploadInfo{
+			FilePath:    "",
+			ArtistName:  "",
+			Title:       "",
+			Version:     nil,
+			Duration:    0,
+			Format:      doc.Format,
+			CoverID:     "",
+			CoverPath:   "",
+			RegistryKey: registryKey,
+			Document:    &doc,
+		}, nil
+	}
+
+	track, err := infoFile.Read()
+	if nil != err {
+		return nil, err
+	}
+	return &TrackUploadInfo{
+		FilePath:    trackPath,
+		ArtistName:  tidal.JoinArtists(track.Artists),
+		Title:       track.Title,
+		Version:     track.Version,
+		Duration:    track.Duration,
+		Format:      track.Format,
+		CoverID:     track.CoverID,
+		CoverPath:   coverPath,
+		RegistryKey: registryKey,
+		Document:    nil,
+	}, nil
+}
+
 func (w *Worker) uploadTracksBatch(ctx context.Context, reply *message.RequestBuilder, batch []TrackUploadInfo, caption []styling.StyledTextOption) (err error) {
 	var (
 		album = make([]message.MultiMediaOption, len(batch))
 		flawP = make(flaw.P)
 	)
 
+	// Caption is attached to the last track in the batch.
+	captionOf := func(i int) []styling.StyledTextOption {
+		if i != len(batch)-1 {
+			return nil
+		}
+		return append(caption, styling.Plain("\n"), styling.Italic(qualityCaption(batch)), styling.Plain("\n"), html.String(nil, w.config.Signature))
+	}
+
 	wg, wgCtx := errgroup.WithContext(ctx)
 	wg.SetLimit(ratelimit.BatchUploadConcurrency)
 
 	loopFlawPs := make([]flaw.P, len(batch))
 	flawP["loop_payloads"] = loopFlawPs
 	for i, item := range batch {
+		if nil != item.Document {
+			album[i] = message.Document(item.Document, captionOf(i)...)
+			continue
+		}
+
 		wg.Go(func() error {
-			builder := newTrackUploadBuilder(&w.cache.UploadedCovers)
-			if i == len(batch)-1 { // last track in this batch
-				caption := append(caption, styling.Plain("\n"), html.String(nil, w.config.Signature))
+			builder := newTrackUploadBuilder(w.cache.UploadedCovers)
+			if caption := captionOf(i); nil != caption {
 				builder.WithCaption(caption)
 			}
 			document, err := builder.uploadTrack(wgCtx, w.logger, w.uploader, item)
@@ -219,13 +228,53 @@ func (w *Worker) uploadTracksBatch(ctx context.Context, reply *message.RequestBu
 		return must.BeFlaw(err).Append(flawP)
 	}
 
+	updates, err := w.sendAlbum(ctx, reply, album)
+	if nil != err && isFileReferenceError(err) {
+		w.logger.Info().Msg("Refreshing file references of already uploaded tracks")
+		if err := w.refreshDocuments(ctx, batch); nil != err {
+			var unavailableErr *UnavailableDocumentsError
+			switch {
+			case errutil.IsContext(ctx):
+				return ctx.Err()
+			case errors.As(err, &unavailableErr):
+				// None of the batch is posted, so that it is posted as a whole once
+				// the unavailable tracks are uploaded anew.
+				return unavailableErr
+			case errutil.IsFlaw(err):
+				return must.BeFlaw(err).Append(flawP)
+			default:
+				panic(errutil.UnknownError(err))
+			}
+		}
+		for i, item := range batch {
+			if nil != item.Document {
+				album[i] = message.Document(item.Document, captionOf(i)...)
+			}
+		}
+		updates, err = w.sendAlbum(ctx, reply, album)
+	}
+	if nil != err {
+		if errutil.IsContext(ctx) {
+			return ctx.Err()
+		}
+
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to send media album: %v", err)).Append(flawP)
+	}
+	w.registerDocuments(ctx, reply, batch, updates)
+	w.currentProgress().TracksUploaded(len(batch))
+	return nil
+}
+
+func (w *Worker) sendAlbum(ctx context.Context, reply *message.RequestBuilder, album []message.MultiMediaOption) (updates tg.UpdatesClass, err error) {
 	var rest []message.MultiMediaOption
 	if len(album) > 1 {
 		rest = album[1:]
 	}
 
 	err = backoff.Retry(func() error {
-		if _, err := reply.Clear().Album(ctx, album[0], rest...); nil != err {
+		res, err := reply.Clear().Album(ctx, album[0], rest...)
+		if nil != err {
 			if timeout, ok := telegram.AsFloodWait(err); ok {
 				w.logger.Error().Err(err).Dur("duration", timeout).Msg("Hit FLOOD_WAIT error")
 				select {
@@ -237,21 +286,14 @@ func (w *Worker) uploadTracksBatch(ctx context.Context, reply *message.RequestBu
 			}
 			return backoff.Permanent(err)
 		}
+		updates = res
 		return nil
 	}, backoff.WithContext(backoff.NewExponentialBackOff(), ctx))
-	if nil != err {
-		if errutil.IsContext(ctx) {
-			return ctx.Err()
-		}
-
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return flaw.From(fmt.Errorf("failed to send media album: %v", err)).Append(flawP)
-	}
-	return nil
+	return updates, err
 }
 
-func (w *Worker) uploadSingle(ctx context.Context, reply *message.RequestBuilder, dir tidalfs.DownloadDir) (err error) {
-	trackFs := dir.Single(w.currentJob.ID)
+func (w *Worker) uploadSingle(ctx context.Context, reply *message.RequestBuilder, dir tidalfs.JobDir, id string) (err error) {
+	trackFs := dir.Single(id)
 
 	info, err := trackFs.InfoFile.Read()
 	if nil != err {
@@ -260,22 +302,26 @@ func (w *Worker) uploadSingle(ctx context.Context, reply *message.RequestBuilder
 
 	flawP := flaw.P{}
 
+	uploadInfo := TrackUploadInfo{
+		FilePath:    trackFs.Path,
+		ArtistName:  tidal.JoinArtists(info.Artists),
+		Title:       info.Title,
+		Version:     info.Version,
+		Duration:    info.Duration,
+		Format:      info.Format,
+		CoverID:     info.CoverID,
+		CoverPath:   trackFs.Cover.Path,
+		RegistryKey: registry.Key(dir.Variant(), tidalfs.TrackKey(id, 0, 0)),
+		Document:    nil,
+	}
 	caption := []styling.StyledTextOption{
 		styling.Plain(info.Caption),
 		styling.Plain("\n"),
+		styling.Italic(qualityCaption([]TrackUploadInfo{uploadInfo})),
+		styling.Plain("\n"),
 		html.String(nil, w.config.Signature),
 	}
-	uploadInfo := TrackUploadInfo{
-		FilePath:   trackFs.Path,
-		ArtistName: tidal.JoinArtists(info.Artists),
-		Title:      info.Title,
-		Version:    info.Version,
-		Duration:   info.Duration,
-		Format:     info.Format,
-		CoverID:    info.CoverID,
-		CoverPath:  trackFs.Cover.Path,
-	}
-	document, err := newTrackUploadBuilder(&w.cache.UploadedCovers).WithCaption(caption).uploadTrack(ctx, w.logger, w.uploader, uploadInfo)
+	document, err := newTrackUploadBuilder(w.cache.UploadedCovers).WithCaption(caption).uploadTrack(ctx, w.logger, w.uploader, uploadInfo)
 	if nil != err {
 		if errutil.IsContext(ctx) {
 			return ctx.Err()
@@ -283,7 +329,8 @@ func (w *Worker) uploadSingle(ctx context.Context, reply *message.RequestBuilder
 		return must.BeFlaw(err).Append(flawP)
 	}
 
-	if _, err := reply.Media(ctx, document); nil != err {
+	updates, err := reply.Media(ctx, document)
+	if nil != err {
 		if errutil.IsContext(ctx) {
 			return ctx.Err()
 		}
@@ -291,6 +338,8 @@ func (w *Worker) uploadSingle(ctx context.Context, reply *message.RequestBuilder
 		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
 		return flaw.From(fmt.Errorf("failed to send media: %v", err)).Append(flawP)
 	}
+	w.registerDocuments(ctx, reply, []TrackUploadInfo{uploadInfo}, updates)
+	w.currentProgress().TracksUploaded(1)
 	return nil
 }
 
@@ -317,12 +366,18 @@ type TrackUploadInfo struct {
 	Format     tidal.TrackFormat
 	CoverID    string
 	CoverPath  string
+	// RegistryKey is the key the uploaded document of the track is registered
+	// with.
+	RegistryKey string
+	// Document is the registered document of the track if it is already uploaded,
+	// in which case it is sent instead of uploading the track file.
+	Document *registry.Document
 }
 
 func (u *TrackUploadBuilder) uploadTrack(ctx context.Context, logger zerolog.Logger, uploader *uploader.Uploader, info TrackUploadInfo) (*message.UploadedDocumentBuilder, error) {
 	flawP := flaw.P{}
 
-	cachedCover, err := u.cache.Fetch(info.CoverID, cache.DefaultUploadedCoverTTL, func() (tg.InputFileClass, error) {
+	cachedCover, err := u.cache.Fetch(info.CoverID, u.cache.TTL(), func() (tg.InputFileClass, error) {
 		uploadedCover, err := uploader.FromPath(ctx, info.CoverPath)
 		if nil != err {
 			if errutil.IsContext(ctx) {
@@ -395,10 +450,157 @@ func (u *TrackUploadBuilder) uploadTrack(ctx context.Context, logger zerolog.Log
 	return document, nil
 }
 
+// qualityCaption reports the distinct variants tracks were delivered in, as they
+// might differ from the requested one.
+func qualityCaption(items []TrackUploadInfo) string {
+	var variants []string
+	for _, item := range items {
+		v := string(item.Format.Quality)
+		if v == "" {
+			v = "UNKNOWN"
+		}
+		if item.Format.IsDolbyAtmos() {
+			v = "Dolby Atmos"
+		}
+		if !slices.Contains(variants, v) {
+			variants = append(variants, v)
+		}
+	}
+	return "Quality: " + strings.Join(variants, ", ")
+}
+
 func uploadTrackFileName(info TrackUploadInfo) string {
 	ext := info.Format.InferTrackExt()
+	title := info.Title
 	if nil != info.Version {
-		return fmt.Sprintf("%s - %s (%s).%s", info.ArtistName, info.Title, *info.Version, ext)
+		title += " (" + *info.Version + ")"
+	}
+	if info.Format.IsDolbyAtmos() {
+		title += " [Dolby Atmos]"
+	}
+	return fmt.Sprintf("%s - %s.%s", info.ArtistName, title, ext)
+}
+
+func (w *Worker) uploadVideo(ctx context.Context, reply *message.RequestBuilder, dir tidalfs.JobDir, id string) (err error) {
+	videoFs := dir.Video(id)
+
+	info, err := videoFs.InfoFile.Read()
+	if nil != err {
+		return err
+	}
+
+	flawP := flaw.P{}
+
+	thumbnail, err := w.uploader.FromPath(ctx, videoFs.Thumbnail.Path)
+	if nil != err {
+		if errutil.IsContext(ctx) {
+			return ctx.Err()
+		}
+
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to upload video thumbnail: %v", err)).Append(flawP)
 	}
-	return fmt.Sprintf("%s - %s.%s", info.ArtistName, info.Title, ext)
+
+	var videoFile tg.InputFileClass
+	err = backoff.Retry(func() error {
+		file, err := w.uploader.FromPath(ctx, videoFs.Path)
+		if nil != err {
+			if timeout, ok := telegram.AsFloodWait(err); ok {
+				w.logger.Error().Err(err).Dur("duration", timeout).Msg("Hit FLOOD_WAIT error")
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(timeout + 1*time.Second):
+					return err
+				}
+			}
+			return backoff.Permanent(err)
+		}
+		videoFile = file
+		return nil
+	}, backoff.WithContext(backoff.NewExponentialBackOff(), ctx))
+	if nil != err {
+		if errutil.IsContext(ctx) {
+			return ctx.Err()
+		}
+
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to upload video file: %v", err)).Append(flawP)
+	}
+
+	caption := []styling.StyledTextOption{
+		styling.Plain(info.Caption),
+		styling.Plain("\n"),
+		html.String(nil, w.config.Signature),
+	}
+	document := message.UploadedDocument(videoFile, caption...).
+		MIME("video/mp4").
+		Attributes(&tg.DocumentAttributeFilename{FileName: uploadVideoFileName(*info)}).
+		Thumb(thumbnail).
+		Video().
+		Duration(time.Duration(info.Duration)*time.Second).
+		Resolution(info.Width, info.Height).
+		SupportsStreaming()
+
+	if _, err := reply.Media(ctx, document); nil != err {
+		if errutil.IsContext(ctx) {
+			return ctx.Err()
+		}
+
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to send video: %v", err)).Append(flawP)
+	}
+	w.currentProgress().TracksUploaded(1)
+	return nil
+}
+
+func uploadVideoFileName(info tidalfs.StoredVideo) string {
+	artistName := tidal.JoinArtists(info.Artists)
+	if nil != info.Version {
+		return fmt.Sprintf("%s - %s (%s).mp4", artistName, info.Title, *info.Version)
+	}
+	return fmt.Sprintf("%s - %s.mp4", artistName, info.Title)
+}
+
+// maxListedSkippedTracks limits skipped tracks listed in the summary message so
+// that it does not exceed the message length limit.
+const maxListedSkippedTracks = 50
+
+func (w *Worker) logSkippedTracks(skipped []tidaldl.SkippedTrack) {
+	for _, t := range skipped {
+		if nil != t.Err {
+			w.logger.Error().Func(log.Flaw(t.Err)).Str("track_id", t.ID).Msg("Failed to download track")
+		}
+	}
+}
+
+func (w *Worker) sendSkippedTracks(ctx context.Context, reply *message.RequestBuilder, skipped []tidaldl.SkippedTrack) error {
+	if len(skipped) == 0 {
+		return nil
+	}
+
+	w.logSkippedTracks(skipped)
+
+	opts := []styling.StyledTextOption{
+		styling.Bold(fmt.Sprintf("%d track(s) skipped:", len(skipped))),
+	}
+	for i, t := range skipped[:min(len(skipped), maxListedSkippedTracks)] {
+		opts = append(
+			opts,
+			styling.Plain(fmt.Sprintf("\n%d. %s (%s): ", i+1, t.Title, t.ID)),
+			styling.Italic(t.Reason),
+		)
+	}
+	if n := len(skipped) - maxListedSkippedTracks; n > 0 {
+		opts = append(opts, styling.Plain(fmt.Sprintf("\n...and %d more.", n)))
+	}
+
+	if _, err := reply.StyledText(ctx, opts...); nil != err {
+		if errutil.IsContext(ctx) {
+			return ctx.Err()
+		}
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to send skipped tracks summary: %v", err)).Append(flawP)
+	}
+	return nil
 }
@@ -4,18 +4,79 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/rs/zerolog"
 	"gopkg.in/yaml.v3"
+
+	"github.com/xeptore/tgtd/cache"
+	"github.com/xeptore/tgtd/tidal"
 )
 
 type Config struct {
-	LogLevel        string  `yaml:"log_level"`
-	DownloadBaseDir string  `yaml:"download_base_dir"`
-	TargetPeerID    string  `yaml:"target_peer_id"`
-	CredsDir        string  `yaml:"credentials_dir"`
-	FromIDs         []int64 `yaml:"from_ids"`
-	Signature       string  `yaml:"signature"`
+	LogLevel                  string        `yaml:"log_level"`
+	DownloadBaseDir           string        `yaml:"download_base_dir"`
+	TargetPeerID              string        `yaml:"target_peer_id"`
+	CredsDir                  string        `yaml:"credentials_dir"`
+	FromIDs                   []int64       `yaml:"from_ids"`
+	Signature                 string        `yaml:"signature"`
+	ArtistIncludeCompilations bool          `yaml:"artist_include_compilations"`
+	VideoMaxResolution        int           `yaml:"video_max_resolution"`
+	AudioQuality              tidal.Quality `yaml:"audio_quality"`
+	CountryCode               string        `yaml:"country_code"`
+	Locale                    string        `yaml:"locale"`
+	DownloadMaxSizeMB         int64         `yaml:"download_max_size_mb"`
+	DownloadMaxAge            time.Duration `yaml:"download_max_age"`
+	DeleteAfterUpload         bool          `yaml:"delete_after_upload"`
+	FollowInterval            time.Duration `yaml:"follow_interval"`
+	Cache                     CacheConfig   `yaml:"cache"`
+}
+
+// MinFollowInterval is the minimum duration between polls of followed playlists.
+const MinFollowInterval = 5 * time.Minute
+
+type CacheConfig struct {
+	AlbumsMeta       CacheOptions `yaml:"albums_meta"`
+	DownloadedCovers CacheOptions `yaml:"downloaded_covers"`
+	UploadedCovers   CacheOptions `yaml:"uploaded_covers"`
+	TrackCredits     CacheOptions `yaml:"track_credits"`
+}
+
+type CacheOptions struct {
+	TTL     time.Duration `yaml:"ttl"`
+	MaxSize int           `yaml:"max_size"`
+}
+
+func (opts *CacheOptions) setDefaults(defaults cache.Options) {
+	if opts.TTL == 0 {
+		opts.TTL = defaults.TTL
+	}
+
+	if opts.MaxSize == 0 {
+		opts.MaxSize = defaults.MaxSize
+	}
+}
+
+func (opts CacheOptions) validate() error {
+	if opts.TTL < 0 {
+		return errors.New("ttl is negative")
+	}
+
+	if opts.MaxSize < 0 {
+		return errors.New("max size is negative")
+	}
+
+	return nil
+}
+
+// Options returns the cache package configuration.
+func (cfg CacheConfig) Options() cache.Config {
+	return cache.Config{
+		AlbumsMeta:       cache.Options(cfg.AlbumsMeta),
+		DownloadedCovers: cache.Options(cfg.DownloadedCovers),
+		UploadedCovers:   cache.Options(cfg.UploadedCovers),
+		TrackCredits:     cache.Options(cfg.TrackCredits),
+	}
 }
 
 func (cfg *Config) setDefaults() {
@@ -26,6 +87,28 @@ func (cfg *Config) setDefaults() {
 	if cfg.CredsDir == "" {
 		cfg.CredsDir = ".creds"
 	}
+
+	if cfg.VideoMaxResolution == 0 {
+		cfg.VideoMaxResolution = 1080
+	}
+
+	if cfg.AudioQuality == "" {
+		cfg.AudioQuality = tidal.QualityHiResLossless
+	}
+
+	if cfg.Locale == "" {
+		cfg.Locale = tidal.DefaultLocale
+	}
+
+	if cfg.FollowInterval == 0 {
+		cfg.FollowInterval = time.Hour
+	}
+
+	defaults := cache.DefaultConfig()
+	cfg.Cache.AlbumsMeta.setDefaults(defaults.AlbumsMeta)
+	cfg.Cache.DownloadedCovers.setDefaults(defaults.DownloadedCovers)
+	cfg.Cache.UploadedCovers.setDefaults(defaults.UploadedCovers)
+	cfg.Cache.TrackCredits.setDefaults(defaults.TrackCredits)
 }
 
 func (cfg *Config) validate() error {
@@ -41,9 +124,59 @@ func (cfg *Config) validate() error {
 		return errors.New("target peer ID is empty")
 	}
 
+	if cfg.VideoMaxResolution < 0 {
+		return errors.New("video max resolution is negative")
+	}
+
+	quality, err := tidal.ParseQuality(string(cfg.AudioQuality))
+	if nil != err {
+		return fmt.Errorf("invalid audio quality: %v", err)
+	}
+	cfg.AudioQuality = quality
+
+	if cfg.DownloadMaxSizeMB < 0 {
+		return errors.New("download max size is negative")
+	}
+
+	if cfg.DownloadMaxAge < 0 {
+		return errors.New("download max age is negative")
+	}
+
+	if cfg.FollowInterval < MinFollowInterval {
+		return fmt.Errorf("follow interval is less than %s", MinFollowInterval)
+	}
+
+	caches := map[string]CacheOptions{
+		"albums meta":       cfg.Cache.AlbumsMeta,
+		"downloaded covers": cfg.Cache.DownloadedCovers,
+		"uploaded covers":   cfg.Cache.UploadedCovers,
+		"track credits":     cfg.Cache.TrackCredits,
+	}
+	for name, opts := range caches {
+		if err := opts.validate(); nil != err {
+			return fmt.Errorf("invalid %s cache options: %v", name, err)
+		}
+	}
+
+	if cfg.CountryCode != "" && !isCountryCode(cfg.CountryCode) {
+		return fmt.Errorf("invalid country code %q: must be an ISO 3166-1 alpha-2 code", cfg.CountryCode)
+	}
+
 	return nil
 }
 
+func isCountryCode(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, c := range s {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
 func FromFile(filePath string) (*Config, error) {
 	data, err := os.ReadFile(filePath)
 	if nil != err {
@@ -4,6 +4,7 @@ import "time"
 
 var (
 	AlbumMetaRequestTimeout        = 5 * time.Second
+	TrackMetaRequestTimeout        = 5 * time.Second
 	MixMetaRequestTimeout          = 5 * time.Second
 	PlaylistMetaRequestTimeout     = 5 * time.Second
 	DashSegmentDownloadTimeout     = 10 * time.Second
@@ -14,4 +15,8 @@ var (
 	GetTrackFileSizeRequestTimeout = 5 * time.Second
 	GetTrackCreditsRequestTimeout  = 2 * time.Second
 	GetTrackLyricsRequestTimeout   = 2 * time.Second
+	VideoMetaRequestTimeout        = 5 * time.Second
+	SearchRequestTimeout           = 5 * time.Second
+	StatusMessageUpdateInterval    = 3 * time.Second
+	StatusMessageEditTimeout       = 5 * time.Second
 )
@@ -16,6 +16,7 @@ require (
 	github.com/tidwall/pretty v1.2.1
 	github.com/urfave/cli/v2 v2.27.7
 	github.com/xeptore/flaw/v8 v8.3.0
+	go.etcd.io/bbolt v1.4.3
 	golang.org/x/exp v0.0.0-20250620022241-b7579e27df2b
 	golang.org/x/sync v0.16.0
 	gopkg.in/matryer/try.v1 v1.0.0-20150601225556-312d2599e12e
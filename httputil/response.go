@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/xeptore/flaw/v8"
@@ -74,3 +76,20 @@ func IsTokenInvalidUnauthorizedResponse(b []byte) (bool, error) {
 	}
 	return body.Status == 401 && body.SubStatus == 11002 && body.UserMessage == "Token could not be verified", nil
 }
+
+// RetryAfter returns the wait duration specified by the Retry-After header of
+// resp, either as delay seconds, or an HTTP date. It returns 0 if the header is
+// missing, or invalid.
+func RetryAfter(resp *http.Response) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); nil == err {
+		return time.Duration(max(secs, 0)) * time.Second
+	}
+	if t, err := http.ParseTime(v); nil == err {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
@@ -0,0 +1,50 @@
+package httputil_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xeptore/tgtd/httputil"
+)
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	respWith := func(v string) *http.Response {
+		h := make(http.Header)
+		if v != "" {
+			h.Set("Retry-After", v)
+		}
+		return &http.Response{Header: h} //nolint:exhaustruct
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		t.Parallel()
+		assert.Zero(t, httputil.RetryAfter(respWith("")))
+	})
+
+	t.Run("seconds", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, 7*time.Second, httputil.RetryAfter(respWith("7")))
+	})
+
+	t.Run("http_date", func(t *testing.T) {
+		t.Parallel()
+		d := httputil.RetryAfter(respWith(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)))
+		assert.Greater(t, d, 58*time.Second)
+		assert.LessOrEqual(t, d, time.Minute)
+	})
+
+	t.Run("past_http_date", func(t *testing.T) {
+		t.Parallel()
+		assert.Zero(t, httputil.RetryAfter(respWith(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		assert.Zero(t, httputil.RetryAfter(respWith("soon")))
+	})
+}
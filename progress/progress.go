@@ -0,0 +1,172 @@
+package progress
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/gotd/td/telegram/uploader"
+)
+
+// Tracker accumulates progress of a single job reported concurrently by download
+// and upload workers. All methods are safe to call on a nil Tracker, in which
+// case they do nothing.
+type Tracker struct {
+	mux              sync.Mutex
+	version          uint64
+	phase            string
+	totalTracks      int
+	downloadedTracks int
+	uploadedTracks   int
+	downloadedBytes  int64
+	uploads          map[int64]uploadState
+}
+
+type uploadState struct {
+	uploaded int64
+	total    int64
+}
+
+type Snapshot struct {
+	Version          uint64
+	Phase            string
+	TotalTracks      int
+	DownloadedTracks int
+	UploadedTracks   int
+	DownloadedBytes  int64
+	UploadedBytes    int64
+	UploadTotalBytes int64
+}
+
+func New() *Tracker {
+	return &Tracker{
+		mux:              sync.Mutex{},
+		version:          0,
+		phase:            "",
+		totalTracks:      0,
+		downloadedTracks: 0,
+		uploadedTracks:   0,
+		downloadedBytes:  0,
+		uploads:          make(map[int64]uploadState),
+	}
+}
+
+func (t *Tracker) SetPhase(phase string) {
+	if nil == t {
+		return
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.phase = phase
+	t.version++
+}
+
+func (t *Tracker) AddTracks(n int) {
+	if nil == t {
+		return
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.totalTracks += n
+	t.version++
+}
+
+func (t *Tracker) TracksDownloaded(n int) {
+	if nil == t {
+		return
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.downloadedTracks += n
+	t.version++
+}
+
+func (t *Tracker) TracksUploaded(n int) {
+	if nil == t {
+		return
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.uploadedTracks += n
+	t.version++
+}
+
+func (t *Tracker) AddDownloadedBytes(n int64) {
+	if nil == t {
+		return
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.downloadedBytes += n
+	t.version++
+}
+
+// Chunk implements uploader.Progress.
+func (t *Tracker) Chunk(_ context.Context, state uploader.ProgressState) error {
+	if nil == t {
+		return nil
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.uploads[state.ID] = uploadState{uploaded: state.Uploaded, total: state.Total}
+	t.version++
+	return nil
+}
+
+func (t *Tracker) Snapshot() Snapshot {
+	if nil == t {
+		return Snapshot{} //nolint:exhaustruct
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	s := Snapshot{
+		Version:          t.version,
+		Phase:            t.phase,
+		TotalTracks:      t.totalTracks,
+		DownloadedTracks: t.downloadedTracks,
+		UploadedTracks:   t.uploadedTracks,
+		DownloadedBytes:  t.downloadedBytes,
+		UploadedBytes:    0,
+		UploadTotalBytes: 0,
+	}
+	for _, u := range t.uploads {
+		s.UploadedBytes += u.uploaded
+		if u.total > 0 {
+			s.UploadTotalBytes += u.total
+		}
+	}
+	return s
+}
+
+// Writer returns a writer that reports bytes written to w as downloaded bytes.
+func (t *Tracker) Writer(w io.Writer) io.Writer {
+	if nil == t {
+		return w
+	}
+	return &countingWriter{w: w, t: t}
+}
+
+type countingWriter struct {
+	w io.Writer
+	t *Tracker
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.t.AddDownloadedBytes(int64(n))
+	return n, err
+}
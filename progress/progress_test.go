@@ -0,0 +1,68 @@
+package progress_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gotd/td/telegram/uploader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/progress"
+)
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("counts", func(t *testing.T) {
+		t.Parallel()
+
+		tracker := progress.New()
+		tracker.SetPhase("Downloading")
+		tracker.AddTracks(3)
+		tracker.TracksDownloaded(2)
+
+		var buf bytes.Buffer
+		_, err := tracker.Writer(&buf).Write([]byte("hello"))
+		require.NoError(t, err)
+		assert.Exactly(t, "hello", buf.String())
+
+		s := tracker.Snapshot()
+		assert.Exactly(t, "Downloading", s.Phase)
+		assert.Exactly(t, 3, s.TotalTracks)
+		assert.Exactly(t, 2, s.DownloadedTracks)
+		assert.Exactly(t, 0, s.UploadedTracks)
+		assert.Exactly(t, int64(5), s.DownloadedBytes)
+
+		tracker.SetPhase("Uploading")
+		tracker.TracksUploaded(1)
+		require.NoError(t, tracker.Chunk(t.Context(), uploader.ProgressState{ID: 1, Uploaded: 10, Total: 20})) //nolint:exhaustruct
+		require.NoError(t, tracker.Chunk(t.Context(), uploader.ProgressState{ID: 1, Uploaded: 20, Total: 20})) //nolint:exhaustruct
+		require.NoError(t, tracker.Chunk(t.Context(), uploader.ProgressState{ID: 2, Uploaded: 5, Total: 30}))  //nolint:exhaustruct
+
+		next := tracker.Snapshot()
+		assert.Greater(t, next.Version, s.Version)
+		assert.Exactly(t, "Uploading", next.Phase)
+		assert.Exactly(t, 3, next.TotalTracks)
+		assert.Exactly(t, 2, next.DownloadedTracks)
+		assert.Exactly(t, 1, next.UploadedTracks)
+		assert.Exactly(t, int64(5), next.DownloadedBytes)
+		assert.Exactly(t, int64(25), next.UploadedBytes)
+		assert.Exactly(t, int64(50), next.UploadTotalBytes)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		t.Parallel()
+
+		var tracker *progress.Tracker
+		tracker.SetPhase("Downloading")
+		tracker.AddTracks(1)
+		tracker.TracksDownloaded(1)
+		tracker.TracksUploaded(1)
+		require.NoError(t, tracker.Chunk(t.Context(), uploader.ProgressState{})) //nolint:exhaustruct
+
+		var buf bytes.Buffer
+		assert.Same(t, &buf, tracker.Writer(&buf))
+		assert.Zero(t, tracker.Snapshot())
+	})
+}
@@ -0,0 +1,260 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/must"
+)
+
+var ErrNotFound = errors.New("queue item not found")
+
+type Item[T any] struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Payload   T         `json:"payload"`
+}
+
+type state[T any] struct {
+	LastID int       `json:"last_id"`
+	Items  []Item[T] `json:"items"`
+}
+
+// Queue is a FIFO queue persisted to a JSON file on every mutation. It expects
+// a single consumer that takes items via Next and acknowledges them via Done.
+type Queue[T any] struct {
+	mux     sync.Mutex
+	path    string
+	state   state[T]
+	running string
+	signal  chan struct{}
+}
+
+func FileFrom(dir string) string {
+	return filepath.Join(dir, "queue.json")
+}
+
+func Load[T any](path string) (*Queue[T], error) {
+	q := &Queue[T]{
+		mux:     sync.Mutex{},
+		path:    path,
+		state:   state[T]{LastID: 0, Items: nil},
+		running: "",
+		signal:  make(chan struct{}, 1),
+	}
+
+	s, err := readStateFile[T](path)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			return q, nil
+		}
+		return nil, err
+	}
+	q.state = *s
+	if len(q.state.Items) > 0 {
+		q.notify()
+	}
+
+	return q, nil
+}
+
+func (q *Queue[T]) notify() {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Push appends payload to the end of the queue and returns the created item
+// along with its 1-based position in the queue, including the running item.
+func (q *Queue[T]) Push(payload T) (*Item[T], int, error) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	next := state[T]{
+		LastID: q.state.LastID + 1,
+		Items:  slices.Clone(q.state.Items),
+	}
+	item := Item[T]{
+		ID:        strconv.Itoa(next.LastID),
+		CreatedAt: time.Now(),
+		Payload:   payload,
+	}
+	next.Items = append(next.Items, item)
+	if err := writeStateFile(q.path, next); nil != err {
+		return nil, 0, err
+	}
+	q.state = next
+	q.notify()
+
+	return &item, len(next.Items), nil
+}
+
+// Next blocks until an item is available, marks it as running, and returns it.
+// The item stays persisted until Done is called with its ID.
+func (q *Queue[T]) Next(ctx context.Context) (*Item[T], error) {
+	for {
+		q.mux.Lock()
+		if len(q.state.Items) > 0 {
+			item := q.state.Items[0]
+			q.running = item.ID
+			q.mux.Unlock()
+			return &item, nil
+		}
+		q.mux.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-q.signal:
+		}
+	}
+}
+
+// Done removes the item with the given ID from the queue.
+func (q *Queue[T]) Done(id string) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	if q.running == id {
+		q.running = ""
+	}
+	return q.remove(id)
+}
+
+// Remove removes a pending item from the queue. It reports true without removing
+// the item if the item is currently running, as it is up to the consumer to stop
+// processing it and acknowledge it via Done.
+func (q *Queue[T]) Remove(id string) (running bool, err error) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	if q.running == id {
+		return true, nil
+	}
+	return false, q.remove(id)
+}
+
+func (q *Queue[T]) remove(id string) error {
+	idx := slices.IndexFunc(q.state.Items, func(item Item[T]) bool { return item.ID == id })
+	if idx == -1 {
+		return ErrNotFound
+	}
+
+	next := state[T]{
+		LastID: q.state.LastID,
+		Items:  slices.Delete(slices.Clone(q.state.Items), idx, idx+1),
+	}
+	if err := writeStateFile(q.path, next); nil != err {
+		return err
+	}
+	q.state = next
+
+	return nil
+}
+
+// Items returns a snapshot of the queue items in order. The first item is the
+// running one, if there is any.
+func (q *Queue[T]) Items() []Item[T] {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return slices.Clone(q.state.Items)
+}
+
+// Running returns ID of the item currently being processed, or an empty string
+// if there is none.
+func (q *Queue[T]) Running() string {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return q.running
+}
+
+func readStateFile[T any](path string) (s *state[T], err error) {
+	flawP := flaw.P{"file_path": path}
+
+	f, err := os.OpenFile(path, os.O_RDONLY, 0o0600)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, os.ErrNotExist
+		}
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to open queue file for read: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if closeErr := f.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close queue file: %v", closeErr)).Append(flawP)
+			if nil != err {
+				err = must.BeFlaw(err).Join(closeErr)
+			} else {
+				err = closeErr
+			}
+		}
+	}()
+
+	var out state[T]
+	if err := json.NewDecoder(f).Decode(&out); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to decode queue file contents: %v", err)).Append(flawP)
+	}
+
+	return &out, nil
+}
+
+func writeStateFile[T any](path string, s state[T]) (err error) {
+	tmpPath := path + ".tmp"
+	flawP := flaw.P{"file_path": path, "tmp_file_path": tmpPath}
+
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o0600)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to open queue temp file for write: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if closeErr := f.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close queue temp file: %v", closeErr)).Append(flawP)
+			if nil != err {
+				err = must.BeFlaw(err).Join(closeErr)
+			} else {
+				err = closeErr
+			}
+		}
+
+		if nil != err {
+			if removeErr := os.Remove(tmpPath); nil != removeErr && !errors.Is(removeErr, os.ErrNotExist) {
+				flawP["err_debug_tree"] = errutil.Tree(removeErr).FlawP()
+				err = flaw.From(fmt.Errorf("failed to remove queue temp file: %v", removeErr)).Join(err).Append(flawP)
+			}
+			return
+		}
+
+		if renameErr := os.Rename(tmpPath, path); nil != renameErr {
+			flawP["err_debug_tree"] = errutil.Tree(renameErr).FlawP()
+			err = flaw.From(fmt.Errorf("failed to replace queue file: %v", renameErr)).Append(flawP)
+		}
+	}()
+
+	if err := json.NewEncoder(f).Encode(s); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to write queue content: %v", err)).Append(flawP)
+	}
+
+	if err := f.Sync(); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to sync queue temp file: %v", err)).Append(flawP)
+	}
+
+	return nil
+}
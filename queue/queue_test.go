@@ -0,0 +1,134 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/queue"
+)
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fifo_order_and_positions", func(t *testing.T) {
+		t.Parallel()
+
+		q, err := queue.Load[string](queue.FileFrom(t.TempDir()))
+		require.NoError(t, err)
+
+		first, pos, err := q.Push("a")
+		require.NoError(t, err)
+		assert.Exactly(t, 1, pos)
+
+		second, pos, err := q.Push("b")
+		require.NoError(t, err)
+		assert.Exactly(t, 2, pos)
+		assert.NotEqual(t, first.ID, second.ID)
+
+		item, err := q.Next(t.Context())
+		require.NoError(t, err)
+		assert.Exactly(t, "a", item.Payload)
+		assert.Exactly(t, first.ID, q.Running())
+
+		require.NoError(t, q.Done(item.ID))
+		assert.Empty(t, q.Running())
+
+		item, err = q.Next(t.Context())
+		require.NoError(t, err)
+		assert.Exactly(t, "b", item.Payload)
+	})
+
+	t.Run("survives_reload", func(t *testing.T) {
+		t.Parallel()
+
+		path := queue.FileFrom(t.TempDir())
+		q, err := queue.Load[string](path)
+		require.NoError(t, err)
+
+		_, _, err = q.Push("a")
+		require.NoError(t, err)
+		_, _, err = q.Push("b")
+		require.NoError(t, err)
+
+		item, err := q.Next(t.Context())
+		require.NoError(t, err)
+		assert.Exactly(t, "a", item.Payload)
+
+		reloaded, err := queue.Load[string](path)
+		require.NoError(t, err)
+
+		items := reloaded.Items()
+		require.Len(t, items, 2)
+		assert.Exactly(t, "a", items[0].Payload)
+		assert.Exactly(t, "b", items[1].Payload)
+
+		third, pos, err := reloaded.Push("c")
+		require.NoError(t, err)
+		assert.Exactly(t, 3, pos)
+		assert.Exactly(t, "3", third.ID)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		t.Parallel()
+
+		q, err := queue.Load[string](queue.FileFrom(t.TempDir()))
+		require.NoError(t, err)
+
+		first, _, err := q.Push("a")
+		require.NoError(t, err)
+		second, _, err := q.Push("b")
+		require.NoError(t, err)
+
+		_, err = q.Next(t.Context())
+		require.NoError(t, err)
+
+		running, err := q.Remove(first.ID)
+		require.NoError(t, err)
+		assert.True(t, running)
+		assert.Len(t, q.Items(), 2)
+
+		running, err = q.Remove(second.ID)
+		require.NoError(t, err)
+		assert.False(t, running)
+		assert.Len(t, q.Items(), 1)
+
+		_, err = q.Remove("unknown")
+		require.ErrorIs(t, err, queue.ErrNotFound)
+	})
+
+	t.Run("next_waits_for_push", func(t *testing.T) {
+		t.Parallel()
+
+		q, err := queue.Load[string](queue.FileFrom(t.TempDir()))
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_, _, _ = q.Push("a")
+		}()
+
+		ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+		defer cancel()
+
+		item, err := q.Next(ctx)
+		require.NoError(t, err)
+		assert.Exactly(t, "a", item.Payload)
+	})
+
+	t.Run("next_respects_context", func(t *testing.T) {
+		t.Parallel()
+
+		q, err := queue.Load[string](queue.FileFrom(t.TempDir()))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err = q.Next(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -1,7 +1,8 @@
 package ratelimit
 
 import (
-	"math/rand/v2"
+	"context"
+	"sync"
 	"time"
 )
 
@@ -13,11 +14,135 @@ const (
 	BatchUploadConcurrency            = 8
 )
 
-func TrackDownloadSleepMS() time.Duration {
-	const (
-		from = 2
-		to   = 6
-	)
-	millis := (rand.IntN(to-from)+from)*1000 + rand.N(1000) //nolint:gosec
-	return time.Duration(millis) * time.Millisecond
+const (
+	tidalInitialRate = 20
+	tidalMinRate     = 1
+	tidalMaxRate     = 50
+	tidalBurst       = 20
+	// DefaultRetryAfter is the pause applied on a throttling response that does
+	// not specify how long to wait.
+	DefaultRetryAfter = 3 * time.Second
+	// decreaseInterval is the minimum time between two rate decreases, so that a
+	// cluster of throttling responses of concurrent in-flight requests halves the
+	// rate only once.
+	decreaseInterval = time.Second
+	// increaseStep is the rate, in requests per second, added on every
+	// successful response.
+	increaseStep = 0.1
+)
+
+// TIDAL limits all requests sent to TIDAL by this process.
+var TIDAL = NewLimiter(tidalInitialRate, tidalMinRate, tidalMaxRate, tidalBurst, time.Now)
+
+// Limiter is a token bucket limiter whose rate decreases multiplicatively on
+// throttling responses, and increases additively on successful ones.
+type Limiter struct {
+	mux     sync.Mutex
+	now     func() time.Time
+	rate    float64
+	minRate float64
+	maxRate float64
+	burst   float64
+	tokens  float64
+	// last is the time tokens was last refilled at. It is in the future while
+	// requests are paused due to a throttling response.
+	last         time.Time
+	lastDecrease time.Time
+}
+
+// NewLimiter creates a limiter allowing rate requests per second, with bursts
+// of up to burst requests. The rate is kept within minRate, and maxRate.
+func NewLimiter(rate, minRate, maxRate float64, burst int, now func() time.Time) *Limiter {
+	return &Limiter{
+		mux:          sync.Mutex{},
+		now:          now,
+		rate:         rate,
+		minRate:      minRate,
+		maxRate:      maxRate,
+		burst:        float64(burst),
+		tokens:       float64(burst),
+		last:         now(),
+		lastDecrease: time.Time{},
+	}
+}
+
+// Rate returns the current rate in requests per second.
+func (l *Limiter) Rate() float64 {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.rate
+}
+
+// Reserve takes a token, and returns how long the caller must wait before
+// sending its request.
+func (l *Limiter) Reserve() time.Duration {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := l.now()
+	if now.After(l.last) {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+	}
+	l.tokens--
+
+	delay := l.last.Sub(now)
+	if l.tokens < 0 {
+		delay += time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	return delay
+}
+
+func (l *Limiter) cancel() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.tokens = min(l.burst, l.tokens+1)
+}
+
+// Wait blocks until a request is allowed to be sent, or ctx is done, in which
+// case it returns ctx.Err().
+func (l *Limiter) Wait(ctx context.Context) error {
+	delay := l.Reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Throttled pauses all requests for retryAfter, or DefaultRetryAfter if it is
+// not positive, and halves the rate.
+func (l *Limiter) Throttled(retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = DefaultRetryAfter
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := l.now()
+	if resume := now.Add(retryAfter); resume.After(l.last) {
+		l.last = resume
+	}
+	l.tokens = min(l.tokens, 0)
+
+	if now.Sub(l.lastDecrease) >= decreaseInterval {
+		l.rate = max(l.minRate, l.rate/2)
+		l.lastDecrease = now
+	}
+}
+
+// Succeeded speeds the rate back up after a successful response.
+func (l *Limiter) Succeeded() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.rate = min(l.maxRate, l.rate+increaseStep)
 }
@@ -1,17 +1,120 @@
 package ratelimit_test
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/xeptore/tgtd/ratelimit"
 )
 
-func TestTrackDownloadSleepMS(t *testing.T) {
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newClock() *clock {
+	return &clock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestLimiter(t *testing.T) {
 	t.Parallel()
-	for range 100 {
-		ms := ratelimit.TrackDownloadSleepMS().Milliseconds()
-		if ms < 2000 || ms > 6000 {
-			t.Errorf("expected 2000 <= ms <= 6000, got %d", ms)
+
+	t.Run("burst_then_rate", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClock()
+		l := ratelimit.NewLimiter(10, 1, 20, 3, c.Now)
+		for range 3 {
+			assert.LessOrEqual(t, l.Reserve(), time.Duration(0))
 		}
-	}
+		assert.Equal(t, 100*time.Millisecond, l.Reserve())
+		assert.Equal(t, 200*time.Millisecond, l.Reserve())
+
+		c.Advance(time.Second)
+		assert.LessOrEqual(t, l.Reserve(), time.Duration(0))
+	})
+
+	t.Run("throttled_pauses_and_halves_rate", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClock()
+		l := ratelimit.NewLimiter(10, 1, 20, 3, c.Now)
+		l.Throttled(2 * time.Second)
+		assert.InDelta(t, 5, l.Rate(), 0.001)
+		assert.Equal(t, 2*time.Second+200*time.Millisecond, l.Reserve())
+
+		c.Advance(3 * time.Second)
+		assert.LessOrEqual(t, l.Reserve(), time.Duration(0))
+	})
+
+	t.Run("default_retry_after", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClock()
+		l := ratelimit.NewLimiter(10, 1, 20, 1, c.Now)
+		l.Throttled(0)
+		assert.Equal(t, ratelimit.DefaultRetryAfter+200*time.Millisecond, l.Reserve())
+	})
+
+	t.Run("clustered_throttles_decrease_once", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClock()
+		l := ratelimit.NewLimiter(16, 1, 20, 1, c.Now)
+		l.Throttled(time.Second)
+		l.Throttled(time.Second)
+		l.Throttled(time.Second)
+		assert.InDelta(t, 8, l.Rate(), 0.001)
+
+		c.Advance(time.Second)
+		l.Throttled(time.Second)
+		assert.InDelta(t, 4, l.Rate(), 0.001)
+
+		for range 10 {
+			c.Advance(time.Second)
+			l.Throttled(time.Second)
+		}
+		assert.InDelta(t, 1, l.Rate(), 0.001)
+	})
+
+	t.Run("succeeded_speeds_up_to_max", func(t *testing.T) {
+		t.Parallel()
+
+		c := newClock()
+		l := ratelimit.NewLimiter(2, 1, 3, 1, c.Now)
+		l.Throttled(time.Second)
+		assert.InDelta(t, 1, l.Rate(), 0.001)
+
+		for range 5 {
+			l.Succeeded()
+		}
+		assert.InDelta(t, 1.5, l.Rate(), 0.001)
+
+		for range 100 {
+			l.Succeeded()
+		}
+		assert.InDelta(t, 3, l.Rate(), 0.001)
+	})
+
+	t.Run("wait_returns_context_error", func(t *testing.T) {
+		t.Parallel()
+
+		l := ratelimit.NewLimiter(10, 1, 20, 1, time.Now)
+		l.Throttled(time.Minute)
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, l.Wait(ctx), context.DeadlineExceeded)
+	})
 }
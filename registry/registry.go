@@ -0,0 +1,207 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/tidal"
+)
+
+// Document is a track document uploaded to Telegram, which can be sent again
+// without downloading, and uploading the track.
+type Document struct {
+	ID            int64  `json:"id"`
+	AccessHash    int64  `json:"access_hash"`
+	FileReference []byte `json:"file_reference"`
+	// Format is the format the track was uploaded in.
+	Format tidal.TrackFormat `json:"format"`
+	// Origin is the message the document was sent in, from which an expired file
+	// reference is refreshed.
+	Origin Origin `json:"origin"`
+}
+
+func (d Document) GetID() int64 {
+	return d.ID
+}
+
+func (d Document) GetAccessHash() int64 {
+	return d.AccessHash
+}
+
+func (d Document) GetFileReference() []byte {
+	return d.FileReference
+}
+
+type Origin struct {
+	// PeerKind is either user, chat, or channel.
+	PeerKind       string `json:"peer_kind"`
+	PeerID         int64  `json:"peer_id"`
+	PeerAccessHash int64  `json:"peer_access_hash"`
+	MessageID      int    `json:"message_id"`
+}
+
+// Key returns the key of the document of a track, which is stored with key in
+// the track store of variant.
+func Key(variant, trackKey string) string {
+	return variant + "/" + trackKey
+}
+
+// Registry holds uploaded track documents persisted to a JSON file on every
+// mutation.
+type Registry struct {
+	mux       sync.Mutex
+	path      string
+	documents map[string]Document
+}
+
+func FileFrom(dir string) string {
+	return filepath.Join(dir, "documents.json")
+}
+
+func Load(path string) (*Registry, error) {
+	r := &Registry{
+		mux:       sync.Mutex{},
+		path:      path,
+		documents: make(map[string]Document),
+	}
+
+	documents, err := readFile(path)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			return r, nil
+		}
+		return nil, err
+	}
+	r.documents = documents
+
+	return r, nil
+}
+
+// Document returns the document registered with key.
+func (r *Registry) Document(key string) (Document, bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	d, ok := r.documents[key]
+	return d, ok
+}
+
+// Put registers documents by their keys, replacing existing ones.
+func (r *Registry) Put(documents map[string]Document) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	next := maps.Clone(r.documents)
+	maps.Copy(next, documents)
+	if err := writeFile(r.path, next); nil != err {
+		return err
+	}
+	r.documents = next
+
+	return nil
+}
+
+// Remove unregisters documents with the given keys, e.g., as they are no longer
+// available.
+func (r *Registry) Remove(keys ...string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	next := maps.Clone(r.documents)
+	for _, k := range keys {
+		delete(next, k)
+	}
+	if err := writeFile(r.path, next); nil != err {
+		return err
+	}
+	r.documents = next
+
+	return nil
+}
+
+func readFile(path string) (m map[string]Document, err error) {
+	flawP := flaw.P{"file_path": path}
+
+	f, err := os.OpenFile(path, os.O_RDONLY, 0o0600)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, os.ErrNotExist
+		}
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to open registry file for read: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if closeErr := f.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close registry file: %v", closeErr)).Append(flawP)
+			if nil != err {
+				err = must.BeFlaw(err).Join(closeErr)
+			} else {
+				err = closeErr
+			}
+		}
+	}()
+
+	out := make(map[string]Document)
+	if err := json.NewDecoder(f).Decode(&out); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to decode registry file contents: %v", err)).Append(flawP)
+	}
+
+	return out, nil
+}
+
+func writeFile(path string, m map[string]Document) (err error) {
+	tmpPath := path + ".tmp"
+	flawP := flaw.P{"file_path": path, "tmp_file_path": tmpPath}
+
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o0600)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to open registry temp file for write: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if closeErr := f.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close registry temp file: %v", closeErr)).Append(flawP)
+			if nil != err {
+				err = must.BeFlaw(err).Join(closeErr)
+			} else {
+				err = closeErr
+			}
+		}
+
+		if nil != err {
+			if removeErr := os.Remove(tmpPath); nil != removeErr && !errors.Is(removeErr, os.ErrNotExist) {
+				flawP["err_debug_tree"] = errutil.Tree(removeErr).FlawP()
+				err = flaw.From(fmt.Errorf("failed to remove registry temp file: %v", removeErr)).Join(err).Append(flawP)
+			}
+			return
+		}
+
+		if renameErr := os.Rename(tmpPath, path); nil != renameErr {
+			flawP["err_debug_tree"] = errutil.Tree(renameErr).FlawP()
+			err = flaw.From(fmt.Errorf("failed to replace registry file: %v", renameErr)).Append(flawP)
+		}
+	}()
+
+	if err := json.NewEncoder(f).Encode(m); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to write registry content: %v", err)).Append(flawP)
+	}
+
+	if err := f.Sync(); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to sync registry temp file: %v", err)).Append(flawP)
+	}
+
+	return nil
+}
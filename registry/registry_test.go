@@ -0,0 +1,67 @@
+package registry_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/registry"
+	"github.com/xeptore/tgtd/tidal"
+)
+
+func document(id int64) registry.Document {
+	return registry.Document{
+		ID:            id,
+		AccessHash:    id * 10,
+		FileReference: []byte{byte(id)},
+		Format:        tidal.TrackFormat{MimeType: "audio/flac", Codec: "flac", Quality: tidal.QualityLossless, AudioMode: ""},
+		Origin:        registry.Origin{PeerKind: "channel", PeerID: 1, PeerAccessHash: 2, MessageID: int(id)},
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("survives_reload", func(t *testing.T) {
+		t.Parallel()
+
+		path := registry.FileFrom(t.TempDir())
+		r, err := registry.Load(path)
+		require.NoError(t, err)
+		_, ok := r.Document(registry.Key("lossless", "1"))
+		assert.False(t, ok)
+
+		require.NoError(t, r.Put(map[string]registry.Document{
+			registry.Key("lossless", "1"): document(1),
+			registry.Key("lossless", "2"): document(2),
+		}))
+
+		r, err = registry.Load(path)
+		require.NoError(t, err)
+		doc, ok := r.Document(registry.Key("lossless", "1"))
+		require.True(t, ok)
+		assert.Equal(t, document(1), doc)
+		_, ok = r.Document(registry.Key("hi_res_lossless", "1"))
+		assert.False(t, ok)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		t.Parallel()
+
+		path := registry.FileFrom(t.TempDir())
+		r, err := registry.Load(path)
+		require.NoError(t, err)
+
+		require.NoError(t, r.Put(map[string]registry.Document{registry.Key("lossless", "1"): document(1)}))
+		require.NoError(t, r.Put(map[string]registry.Document{registry.Key("lossless", "2"): document(2)}))
+		require.NoError(t, r.Remove(registry.Key("lossless", "1")))
+
+		r, err = registry.Load(path)
+		require.NoError(t, err)
+		_, ok := r.Document(registry.Key("lossless", "1"))
+		assert.False(t, ok)
+		_, ok = r.Document(registry.Key("lossless", "2"))
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,159 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/tidal"
+)
+
+// Chat holds settings overridden for a single chat. Zero values mean the global
+// configuration is used.
+type Chat struct {
+	Quality tidal.Quality `json:"quality,omitempty"`
+}
+
+// Store holds per-chat settings persisted to a JSON file on every mutation.
+type Store struct {
+	mux   sync.Mutex
+	path  string
+	chats map[string]Chat
+}
+
+func FileFrom(dir string) string {
+	return filepath.Join(dir, "settings.json")
+}
+
+func Load(path string) (*Store, error) {
+	s := &Store{
+		mux:   sync.Mutex{},
+		path:  path,
+		chats: make(map[string]Chat),
+	}
+
+	chats, err := readFile(path)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	s.chats = chats
+
+	return s, nil
+}
+
+// Chat returns settings of the chat identified by key.
+func (s *Store) Chat(key string) Chat {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.chats[key]
+}
+
+// SetChat replaces settings of the chat identified by key. Setting the zero value
+// removes the chat overrides altogether.
+func (s *Store) SetChat(key string, c Chat) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	next := maps.Clone(s.chats)
+	if c == (Chat{}) { //nolint:exhaustruct
+		delete(next, key)
+	} else {
+		next[key] = c
+	}
+	if err := writeFile(s.path, next); nil != err {
+		return err
+	}
+	s.chats = next
+
+	return nil
+}
+
+func readFile(path string) (m map[string]Chat, err error) {
+	flawP := flaw.P{"file_path": path}
+
+	f, err := os.OpenFile(path, os.O_RDONLY, 0o0600)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, os.ErrNotExist
+		}
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to open settings file for read: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if closeErr := f.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close settings file: %v", closeErr)).Append(flawP)
+			if nil != err {
+				err = must.BeFlaw(err).Join(closeErr)
+			} else {
+				err = closeErr
+			}
+		}
+	}()
+
+	out := make(map[string]Chat)
+	if err := json.NewDecoder(f).Decode(&out); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to decode settings file contents: %v", err)).Append(flawP)
+	}
+
+	return out, nil
+}
+
+func writeFile(path string, m map[string]Chat) (err error) {
+	tmpPath := path + ".tmp"
+	flawP := flaw.P{"file_path": path, "tmp_file_path": tmpPath}
+
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o0600)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to open settings temp file for write: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if closeErr := f.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close settings temp file: %v", closeErr)).Append(flawP)
+			if nil != err {
+				err = must.BeFlaw(err).Join(closeErr)
+			} else {
+				err = closeErr
+			}
+		}
+
+		if nil != err {
+			if removeErr := os.Remove(tmpPath); nil != removeErr && !errors.Is(removeErr, os.ErrNotExist) {
+				flawP["err_debug_tree"] = errutil.Tree(removeErr).FlawP()
+				err = flaw.From(fmt.Errorf("failed to remove settings temp file: %v", removeErr)).Join(err).Append(flawP)
+			}
+			return
+		}
+
+		if renameErr := os.Rename(tmpPath, path); nil != renameErr {
+			flawP["err_debug_tree"] = errutil.Tree(renameErr).FlawP()
+			err = flaw.From(fmt.Errorf("failed to replace settings file: %v", renameErr)).Append(flawP)
+		}
+	}()
+
+	if err := json.NewEncoder(f).Encode(m); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to write settings content: %v", err)).Append(flawP)
+	}
+
+	if err := f.Sync(); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to sync settings temp file: %v", err)).Append(flawP)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package settings_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/settings"
+	"github.com/xeptore/tgtd/tidal"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("survives_reload", func(t *testing.T) {
+		t.Parallel()
+
+		path := settings.FileFrom(t.TempDir())
+		s, err := settings.Load(path)
+		require.NoError(t, err)
+		assert.Zero(t, s.Chat("user:1"))
+
+		require.NoError(t, s.SetChat("user:1", settings.Chat{Quality: tidal.QualityHigh}))
+		require.NoError(t, s.SetChat("channel:2", settings.Chat{Quality: tidal.QualityLossless}))
+
+		s, err = settings.Load(path)
+		require.NoError(t, err)
+		assert.Exactly(t, tidal.QualityHigh, s.Chat("user:1").Quality)
+		assert.Exactly(t, tidal.QualityLossless, s.Chat("channel:2").Quality)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		t.Parallel()
+
+		path := settings.FileFrom(t.TempDir())
+		s, err := settings.Load(path)
+		require.NoError(t, err)
+
+		require.NoError(t, s.SetChat("user:1", settings.Chat{Quality: tidal.QualityHigh}))
+		require.NoError(t, s.SetChat("user:1", settings.Chat{})) //nolint:exhaustruct
+
+		s, err = settings.Load(path)
+		require.NoError(t, err)
+		assert.Zero(t, s.Chat("user:1"))
+	})
+}
@@ -0,0 +1,283 @@
+package subscription
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/must"
+)
+
+var ErrNotFound = errors.New("subscription not found")
+
+// Subscription is a followed playlist whose newly added tracks are posted to
+// Target.
+type Subscription[T any] struct {
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	PlaylistID string    `json:"playlist_id"`
+	Title      string    `json:"title"`
+	Target     T         `json:"target"`
+	// Interval is the duration between consecutive polls of the playlist.
+	Interval time.Duration `json:"interval"`
+	// TrackIDs are IDs of the playlist tracks as of the last poll, excluding ones
+	// added since the previous polls which are not posted yet.
+	TrackIDs []string  `json:"track_ids"`
+	PolledAt time.Time `json:"polled_at"`
+	// JobID is ID of the job posting added tracks, or empty if there is none.
+	JobID string `json:"job_id,omitempty"`
+}
+
+// Due reports whether the playlist is due to be polled at now.
+func (s Subscription[T]) Due(now time.Time) bool {
+	return !now.Before(s.PolledAt.Add(s.Interval))
+}
+
+type state[T any] struct {
+	LastID        int               `json:"last_id"`
+	Subscriptions []Subscription[T] `json:"subscriptions"`
+}
+
+// Store holds subscriptions persisted to a JSON file on every mutation.
+type Store[T any] struct {
+	mux   sync.Mutex
+	path  string
+	state state[T]
+}
+
+func FileFrom(dir string) string {
+	return filepath.Join(dir, "subscriptions.json")
+}
+
+func Load[T any](path string) (*Store[T], error) {
+	s := &Store[T]{
+		mux:   sync.Mutex{},
+		path:  path,
+		state: state[T]{LastID: 0, Subscriptions: nil},
+	}
+
+	st, err := readStateFile[T](path)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	s.state = *st
+
+	return s, nil
+}
+
+// Add stores sub with a newly assigned ID, and creation time, which replace
+// those of sub, and returns the stored subscription.
+func (s *Store[T]) Add(sub Subscription[T]) (*Subscription[T], error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	next := state[T]{
+		LastID:        s.state.LastID + 1,
+		Subscriptions: slices.Clone(s.state.Subscriptions),
+	}
+	sub.ID = strconv.Itoa(next.LastID)
+	sub.CreatedAt = time.Now()
+	next.Subscriptions = append(next.Subscriptions, sub)
+	if err := writeStateFile(s.path, next); nil != err {
+		return nil, err
+	}
+	s.state = next
+
+	return &sub, nil
+}
+
+// Remove removes the subscription with the given ID, and returns it.
+func (s *Store[T]) Remove(id string) (*Subscription[T], error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	idx := slices.IndexFunc(s.state.Subscriptions, func(sub Subscription[T]) bool { return sub.ID == id })
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+	sub := s.state.Subscriptions[idx]
+
+	next := state[T]{
+		LastID:        s.state.LastID,
+		Subscriptions: slices.Delete(slices.Clone(s.state.Subscriptions), idx, idx+1),
+	}
+	if err := writeStateFile(s.path, next); nil != err {
+		return nil, err
+	}
+	s.state = next
+
+	return &sub, nil
+}
+
+// Polled records the result of polling the playlist of the subscription with
+// the given ID at the given time, along with ID of the job posting tracks added
+// to it, if any.
+func (s *Store[T]) Polled(id, title string, trackIDs []string, jobID string, at time.Time) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	idx := slices.IndexFunc(s.state.Subscriptions, func(sub Subscription[T]) bool { return sub.ID == id })
+	if idx == -1 {
+		return ErrNotFound
+	}
+
+	next := state[T]{
+		LastID:        s.state.LastID,
+		Subscriptions: slices.Clone(s.state.Subscriptions),
+	}
+	next.Subscriptions[idx].Title = title
+	next.Subscriptions[idx].TrackIDs = trackIDs
+	next.Subscriptions[idx].JobID = jobID
+	next.Subscriptions[idx].PolledAt = at
+	if err := writeStateFile(s.path, next); nil != err {
+		return err
+	}
+	s.state = next
+
+	return nil
+}
+
+// Posted adds trackIDs posted by the job with the given ID to the snapshot of
+// the subscription with the given ID, so that they are not considered added by
+// later polls, and clears the job of the subscription if it is the given one.
+func (s *Store[T]) Posted(id, jobID string, trackIDs []string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	idx := slices.IndexFunc(s.state.Subscriptions, func(sub Subscription[T]) bool { return sub.ID == id })
+	if idx == -1 {
+		return ErrNotFound
+	}
+
+	next := state[T]{
+		LastID:        s.state.LastID,
+		Subscriptions: slices.Clone(s.state.Subscriptions),
+	}
+	sub := &next.Subscriptions[idx]
+	known := make(map[string]struct{}, len(sub.TrackIDs))
+	for _, trackID := range sub.TrackIDs {
+		known[trackID] = struct{}{}
+	}
+	snapshot := slices.Clone(sub.TrackIDs)
+	for _, trackID := range trackIDs {
+		if _, ok := known[trackID]; !ok {
+			known[trackID] = struct{}{}
+			snapshot = append(snapshot, trackID)
+		}
+	}
+	sub.TrackIDs = snapshot
+	if sub.JobID == jobID {
+		sub.JobID = ""
+	}
+	if err := writeStateFile(s.path, next); nil != err {
+		return err
+	}
+	s.state = next
+
+	return nil
+}
+
+// List returns a snapshot of subscriptions in the order they were added.
+func (s *Store[T]) List() []Subscription[T] {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return slices.Clone(s.state.Subscriptions)
+}
+
+// Due returns subscriptions which are due to be polled at now.
+func (s *Store[T]) Due(now time.Time) []Subscription[T] {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return slices.DeleteFunc(slices.Clone(s.state.Subscriptions), func(sub Subscription[T]) bool { return !sub.Due(now) })
+}
+
+func readStateFile[T any](path string) (s *state[T], err error) {
+	flawP := flaw.P{"file_path": path}
+
+	f, err := os.OpenFile(path, os.O_RDONLY, 0o0600)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, os.ErrNotExist
+		}
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to open subscriptions file for read: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if closeErr := f.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close subscriptions file: %v", closeErr)).Append(flawP)
+			if nil != err {
+				err = must.BeFlaw(err).Join(closeErr)
+			} else {
+				err = closeErr
+			}
+		}
+	}()
+
+	var out state[T]
+	if err := json.NewDecoder(f).Decode(&out); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to decode subscriptions file contents: %v", err)).Append(flawP)
+	}
+
+	return &out, nil
+}
+
+func writeStateFile[T any](path string, s state[T]) (err error) {
+	tmpPath := path + ".tmp"
+	flawP := flaw.P{"file_path": path, "tmp_file_path": tmpPath}
+
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o0600)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to open subscriptions temp file for write: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if closeErr := f.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close subscriptions temp file: %v", closeErr)).Append(flawP)
+			if nil != err {
+				err = must.BeFlaw(err).Join(closeErr)
+			} else {
+				err = closeErr
+			}
+		}
+
+		if nil != err {
+			if removeErr := os.Remove(tmpPath); nil != removeErr && !errors.Is(removeErr, os.ErrNotExist) {
+				flawP["err_debug_tree"] = errutil.Tree(removeErr).FlawP()
+				err = flaw.From(fmt.Errorf("failed to remove subscriptions temp file: %v", removeErr)).Join(err).Append(flawP)
+			}
+			return
+		}
+
+		if renameErr := os.Rename(tmpPath, path); nil != renameErr {
+			flawP["err_debug_tree"] = errutil.Tree(renameErr).FlawP()
+			err = flaw.From(fmt.Errorf("failed to replace subscriptions file: %v", renameErr)).Append(flawP)
+		}
+	}()
+
+	if err := json.NewEncoder(f).Encode(s); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to write subscriptions content: %v", err)).Append(flawP)
+	}
+
+	if err := f.Sync(); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to sync subscriptions temp file: %v", err)).Append(flawP)
+	}
+
+	return nil
+}
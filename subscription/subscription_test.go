@@ -0,0 +1,130 @@
+package subscription_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/subscription"
+)
+
+func newSubscription(playlistID string, polledAt time.Time) subscription.Subscription[string] {
+	return subscription.Subscription[string]{
+		ID:         "",
+		CreatedAt:  time.Time{},
+		PlaylistID: playlistID,
+		Title:      "Playlist " + playlistID,
+		Target:     "chat",
+		Interval:   time.Hour,
+		TrackIDs:   []string{"1", "2"},
+		PolledAt:   polledAt,
+		JobID:      "",
+	}
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("add_and_remove", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := subscription.Load[string](subscription.FileFrom(t.TempDir()))
+		require.NoError(t, err)
+
+		first, err := s.Add(newSubscription("a", time.Now()))
+		require.NoError(t, err)
+		second, err := s.Add(newSubscription("b", time.Now()))
+		require.NoError(t, err)
+		assert.NotEqual(t, first.ID, second.ID)
+		assert.False(t, first.CreatedAt.IsZero())
+
+		removed, err := s.Remove(first.ID)
+		require.NoError(t, err)
+		assert.Exactly(t, "a", removed.PlaylistID)
+
+		subs := s.List()
+		require.Len(t, subs, 1)
+		assert.Exactly(t, second.ID, subs[0].ID)
+
+		_, err = s.Remove(first.ID)
+		require.ErrorIs(t, err, subscription.ErrNotFound)
+	})
+
+	t.Run("due", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := subscription.Load[string](subscription.FileFrom(t.TempDir()))
+		require.NoError(t, err)
+
+		now := time.Now()
+		_, err = s.Add(newSubscription("a", now.Add(-2*time.Hour)))
+		require.NoError(t, err)
+		recent, err := s.Add(newSubscription("b", now.Add(-time.Minute)))
+		require.NoError(t, err)
+
+		due := s.Due(now)
+		require.Len(t, due, 1)
+		assert.Exactly(t, "a", due[0].PlaylistID)
+
+		assert.Empty(t, s.Due(now.Add(-90*time.Minute)))
+		assert.Len(t, s.Due(now.Add(time.Hour)), 2)
+
+		require.NoError(t, s.Polled(due[0].ID, "Renamed", []string{"1", "2", "3"}, "", now))
+		assert.Empty(t, s.Due(now))
+		assert.False(t, recent.Due(now))
+
+		require.ErrorIs(t, s.Polled("unknown", "", nil, "", now), subscription.ErrNotFound)
+	})
+
+	t.Run("posted", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := subscription.Load[string](subscription.FileFrom(t.TempDir()))
+		require.NoError(t, err)
+
+		sub, err := s.Add(newSubscription("a", time.Now()))
+		require.NoError(t, err)
+		require.NoError(t, s.Polled(sub.ID, sub.Title, []string{"1", "2"}, "7", time.Now()))
+
+		// Tracks posted by an earlier job do not clear the pending one.
+		require.NoError(t, s.Posted(sub.ID, "6", []string{"3"}))
+		subs := s.List()
+		assert.Exactly(t, []string{"1", "2", "3"}, subs[0].TrackIDs)
+		assert.Exactly(t, "7", subs[0].JobID)
+
+		require.NoError(t, s.Posted(sub.ID, "7", []string{"2", "4"}))
+		subs = s.List()
+		assert.Exactly(t, []string{"1", "2", "3", "4"}, subs[0].TrackIDs)
+		assert.Empty(t, subs[0].JobID)
+
+		require.ErrorIs(t, s.Posted("unknown", "7", nil), subscription.ErrNotFound)
+	})
+
+	t.Run("survives_reload", func(t *testing.T) {
+		t.Parallel()
+
+		path := subscription.FileFrom(t.TempDir())
+		s, err := subscription.Load[string](path)
+		require.NoError(t, err)
+
+		sub, err := s.Add(newSubscription("a", time.Now()))
+		require.NoError(t, err)
+		require.NoError(t, s.Polled(sub.ID, "Renamed", []string{"1", "2", "3"}, "", time.Now()))
+
+		reloaded, err := subscription.Load[string](path)
+		require.NoError(t, err)
+
+		subs := reloaded.List()
+		require.Len(t, subs, 1)
+		assert.Exactly(t, "Renamed", subs[0].Title)
+		assert.Exactly(t, []string{"1", "2", "3"}, subs[0].TrackIDs)
+		assert.Exactly(t, time.Hour, subs[0].Interval)
+		assert.Exactly(t, "chat", subs[0].Target)
+
+		next, err := reloaded.Add(newSubscription("b", time.Now()))
+		require.NoError(t, err)
+		assert.Exactly(t, "2", next.ID)
+	})
+}
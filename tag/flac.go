@@ -0,0 +1,444 @@
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/must"
+)
+
+const (
+	flacMagic = "fLaC"
+	// vendor is the vendor string of written Vorbis comment blocks.
+	vendor = "tgtd"
+
+	flacBlockStreamInfo    = 0
+	flacBlockPadding       = 1
+	flacBlockVorbisComment = 4
+	flacBlockPicture       = 6
+
+	flacPictureTypeFrontCover = 3
+	flacMaxBlockLength        = 1<<24 - 1
+)
+
+type flacBlock struct {
+	typ  byte
+	data []byte
+}
+
+// WriteFLAC replaces Vorbis comments and pictures of the FLAC file at path with
+// the given tags. FLAC streams stored in an MP4 container, as delivered by DASH
+// streams, are converted to native FLAC files.
+func WriteFLAC(path string, tags Tags) (err error) {
+	flawP := flaw.P{"file_path": path}
+
+	f, err := os.Open(path)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to open track file: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if closeErr := f.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close track file: %v", closeErr)).Append(flawP)
+			switch {
+			case nil == err:
+				err = closeErr
+			case errutil.IsFlaw(err):
+				err = must.BeFlaw(err).Join(closeErr)
+			}
+		}
+	}()
+
+	tagBlocks, err := flacTagBlocks(tags)
+	if nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	}
+
+	var (
+		blocks []flacBlock
+		frames func(w io.Writer) error
+	)
+	if isMP4, err := hasMP4Signature(f); nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	} else if isMP4 {
+		b, copyFrames, err := flacFromMP4(f)
+		if nil != err {
+			if errors.Is(err, ErrUnsupported) {
+				return ErrUnsupported
+			}
+			return must.BeFlaw(err).Append(flawP)
+		}
+		blocks, frames = b, copyFrames
+	} else {
+		b, framesOffset, err := readFLACBlocks(f)
+		if nil != err {
+			if errors.Is(err, ErrUnsupported) {
+				return ErrUnsupported
+			}
+			return must.BeFlaw(err).Append(flawP)
+		}
+		blocks = b
+		frames = func(w io.Writer) error {
+			if _, err := f.Seek(framesOffset, io.SeekStart); nil != err {
+				flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+				return flaw.From(fmt.Errorf("failed to seek to audio frames: %v", err)).Append(flawP)
+			}
+			if _, err := io.Copy(w, f); nil != err {
+				flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+				return flaw.From(fmt.Errorf("failed to copy audio frames: %v", err)).Append(flawP)
+			}
+			return nil
+		}
+	}
+
+	kept := make([]flacBlock, 0, len(blocks)+len(tagBlocks))
+	for _, b := range blocks {
+		switch b.typ {
+		case flacBlockPadding, flacBlockVorbisComment, flacBlockPicture:
+		default:
+			kept = append(kept, b)
+		}
+	}
+	if len(kept) == 0 || kept[0].typ != flacBlockStreamInfo {
+		return flaw.From(errors.New("missing FLAC stream info block")).Append(flawP)
+	}
+	kept = append(kept, tagBlocks...)
+
+	return replaceFile(path, func(w io.Writer) error {
+		if _, err := io.WriteString(w, flacMagic); nil != err {
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+			return flaw.From(fmt.Errorf("failed to write FLAC signature: %v", err)).Append(flawP)
+		}
+		for i, b := range kept {
+			if err := writeFLACBlock(w, b, i == len(kept)-1); nil != err {
+				return err
+			}
+		}
+		return frames(w)
+	})
+}
+
+// ReadFLAC reads tags written by WriteFLAC from the FLAC file at path.
+func ReadFLAC(path string) (*Tags, error) {
+	flawP := flaw.P{"file_path": path}
+
+	f, err := os.Open(path)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to open track file: %v", err)).Append(flawP)
+	}
+	defer f.Close()
+
+	blocks, _, err := readFLACBlocks(f)
+	if nil != err {
+		if errors.Is(err, ErrUnsupported) {
+			return nil, ErrUnsupported
+		}
+		return nil, must.BeFlaw(err).Append(flawP)
+	}
+
+	var tags Tags
+	for _, b := range blocks {
+		switch b.typ {
+		case flacBlockVorbisComment:
+			if err := parseVorbisComments(b.data, &tags); nil != err {
+				return nil, must.BeFlaw(err).Append(flawP)
+			}
+		case flacBlockPicture:
+			cover, err := parseFLACPicture(b.data)
+			if nil != err {
+				return nil, must.BeFlaw(err).Append(flawP)
+			}
+			tags.Cover = cover
+		}
+	}
+	return &tags, nil
+}
+
+// readFLACBlocks reads metadata blocks of a native FLAC stream, and returns them
+// along with offset of the first audio frame.
+func readFLACBlocks(r io.ReadSeeker) ([]flacBlock, int64, error) {
+	offset, err := skipID3(r)
+	if nil != err {
+		return nil, 0, err
+	}
+
+	magic := make([]byte, len(flacMagic))
+	if _, err := io.ReadFull(r, magic); nil != err || string(magic) != flacMagic {
+		return nil, 0, ErrUnsupported
+	}
+	offset += int64(len(magic))
+
+	var blocks []flacBlock
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); nil != err {
+			flawP := flaw.P{"offset": offset, "err_debug_tree": errutil.Tree(err).FlawP()}
+			return nil, 0, flaw.From(fmt.Errorf("failed to read metadata block header: %v", err)).Append(flawP)
+		}
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); nil != err {
+			flawP := flaw.P{"offset": offset, "length": length, "err_debug_tree": errutil.Tree(err).FlawP()}
+			return nil, 0, flaw.From(fmt.Errorf("failed to read metadata block: %v", err)).Append(flawP)
+		}
+		offset += int64(len(header) + length)
+		blocks = append(blocks, flacBlock{typ: header[0] & 0x7f, data: data})
+
+		if header[0]&0x80 != 0 {
+			return blocks, offset, nil
+		}
+	}
+}
+
+// skipID3 skips an ID3v2 tag some encoders prepend to FLAC files, and returns
+// number of skipped bytes.
+func skipID3(r io.ReadSeeker) (int64, error) {
+	var header [10]byte
+	n, err := io.ReadFull(r, header[:])
+	if nil != err && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return 0, flaw.From(fmt.Errorf("failed to read file header: %v", err)).Append(flawP)
+	}
+
+	var skip int64
+	if n == len(header) && string(header[:3]) == "ID3" {
+		size := int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9])
+		skip = int64(len(header)) + size
+	}
+	if _, err := r.Seek(skip, io.SeekStart); nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return 0, flaw.From(fmt.Errorf("failed to seek past ID3 tag: %v", err)).Append(flawP)
+	}
+	return skip, nil
+}
+
+func writeFLACBlock(w io.Writer, b flacBlock, last bool) error {
+	flawP := flaw.P{"type": b.typ, "length": len(b.data)}
+	if len(b.data) > flacMaxBlockLength {
+		return flaw.From(errors.New("metadata block is too large")).Append(flawP)
+	}
+
+	header := [4]byte{b.typ, byte(len(b.data) >> 16), byte(len(b.data) >> 8), byte(len(b.data))}
+	if last {
+		header[0] |= 0x80
+	}
+	if _, err := w.Write(header[:]); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to write metadata block header: %v", err)).Append(flawP)
+	}
+	if _, err := w.Write(b.data); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to write metadata block: %v", err)).Append(flawP)
+	}
+	return nil
+}
+
+func flacTagBlocks(tags Tags) ([]flacBlock, error) {
+	blocks := []flacBlock{{typ: flacBlockVorbisComment, data: vorbisComments(tags)}}
+	if len(tags.Cover) > 0 {
+		p, err := flacPicture(tags.Cover)
+		if nil != err {
+			return nil, err
+		}
+		blocks = append(blocks, flacBlock{typ: flacBlockPicture, data: p})
+	}
+	return blocks, nil
+}
+
+func vorbisComments(tags Tags) []byte {
+	var comments []string
+	add := func(key, value string) {
+		if value != "" {
+			comments = append(comments, key+"="+value)
+		}
+	}
+	addInt := func(key string, value int) {
+		if value > 0 {
+			add(key, strconv.Itoa(value))
+		}
+	}
+
+	add("TITLE", tags.Title)
+	add("ARTIST", tags.Artist)
+	add("LEAD_PERFORMER", tags.LeadPerformer)
+	add("ALBUM", tags.Album)
+	add("ALBUMARTIST", tags.AlbumArtist)
+	add("COPYRIGHT", tags.Copyright)
+	add("ISRC", tags.ISRC)
+	addInt("TRACKNUMBER", tags.TrackNumber)
+	addInt("TRACKTOTAL", tags.TrackTotal)
+	addInt("DISCNUMBER", tags.DiscNumber)
+	addInt("DISCTOTAL", tags.DiscTotal)
+	if !tags.Date.IsZero() {
+		add("DATE", tags.Date.Format(time.DateOnly))
+		addInt("YEAR", tags.Date.Year())
+	}
+	add("LYRICS", tags.Lyrics)
+	add("COMPOSER", tags.Composer)
+	add("LYRICIST", tags.Lyricist)
+	add("PRODUCER", tags.Producer)
+	add("COPRODUCER", tags.CoProducer)
+	add("VERSION", tags.Version)
+	add("COMMENT", tags.Comment)
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(vendor)))
+	buf.WriteString(vendor)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(comments)))
+	for _, c := range comments {
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(len(c)))
+		buf.WriteString(c)
+	}
+	return buf.Bytes()
+}
+
+func parseVorbisComments(b []byte, tags *Tags) error {
+	r := bytes.NewReader(b)
+	readString := func() (string, error) {
+		var length uint32
+		if err := binary.Read(r, binary.LittleEndian, &length); nil != err {
+			return "", err
+		}
+		if int64(length) > int64(r.Len()) {
+			return "", io.ErrUnexpectedEOF
+		}
+		s := make([]byte, length)
+		if _, err := io.ReadFull(r, s); nil != err {
+			return "", err
+		}
+		return string(s), nil
+	}
+
+	if _, err := readString(); nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to read vendor string: %v", err)).Append(flawP)
+	}
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to read comments count: %v", err)).Append(flawP)
+	}
+
+	for i := range count {
+		comment, err := readString()
+		if nil != err {
+			flawP := flaw.P{"index": i, "err_debug_tree": errutil.Tree(err).FlawP()}
+			return flaw.From(fmt.Errorf("failed to read comment: %v", err)).Append(flawP)
+		}
+		key, value, ok := strings.Cut(comment, "=")
+		if !ok {
+			continue
+		}
+
+		switch strings.ToUpper(key) {
+		case "TITLE":
+			tags.Title = value
+		case "ARTIST":
+			tags.Artist = value
+		case "LEAD_PERFORMER":
+			tags.LeadPerformer = value
+		case "ALBUM":
+			tags.Album = value
+		case "ALBUMARTIST":
+			tags.AlbumArtist = value
+		case "COPYRIGHT":
+			tags.Copyright = value
+		case "ISRC":
+			tags.ISRC = value
+		case "TRACKNUMBER":
+			tags.TrackNumber, _ = strconv.Atoi(value)
+		case "TRACKTOTAL":
+			tags.TrackTotal, _ = strconv.Atoi(value)
+		case "DISCNUMBER":
+			tags.DiscNumber, _ = strconv.Atoi(value)
+		case "DISCTOTAL":
+			tags.DiscTotal, _ = strconv.Atoi(value)
+		case "DATE":
+			tags.Date, _ = time.Parse(time.DateOnly, value)
+		case "LYRICS":
+			tags.Lyrics = value
+		case "COMPOSER":
+			tags.Composer = value
+		case "LYRICIST":
+			tags.Lyricist = value
+		case "PRODUCER":
+			tags.Producer = value
+		case "COPRODUCER":
+			tags.CoProducer = value
+		case "VERSION":
+			tags.Version = value
+		case "COMMENT":
+			tags.Comment = value
+		}
+	}
+	return nil
+}
+
+func flacPicture(cover []byte) ([]byte, error) {
+	p, err := inspectCover(cover)
+	if nil != err {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, v := range []uint32{flacPictureTypeFrontCover, uint32(len(p.mimeType))} {
+		_ = binary.Write(&buf, binary.BigEndian, v)
+	}
+	buf.WriteString(p.mimeType)
+	for _, v := range []uint32{0, uint32(p.width), uint32(p.height), uint32(p.depth), 0, uint32(len(cover))} {
+		_ = binary.Write(&buf, binary.BigEndian, v)
+	}
+	buf.Write(cover)
+	return buf.Bytes(), nil
+}
+
+func parseFLACPicture(b []byte) ([]byte, error) {
+	r := bytes.NewReader(b)
+	skipString := func() error {
+		var length uint32
+		if err := binary.Read(r, binary.BigEndian, &length); nil != err {
+			return err
+		}
+		_, err := r.Seek(int64(length), io.SeekCurrent)
+		return err
+	}
+
+	// Picture type, followed by MIME type, and description strings.
+	if _, err := r.Seek(4, io.SeekStart); nil != err {
+		return nil, flaw.From(fmt.Errorf("failed to skip picture type: %v", err))
+	}
+	for range 2 {
+		if err := skipString(); nil != err {
+			return nil, flaw.From(fmt.Errorf("failed to skip picture string field: %v", err))
+		}
+	}
+	// Width, height, color depth, and number of colors.
+	if _, err := r.Seek(16, io.SeekCurrent); nil != err {
+		return nil, flaw.From(fmt.Errorf("failed to skip picture dimensions: %v", err))
+	}
+
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); nil != err {
+		return nil, flaw.From(fmt.Errorf("failed to read picture data length: %v", err))
+	}
+	if int64(length) > int64(r.Len()) {
+		return nil, flaw.From(errors.New("picture data length exceeds block length"))
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); nil != err {
+		return nil, flaw.From(fmt.Errorf("failed to read picture data: %v", err))
+	}
+	return data, nil
+}
@@ -0,0 +1,615 @@
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/must"
+)
+
+const (
+	mp4DataTypeUTF8 = 1
+	mp4DataTypeJPEG = 13
+	mp4DataTypePNG  = 14
+
+	// mp4FreeformMean is the namespace of freeform (----) atoms.
+	mp4FreeformMean = "com.apple.iTunes"
+)
+
+type mp4Box struct {
+	typ string
+	// offset is the position of the box header in the file.
+	offset int64
+	// headerSize is either 8, or 16 for boxes with 64-bit size.
+	headerSize int64
+	size       int64
+}
+
+func (b mp4Box) payloadOffset() int64 {
+	return b.offset + b.headerSize
+}
+
+func (b mp4Box) payloadSize() int64 {
+	return b.size - b.headerSize
+}
+
+// WriteMP4 replaces the iTunes metadata list (ilst) of the MP4 file at path with
+// the given tags.
+func WriteMP4(path string, tags Tags) (err error) {
+	flawP := flaw.P{"file_path": path}
+
+	f, err := os.Open(path)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to open track file: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if closeErr := f.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close track file: %v", closeErr)).Append(flawP)
+			switch {
+			case nil == err:
+				err = closeErr
+			case errutil.IsFlaw(err):
+				err = must.BeFlaw(err).Join(closeErr)
+			}
+		}
+	}()
+
+	if isMP4, err := hasMP4Signature(f); nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	} else if !isMP4 {
+		return ErrUnsupported
+	}
+
+	info, err := f.Stat()
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to stat track file: %v", err)).Append(flawP)
+	}
+
+	boxes, err := readMP4Boxes(f, 0, info.Size())
+	if nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	}
+	moovIdx := -1
+	for i, b := range boxes {
+		if b.typ == "moov" {
+			moovIdx = i
+			break
+		}
+	}
+	if moovIdx == -1 {
+		return flaw.From(errors.New("missing moov box")).Append(flawP)
+	}
+	moov := boxes[moovIdx]
+
+	moovPayload := make([]byte, moov.payloadSize())
+	if _, err := f.ReadAt(moovPayload, moov.payloadOffset()); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to read moov box: %v", err)).Append(flawP)
+	}
+
+	ilst, err := mp4Ilst(tags)
+	if nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	}
+	newMoov, err := rebuildMoov(moovPayload, ilst)
+	if nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	}
+	delta := int64(len(newMoov)) - moov.size
+
+	return replaceFile(path, func(w io.Writer) error {
+		for i, b := range boxes {
+			switch {
+			case i == moovIdx:
+				if err := shiftChunkOffsets(newMoov, moov.offset, delta); nil != err {
+					return err
+				}
+				if _, err := w.Write(newMoov); nil != err {
+					flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+					return flaw.From(fmt.Errorf("failed to write moov box: %v", err)).Append(flawP)
+				}
+			case b.typ == "mfra":
+				// Fragment random access offsets are invalidated by resizing moov.
+				// Players fall back to scanning fragments without it.
+				continue
+			case b.typ == "moof" && i > moovIdx && delta != 0:
+				moof := make([]byte, b.size)
+				if _, err := f.ReadAt(moof, b.offset); nil != err {
+					flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+					return flaw.From(fmt.Errorf("failed to read moof box: %v", err)).Append(flawP)
+				}
+				if err := shiftFragmentBaseOffsets(moof, delta); nil != err {
+					return err
+				}
+				if _, err := w.Write(moof); nil != err {
+					flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+					return flaw.From(fmt.Errorf("failed to write moof box: %v", err)).Append(flawP)
+				}
+			default:
+				if _, err := io.Copy(w, io.NewSectionReader(f, b.offset, b.size)); nil != err {
+					flawP := flaw.P{"box_type": b.typ, "err_debug_tree": errutil.Tree(err).FlawP()}
+					return flaw.From(fmt.Errorf("failed to copy box: %v", err)).Append(flawP)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ReadMP4 reads tags written by WriteMP4 from the MP4 file at path.
+func ReadMP4(path string) (*Tags, error) {
+	flawP := flaw.P{"file_path": path}
+
+	b, err := os.ReadFile(path)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to read track file: %v", err)).Append(flawP)
+	}
+
+	moov, err := findMP4Box(b, "moov")
+	if nil != err {
+		return nil, must.BeFlaw(err).Append(flawP)
+	}
+	if moov == nil {
+		return nil, ErrUnsupported
+	}
+
+	var tags Tags
+	udta, err := findMP4Box(moov, "udta")
+	if nil != err || udta == nil {
+		return &tags, err
+	}
+	meta, err := findMP4Box(udta, "meta")
+	if nil != err || meta == nil || len(meta) < 4 {
+		return &tags, err
+	}
+	ilst, err := findMP4Box(meta[4:], "ilst")
+	if nil != err || ilst == nil {
+		return &tags, err
+	}
+
+	err = walkMP4Boxes(ilst, func(typ string, item []byte) error {
+		name := typ
+		var value []byte
+		if err := walkMP4Boxes(item, func(typ string, payload []byte) error {
+			switch typ {
+			case "name":
+				if len(payload) >= 4 {
+					name = string(payload[4:])
+				}
+			case "data":
+				if len(payload) >= 8 {
+					value = payload[8:]
+				}
+			}
+			return nil
+		}); nil != err {
+			return err
+		}
+
+		switch name {
+		case "\xa9nam":
+			tags.Title = string(value)
+		case "\xa9ART":
+			tags.Artist = string(value)
+		case "aART":
+			tags.AlbumArtist = string(value)
+		case "\xa9alb":
+			tags.Album = string(value)
+		case "cprt":
+			tags.Copyright = string(value)
+		case "\xa9day":
+			tags.Date, _ = time.Parse(time.DateOnly, string(value))
+		case "\xa9lyr":
+			tags.Lyrics = string(value)
+		case "\xa9wrt":
+			tags.Composer = string(value)
+		case "\xa9cmt":
+			tags.Comment = string(value)
+		case "trkn":
+			tags.TrackNumber, tags.TrackTotal = parseMP4Pair(value)
+		case "disk":
+			tags.DiscNumber, tags.DiscTotal = parseMP4Pair(value)
+		case "covr":
+			tags.Cover = bytes.Clone(value)
+		case "ISRC":
+			tags.ISRC = string(value)
+		case "LEAD_PERFORMER":
+			tags.LeadPerformer = string(value)
+		case "LYRICIST":
+			tags.Lyricist = string(value)
+		case "PRODUCER":
+			tags.Producer = string(value)
+		case "COPRODUCER":
+			tags.CoProducer = string(value)
+		case "VERSION":
+			tags.Version = string(value)
+		}
+		return nil
+	})
+	if nil != err {
+		return nil, must.BeFlaw(err).Append(flawP)
+	}
+
+	return &tags, nil
+}
+
+func hasMP4Signature(r io.ReadSeeker) (bool, error) {
+	var header [8]byte
+	n, err := io.ReadFull(r, header[:])
+	if nil != err && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return false, flaw.From(fmt.Errorf("failed to read file header: %v", err)).Append(flawP)
+	}
+	if _, err := r.Seek(0, io.SeekStart); nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return false, flaw.From(fmt.Errorf("failed to seek to file start: %v", err)).Append(flawP)
+	}
+	return n == len(header) && string(header[4:]) == "ftyp", nil
+}
+
+// readMP4Boxes reads headers of boxes in [start, end) of r.
+func readMP4Boxes(r io.ReaderAt, start, end int64) ([]mp4Box, error) {
+	var boxes []mp4Box
+	for offset := start; offset < end; {
+		flawP := flaw.P{"offset": offset}
+
+		var header [16]byte
+		if _, err := r.ReadAt(header[:8], offset); nil != err {
+			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+			return nil, flaw.From(fmt.Errorf("failed to read box header: %v", err)).Append(flawP)
+		}
+		b := mp4Box{
+			typ:        string(header[4:8]),
+			offset:     offset,
+			headerSize: 8,
+			size:       int64(binary.BigEndian.Uint32(header[:4])),
+		}
+		switch b.size {
+		case 0:
+			b.size = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:], offset+8); nil != err {
+				flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+				return nil, flaw.From(fmt.Errorf("failed to read box extended size: %v", err)).Append(flawP)
+			}
+			b.headerSize = 16
+			b.size = int64(binary.BigEndian.Uint64(header[8:]))
+		}
+		if b.size < b.headerSize || offset+b.size > end {
+			flawP["box_type"] = b.typ
+			flawP["box_size"] = b.size
+			return nil, flaw.From(errors.New("invalid box size")).Append(flawP)
+		}
+
+		boxes = append(boxes, b)
+		offset += b.size
+	}
+	return boxes, nil
+}
+
+// walkMP4Boxes calls fn with type and payload of each box contained in b.
+func walkMP4Boxes(b []byte, fn func(typ string, payload []byte) error) error {
+	boxes, err := readMP4Boxes(bytes.NewReader(b), 0, int64(len(b)))
+	if nil != err {
+		return err
+	}
+	for _, box := range boxes {
+		if err := fn(box.typ, b[box.payloadOffset():box.offset+box.size]); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// findMP4Box returns payload of the first box of type typ contained in b, or nil
+// if there is no such box.
+func findMP4Box(b []byte, typ string) ([]byte, error) {
+	var out []byte
+	err := walkMP4Boxes(b, func(t string, payload []byte) error {
+		if out == nil && t == typ {
+			out = payload
+		}
+		return nil
+	})
+	return out, err
+}
+
+func appendMP4Box(dst []byte, typ string, payload ...[]byte) []byte {
+	size := 8
+	for _, p := range payload {
+		size += len(p)
+	}
+	dst = binary.BigEndian.AppendUint32(dst, uint32(size)) //nolint:gosec
+	dst = append(dst, typ...)
+	for _, p := range payload {
+		dst = append(dst, p...)
+	}
+	return dst
+}
+
+// rebuildMoov returns moov box with any existing udta/meta box replaced with one
+// holding the given ilst payload.
+func rebuildMoov(payload, ilst []byte) ([]byte, error) {
+	var (
+		children []byte
+		udta     []byte
+	)
+	err := walkMP4Boxes(payload, func(typ string, p []byte) error {
+		if typ == "udta" {
+			udta = p
+			return nil
+		}
+		children = appendMP4Box(children, typ, p)
+		return nil
+	})
+	if nil != err {
+		return nil, err
+	}
+
+	var udtaChildren []byte
+	if udta != nil {
+		err := walkMP4Boxes(udta, func(typ string, p []byte) error {
+			if typ != "meta" {
+				udtaChildren = appendMP4Box(udtaChildren, typ, p)
+			}
+			return nil
+		})
+		if nil != err {
+			return nil, err
+		}
+	}
+
+	hdlr := []byte{
+		0, 0, 0, 0, // version and flags
+		0, 0, 0, 0, // pre-defined
+		'm', 'd', 'i', 'r',
+		'a', 'p', 'p', 'l',
+		0, 0, 0, 0, 0, 0, 0, 0, // reserved
+		0, // empty name
+	}
+	meta := appendMP4Box(nil, "meta", []byte{0, 0, 0, 0}, appendMP4Box(nil, "hdlr", hdlr), appendMP4Box(nil, "ilst", ilst))
+	children = appendMP4Box(children, "udta", udtaChildren, meta)
+
+	return appendMP4Box(nil, "moov", children), nil
+}
+
+func mp4Ilst(tags Tags) ([]byte, error) {
+	var ilst []byte
+	data := func(typ uint32, value []byte) []byte {
+		return appendMP4Box(nil, "data", binary.BigEndian.AppendUint32(nil, typ), []byte{0, 0, 0, 0}, value)
+	}
+	addText := func(name, value string) {
+		if value != "" {
+			ilst = appendMP4Box(ilst, name, data(mp4DataTypeUTF8, []byte(value)))
+		}
+	}
+	addFreeform := func(name, value string) {
+		if value != "" {
+			mean := appendMP4Box(nil, "mean", []byte{0, 0, 0, 0}, []byte(mp4FreeformMean))
+			nameBox := appendMP4Box(nil, "name", []byte{0, 0, 0, 0}, []byte(name))
+			ilst = appendMP4Box(ilst, "----", mean, nameBox, data(mp4DataTypeUTF8, []byte(value)))
+		}
+	}
+	addPair := func(name string, n, total int) {
+		if n > 0 || total > 0 {
+			v := []byte{0, 0, byte(n >> 8), byte(n), byte(total >> 8), byte(total), 0, 0}
+			ilst = appendMP4Box(ilst, name, data(0, v))
+		}
+	}
+
+	addText("\xa9nam", tags.Title)
+	addText("\xa9ART", tags.Artist)
+	addText("aART", tags.AlbumArtist)
+	addText("\xa9alb", tags.Album)
+	addText("cprt", tags.Copyright)
+	addPair("trkn", tags.TrackNumber, tags.TrackTotal)
+	addPair("disk", tags.DiscNumber, tags.DiscTotal)
+	if !tags.Date.IsZero() {
+		addText("\xa9day", tags.Date.Format(time.DateOnly))
+	}
+	addText("\xa9lyr", tags.Lyrics)
+	addText("\xa9wrt", tags.Composer)
+	addText("\xa9cmt", tags.Comment)
+	addFreeform("ISRC", tags.ISRC)
+	addFreeform("LEAD_PERFORMER", tags.LeadPerformer)
+	addFreeform("LYRICIST", tags.Lyricist)
+	addFreeform("PRODUCER", tags.Producer)
+	addFreeform("COPRODUCER", tags.CoProducer)
+	addFreeform("VERSION", tags.Version)
+
+	if len(tags.Cover) > 0 {
+		p, err := inspectCover(tags.Cover)
+		if nil != err {
+			return nil, err
+		}
+		typ := uint32(mp4DataTypeJPEG)
+		if p.mimeType == "image/png" {
+			typ = mp4DataTypePNG
+		}
+		ilst = appendMP4Box(ilst, "covr", data(typ, tags.Cover))
+	}
+
+	return ilst, nil
+}
+
+func parseMP4Pair(b []byte) (int, int) {
+	if len(b) < 6 {
+		return 0, 0
+	}
+	return int(binary.BigEndian.Uint16(b[2:4])), int(binary.BigEndian.Uint16(b[4:6]))
+}
+
+// shiftChunkOffsets adds delta to all stco and co64 entries of moov pointing past
+// the original moov box at moovOffset.
+func shiftChunkOffsets(moov []byte, moovOffset, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+
+	var walk func(b []byte) error
+	walk = func(b []byte) error {
+		return walkMP4Boxes(b, func(typ string, payload []byte) error {
+			switch typ {
+			case "trak", "mdia", "minf", "stbl":
+				return walk(payload)
+			case "stco", "co64":
+				if len(payload) < 8 {
+					return flaw.From(fmt.Errorf("invalid %s box", typ))
+				}
+				count := int(binary.BigEndian.Uint32(payload[4:8]))
+				entrySize := 4
+				if typ == "co64" {
+					entrySize = 8
+				}
+				if len(payload) < 8+count*entrySize {
+					return flaw.From(fmt.Errorf("truncated %s box", typ))
+				}
+				for i := range count {
+					entry := payload[8+i*entrySize : 8+(i+1)*entrySize]
+					if typ == "stco" {
+						if v := int64(binary.BigEndian.Uint32(entry)); v > moovOffset {
+							if v+delta > int64(^uint32(0)) {
+								return flaw.From(errors.New("chunk offset overflows stco box"))
+							}
+							binary.BigEndian.PutUint32(entry, uint32(v+delta)) //nolint:gosec
+						}
+					} else if v := int64(binary.BigEndian.Uint64(entry)); v > moovOffset { //nolint:gosec
+						binary.BigEndian.PutUint64(entry, uint64(v+delta)) //nolint:gosec
+					}
+				}
+			}
+			return nil
+		})
+	}
+
+	moovPayload, err := findMP4Box(moov, "moov")
+	if nil != err {
+		return err
+	}
+	return walk(moovPayload)
+}
+
+// shiftFragmentBaseOffsets adds delta to explicit base data offsets of track
+// fragment headers in moof.
+func shiftFragmentBaseOffsets(moof []byte, delta int64) error {
+	payload, err := findMP4Box(moof, "moof")
+	if nil != err {
+		return err
+	}
+	return walkMP4Boxes(payload, func(typ string, traf []byte) error {
+		if typ != "traf" {
+			return nil
+		}
+		return walkMP4Boxes(traf, func(typ string, tfhd []byte) error {
+			if typ != "tfhd" || len(tfhd) < 16 {
+				return nil
+			}
+			if flags := binary.BigEndian.Uint32(tfhd[:4]) & 0xffffff; flags&0x1 != 0 {
+				v := int64(binary.BigEndian.Uint64(tfhd[8:16]))         //nolint:gosec
+				binary.BigEndian.PutUint64(tfhd[8:16], uint64(v+delta)) //nolint:gosec
+			}
+			return nil
+		})
+	})
+}
+
+// flacFromMP4 extracts FLAC metadata blocks and a function copying audio frames
+// of the FLAC stream stored in the MP4 file r.
+func flacFromMP4(r io.ReadSeeker) ([]flacBlock, func(w io.Writer) error, error) {
+	b, err := io.ReadAll(r)
+	if nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return nil, nil, flaw.From(fmt.Errorf("failed to read track file: %v", err)).Append(flawP)
+	}
+
+	moov, err := findMP4Box(b, "moov")
+	if nil != err {
+		return nil, nil, err
+	}
+	if moov == nil {
+		return nil, nil, ErrUnsupported
+	}
+	dfLa, err := findFLACSampleEntry(moov)
+	if nil != err {
+		return nil, nil, err
+	}
+	if dfLa == nil || len(dfLa) < 4 {
+		return nil, nil, ErrUnsupported
+	}
+
+	// dfLa is a full box holding native FLAC metadata blocks.
+	blocks, _, err := readFLACBlocks(bytes.NewReader(append([]byte(flacMagic), dfLa[4:]...)))
+	if nil != err {
+		return nil, nil, err
+	}
+
+	frames := func(w io.Writer) error {
+		return walkMP4Boxes(b, func(typ string, payload []byte) error {
+			if typ != "mdat" {
+				return nil
+			}
+			if _, err := w.Write(payload); nil != err {
+				flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+				return flaw.From(fmt.Errorf("failed to write audio frames: %v", err)).Append(flawP)
+			}
+			return nil
+		})
+	}
+
+	return blocks, frames, nil
+}
+
+// findFLACSampleEntry returns payload of the dfLa box of the first fLaC sample
+// entry in moov, or nil if there is none.
+func findFLACSampleEntry(moov []byte) ([]byte, error) {
+	// Audio sample entry fields precede child boxes of a fLaC box.
+	const audioSampleEntrySize = 28
+
+	var (
+		out  []byte
+		walk func(b []byte) error
+	)
+	walk = func(b []byte) error {
+		return walkMP4Boxes(b, func(typ string, payload []byte) error {
+			if out != nil {
+				return nil
+			}
+			switch typ {
+			case "trak", "mdia", "minf", "stbl":
+				return walk(payload)
+			case "stsd":
+				// Full box header, followed by entry count.
+				if len(payload) < 8 {
+					return flaw.From(errors.New("invalid stsd box"))
+				}
+				return walk(payload[8:])
+			case "fLaC":
+				if len(payload) < audioSampleEntrySize {
+					return flaw.From(errors.New("invalid fLaC sample entry"))
+				}
+				dfLa, err := findMP4Box(payload[audioSampleEntrySize:], "dfLa")
+				if nil != err {
+					return err
+				}
+				out = dfLa
+			}
+			return nil
+		})
+	}
+	if err := walk(moov); nil != err {
+		return nil, err
+	}
+	return out, nil
+}
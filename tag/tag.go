@@ -0,0 +1,123 @@
+package tag
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"time"
+
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/must"
+)
+
+// ErrUnsupported is returned if the file is not in a format tags can be written to.
+var ErrUnsupported = errors.New("unsupported file format")
+
+// Tags holds metadata embedded into track files. Zero values are not written.
+type Tags struct {
+	Title         string
+	Artist        string
+	LeadPerformer string
+	Album         string
+	AlbumArtist   string
+	Copyright     string
+	ISRC          string
+	TrackNumber   int
+	TrackTotal    int
+	DiscNumber    int
+	DiscTotal     int
+	Date          time.Time
+	Lyrics        string
+	Composer      string
+	Lyricist      string
+	Producer      string
+	CoProducer    string
+	Version       string
+	Comment       string
+	// Cover is the JPEG or PNG encoded front cover image.
+	Cover []byte
+}
+
+type picture struct {
+	mimeType string
+	width    int
+	height   int
+	depth    int
+}
+
+func inspectCover(b []byte) (*picture, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(b))
+	if nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return nil, flaw.From(fmt.Errorf("failed to decode cover image: %v", err)).Append(flawP)
+	}
+
+	p := &picture{mimeType: "", width: cfg.Width, height: cfg.Height, depth: 24}
+	switch format {
+	case "jpeg":
+		p.mimeType = "image/jpeg"
+	case "png":
+		p.mimeType = "image/png"
+	default:
+		return nil, flaw.From(fmt.Errorf("unsupported cover image format %q", format))
+	}
+	return p, nil
+}
+
+// replaceFile atomically replaces the file at path with the content written by write.
+func replaceFile(path string, write func(w io.Writer) error) (err error) {
+	tmpPath := path + ".tag.tmp"
+	flawP := flaw.P{"file_path": path, "tmp_file_path": tmpPath}
+
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o0600)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to create temp file: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if closeErr := f.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close temp file: %v", closeErr)).Append(flawP)
+			switch {
+			case nil == err:
+				err = closeErr
+			case errutil.IsFlaw(err):
+				err = must.BeFlaw(err).Join(closeErr)
+			}
+		}
+
+		if nil != err {
+			if removeErr := os.Remove(tmpPath); nil != removeErr && !errors.Is(removeErr, os.ErrNotExist) {
+				flawP["err_debug_tree"] = errutil.Tree(removeErr).FlawP()
+				err = flaw.From(fmt.Errorf("failed to remove temp file: %v", removeErr)).Join(err).Append(flawP)
+			}
+			return
+		}
+
+		if renameErr := os.Rename(tmpPath, path); nil != renameErr {
+			flawP["err_debug_tree"] = errutil.Tree(renameErr).FlawP()
+			err = flaw.From(fmt.Errorf("failed to replace file: %v", renameErr)).Append(flawP)
+		}
+	}()
+
+	if err := write(f); nil != err {
+		if errors.Is(err, ErrUnsupported) {
+			return ErrUnsupported
+		}
+		return must.BeFlaw(err).Append(flawP)
+	}
+
+	if err := f.Sync(); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to sync temp file: %v", err)).Append(flawP)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package tag_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/tag"
+)
+
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, b, 0o0600))
+	return path
+}
+
+func cover(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 3))
+	img.Set(1, 1, color.RGBA{R: 255, G: 0, B: 0, A: 255})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func tags(t *testing.T) tag.Tags {
+	t.Helper()
+
+	return tag.Tags{
+		Title:         "Title",
+		Artist:        "Artist A, Artist B",
+		LeadPerformer: "Performer",
+		Album:         "Album",
+		AlbumArtist:   "Album Artist",
+		Copyright:     "© 2024 Label",
+		ISRC:          "USAT22400001",
+		TrackNumber:   3,
+		TrackTotal:    12,
+		DiscNumber:    1,
+		DiscTotal:     2,
+		Date:          time.Date(2024, time.March, 8, 0, 0, 0, 0, time.UTC),
+		Lyrics:        "line one\nline two",
+		Composer:      "Composer",
+		Lyricist:      "Lyricist",
+		Producer:      "Producer",
+		CoProducer:    "Co-Producer",
+		Version:       "Remastered",
+		Comment:       "Dolby Atmos",
+		Cover:         cover(t),
+	}
+}
+
+func TestFLAC(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round_trip", func(t *testing.T) {
+		t.Parallel()
+
+		path := fixture(t, "track.flac")
+		want := tags(t)
+		require.NoError(t, tag.WriteFLAC(path, want))
+
+		got, err := tag.ReadFLAC(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, *got)
+	})
+
+	t.Run("rewrite_replaces_tags", func(t *testing.T) {
+		t.Parallel()
+
+		path := fixture(t, "track.flac")
+		require.NoError(t, tag.WriteFLAC(path, tags(t)))
+		want := tag.Tags{Title: "Other"} //nolint:exhaustruct
+		require.NoError(t, tag.WriteFLAC(path, want))
+
+		got, err := tag.ReadFLAC(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, *got)
+	})
+
+	t.Run("preserves_audio_frames", func(t *testing.T) {
+		t.Parallel()
+
+		path := fixture(t, "track.flac")
+		before, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, tag.WriteFLAC(path, tags(t)))
+
+		after, err := os.ReadFile(path)
+		require.NoError(t, err)
+		frame := before[len(before)-11:]
+		assert.True(t, bytes.HasSuffix(after, frame))
+		assert.Equal(t, before[:42], after[:42], "stream info block must be kept as is")
+	})
+
+	t.Run("converts_fragmented_mp4", func(t *testing.T) {
+		t.Parallel()
+
+		path := fixture(t, "track.mp4")
+		want := tags(t)
+		require.NoError(t, tag.WriteFLAC(path, want))
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("fLaC"), b[:4])
+
+		got, err := tag.ReadFLAC(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, *got)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "track.flac")
+		require.NoError(t, os.WriteFile(path, []byte("not a flac file"), 0o0600))
+		require.ErrorIs(t, tag.WriteFLAC(path, tags(t)), tag.ErrUnsupported)
+	})
+}
+
+func TestMP4(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round_trip", func(t *testing.T) {
+		t.Parallel()
+
+		path := fixture(t, "track.m4a")
+		want := tags(t)
+		require.NoError(t, tag.WriteMP4(path, want))
+
+		got, err := tag.ReadMP4(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, *got)
+	})
+
+	t.Run("rewrite_replaces_tags", func(t *testing.T) {
+		t.Parallel()
+
+		path := fixture(t, "track.m4a")
+		require.NoError(t, tag.WriteMP4(path, tags(t)))
+		want := tag.Tags{Title: "Other", TrackNumber: 1} //nolint:exhaustruct
+		require.NoError(t, tag.WriteMP4(path, want))
+
+		got, err := tag.ReadMP4(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, *got)
+	})
+
+	t.Run("shifts_chunk_offsets", func(t *testing.T) {
+		t.Parallel()
+
+		path := fixture(t, "track.m4a")
+		require.NoError(t, tag.WriteMP4(path, tags(t)))
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		i := bytes.Index(b, []byte("stco"))
+		require.Positive(t, i)
+		offset := int(b[i+12])<<24 | int(b[i+13])<<16 | int(b[i+14])<<8 | int(b[i+15])
+		require.Less(t, offset, len(b))
+		assert.True(t, bytes.HasPrefix(b[offset:], []byte("AACPAYLOADBYTES!")))
+		assert.True(t, bytes.Contains(b, []byte("keepkept")), "other user data boxes must be kept")
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		path := fixture(t, "track.flac")
+		require.ErrorIs(t, tag.WriteMP4(path, tags(t)), tag.ErrUnsupported)
+	})
+}
@@ -0,0 +1,38 @@
+package tgutil
+
+import (
+	"github.com/gotd/td/tg"
+)
+
+// SentMessageID extracts ID of the sent message from the updates returned by a
+// send message request.
+func SentMessageID(u tg.UpdatesClass) (int, bool) {
+	switch u := u.(type) {
+	case *tg.UpdateShortSentMessage:
+		return u.ID, true
+	case *tg.Updates:
+		return sentMessageIDFromUpdates(u.Updates)
+	case *tg.UpdatesCombined:
+		return sentMessageIDFromUpdates(u.Updates)
+	default:
+		return 0, false
+	}
+}
+
+func sentMessageIDFromUpdates(updates []tg.UpdateClass) (int, bool) {
+	for _, u := range updates {
+		switch u := u.(type) {
+		case *tg.UpdateMessageID:
+			return u.ID, true
+		case *tg.UpdateNewMessage:
+			if msg, ok := u.Message.(*tg.Message); ok {
+				return msg.ID, true
+			}
+		case *tg.UpdateNewChannelMessage:
+			if msg, ok := u.Message.(*tg.Message); ok {
+				return msg.ID, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/xeptore/tgtd/config"
+)
+
+type Album struct {
+	Artist struct {
+		Name string `json:"name"`
+	} `json:"artist"`
+	Title        string `json:"title"`
+	ReleaseDate  string `json:"releaseDate"`
+	CoverID      string `json:"cover"`
+	TotalTracks  int    `json:"numberOfTracks"`
+	TotalVolumes int    `json:"numberOfVolumes"`
+}
+
+func (c *Client) Album(ctx context.Context, id string) (*Album, error) {
+	r := request{
+		url:            baseURL + "/albums/" + id,
+		params:         c.params(0),
+		header:         nil,
+		timeout:        config.AlbumMetaRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	var a Album
+	if err := c.getJSON(ctx, r, &a); nil != err {
+		return nil, err
+	}
+	return &a, nil
+}
+
+type AlbumItem struct {
+	Type    string  `json:"type"`
+	Credits Credits `json:"credits"`
+	Item    Track   `json:"item"`
+}
+
+// AlbumItems returns all items of the album along with their credits, ordered
+// by volume, and track number.
+func (c *Client) AlbumItems(ctx context.Context, id string) ([]AlbumItem, error) {
+	r := request{
+		url:            baseURL + "/albums/" + id + "/items/credits",
+		params:         c.params(2),
+		header:         nil,
+		timeout:        config.GetPageTracksRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	return getAllPages[AlbumItem](ctx, c, r)
+}
+
+// ImageURL returns URL of the size x size JPEG variant of the image with the
+// given ID, e.g., an album cover, or an artist picture. Available sizes depend
+// on the kind of image, with 160, 320, and 640 being available for all.
+func ImageURL(id string, size int) string {
+	return fmt.Sprintf("%s/images/%s/%dx%d.jpg", resourcesBaseURL, strings.ReplaceAll(id, "-", "/"), size, size)
+}
+
+// Cover downloads the 1280x1280 JPEG cover image with the given ID.
+func (c *Client) Cover(ctx context.Context, id string) ([]byte, error) {
+	r := request{
+		url:            ImageURL(id, 1280),
+		params:         nil,
+		header:         nil,
+		timeout:        config.CoverDownloadTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	return c.get(ctx, r)
+}
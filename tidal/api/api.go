@@ -0,0 +1,323 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/httputil"
+	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/ratelimit"
+	"github.com/xeptore/tgtd/tidal"
+	"github.com/xeptore/tgtd/tidal/auth"
+)
+
+const (
+	baseURL          = "https://api.tidal.com/v1"
+	listenBaseURL    = "https://listen.tidal.com/v1"
+	resourcesBaseURL = "https://resources.tidal.com"
+	// PageSize is the number of items requested per page of paginated endpoints.
+	PageSize = 100
+)
+
+var (
+	ErrTooManyRequests = errors.New("too many requests")
+	// ErrNotFound is returned by methods documenting it if the resource does not exist.
+	ErrNotFound = errors.New("resource not found")
+	// ErrForbidden is returned by methods documenting it if access to the resource
+	// is denied for reasons other than an expired token, or rate limiting, e.g.,
+	// due to account subscription limits.
+	ErrForbidden = errors.New("access to resource is forbidden")
+)
+
+// transport is shared among all clients so connections to TIDAL hosts are reused
+// across jobs.
+var transport = http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+
+type TokenSource interface {
+	AccessToken(ctx context.Context) (string, error)
+}
+
+// Client sends requests to TIDAL API on behalf of the account owning tokens.
+type Client struct {
+	http   *http.Client
+	tokens TokenSource
+	region tidal.Region
+}
+
+func NewClient(tokens TokenSource, region tidal.Region) *Client {
+	return &Client{
+		http:   &http.Client{Transport: transport}, //nolint:exhaustruct
+		tokens: tokens,
+		region: region,
+	}
+}
+
+func (c *Client) Region() tidal.Region {
+	return c.region
+}
+
+type request struct {
+	url     string
+	params  url.Values
+	header  http.Header
+	timeout time.Duration
+	// allowNotFound makes 404 responses return ErrNotFound instead of a flaw.
+	allowNotFound bool
+	// allowForbidden makes 401, and 403 responses not caused by an invalid token,
+	// or rate limiting return ErrForbidden instead of a flaw.
+	allowForbidden bool
+}
+
+func (r request) flawP() flaw.P {
+	return flaw.P{"url": r.url, "encoded_query_params": r.params.Encode()}
+}
+
+// get sends r and returns the response body. Besides flaws, it returns context
+// errors, auth.ErrUnauthorized, and ErrTooManyRequests, as well as ErrNotFound,
+// and ErrForbidden if allowed by r.
+func (c *Client) get(ctx context.Context, r request) (b []byte, err error) {
+	flawP := r.flawP()
+
+	accessToken, err := c.tokens.AccessToken(ctx)
+	if nil != err {
+		return nil, err
+	}
+
+	reqURL, err := url.Parse(r.url)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to parse request URL: %v", err)).Append(flawP)
+	}
+	reqURL.RawQuery = r.params.Encode()
+
+	if err := ratelimit.TIDAL.Wait(ctx); nil != err {
+		return nil, err
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, reqURL.String(), nil)
+	if nil != err {
+		if errutil.IsContext(ctx) {
+			return nil, ctx.Err()
+		}
+
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to create request: %v", err)).Append(flawP)
+	}
+	for k, vs := range r.header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := c.http.Do(req)
+	if nil != err {
+		switch {
+		case errutil.IsContext(ctx):
+			return nil, ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, context.DeadlineExceeded
+		default:
+			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+			return nil, flaw.From(fmt.Errorf("failed to send request: %v", err)).Append(flawP)
+		}
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close response body: %v", closeErr)).Append(flawP)
+			switch {
+			case nil == err:
+				err = closeErr
+			case errutil.IsContext(ctx):
+				err = flaw.From(errors.New("context was ended")).Join(closeErr)
+			case errors.Is(err, context.DeadlineExceeded):
+				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
+			case errors.Is(err, auth.ErrUnauthorized):
+				err = flaw.From(errors.New("unauthorized")).Join(closeErr)
+			case errors.Is(err, ErrTooManyRequests):
+				err = flaw.From(errors.New("too many requests")).Join(closeErr)
+			case errors.Is(err, ErrNotFound):
+				err = flaw.From(errors.New("resource not found")).Join(closeErr)
+			case errors.Is(err, ErrForbidden):
+				err = flaw.From(errors.New("access to resource is forbidden")).Join(closeErr)
+			case errutil.IsFlaw(err):
+				err = must.BeFlaw(err).Join(closeErr)
+			default:
+				panic(errutil.UnknownError(err))
+			}
+		}
+	}()
+	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
+
+	if err := checkStatus(ctx, reqCtx, resp, http.StatusOK, r.allowNotFound, r.allowForbidden, flawP); nil != err {
+		return nil, err
+	}
+
+	respBytes, err := httputil.ReadResponseBody(reqCtx, resp)
+	if nil != err {
+		return nil, requestError(ctx, err)
+	}
+	return respBytes, nil
+}
+
+// checkStatus returns nil if resp has the expected status. Otherwise, besides
+// flaws, it returns auth.ErrUnauthorized, and ErrTooManyRequests, as well as
+// ErrNotFound, and ErrForbidden if allowed. The response body is read with
+// reqCtx, and errors of reading it are mapped to those of ctx.
+func checkStatus(ctx, reqCtx context.Context, resp *http.Response, expected int, allowNotFound, allowForbidden bool, flawP flaw.P) error {
+	switch code := resp.StatusCode; code {
+	case expected:
+		ratelimit.TIDAL.Succeeded()
+		return nil
+	case http.StatusNotFound:
+		if allowNotFound {
+			return ErrNotFound
+		}
+		respBytes, err := httputil.ReadOptionalResponseBody(reqCtx, resp)
+		if nil != err {
+			return requestError(ctx, err)
+		}
+		flawP["response_body"] = string(respBytes)
+		return flaw.From(errors.New("received 404 response")).Append(flawP)
+	case http.StatusUnauthorized:
+		respBytes, err := httputil.ReadResponseBody(reqCtx, resp)
+		if nil != err {
+			return requestError(ctx, err)
+		}
+
+		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
+			return must.BeFlaw(err).Append(flawP)
+		} else if ok {
+			return auth.ErrUnauthorized
+		}
+
+		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
+			return must.BeFlaw(err).Append(flawP)
+		} else if ok {
+			return auth.ErrUnauthorized
+		}
+
+		if allowForbidden {
+			return ErrForbidden
+		}
+		flawP["response_body"] = string(respBytes)
+		return flaw.From(errors.New("received 401 response")).Append(flawP)
+	case http.StatusTooManyRequests:
+		ratelimit.TIDAL.Throttled(httputil.RetryAfter(resp))
+		return ErrTooManyRequests
+	case http.StatusForbidden:
+		respBytes, err := httputil.ReadResponseBody(reqCtx, resp)
+		if nil != err {
+			return requestError(ctx, err)
+		}
+		if ok, err := errutil.IsTooManyErrorResponse(resp, respBytes); nil != err {
+			flawP["response_body"] = string(respBytes)
+			return must.BeFlaw(err).Append(flawP)
+		} else if ok {
+			ratelimit.TIDAL.Throttled(httputil.RetryAfter(resp))
+			return ErrTooManyRequests
+		}
+
+		if allowForbidden {
+			return ErrForbidden
+		}
+		flawP["response_body"] = string(respBytes)
+		return flaw.From(errors.New("unexpected 403 response")).Append(flawP)
+	default:
+		respBytes, err := httputil.ReadOptionalResponseBody(reqCtx, resp)
+		if nil != err {
+			return requestError(ctx, err)
+		}
+		flawP["response_body"] = string(respBytes)
+		return flaw.From(fmt.Errorf("unexpected status code: %d", code)).Append(flawP)
+	}
+}
+
+// requestError maps errors of reading response bodies with the request timeout
+// context to errors of the parent context ctx.
+func requestError(ctx context.Context, err error) error {
+	switch {
+	case errutil.IsContext(ctx):
+		return ctx.Err()
+	case errors.Is(err, context.DeadlineExceeded):
+		return context.DeadlineExceeded
+	case errutil.IsFlaw(err):
+		return err
+	default:
+		panic(errutil.UnknownError(err))
+	}
+}
+
+// getJSON sends r and decodes the response body into out.
+func (c *Client) getJSON(ctx context.Context, r request, out any) error {
+	respBytes, err := c.get(ctx, r)
+	if nil != err {
+		return err
+	}
+
+	if err := json.Unmarshal(respBytes, out); nil != err {
+		flawP := r.flawP()
+		flawP["response_body"] = string(respBytes)
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to decode response body: %v", err)).Append(flawP)
+	}
+	return nil
+}
+
+type page[T any] struct {
+	TotalNumberOfItems int `json:"totalNumberOfItems"`
+	Items              []T `json:"items"`
+}
+
+// getAllPages sends r for every page of a paginated endpoint, and returns items
+// of all pages.
+func getAllPages[T any](ctx context.Context, c *Client, r request) ([]T, error) {
+	var items []T
+	for offset := 0; ; {
+		pageReq := r
+		pageReq.params = cloneParams(r.params)
+		pageReq.params.Set("limit", strconv.Itoa(PageSize))
+		pageReq.params.Set("offset", strconv.Itoa(offset))
+
+		var p page[T]
+		if err := c.getJSON(ctx, pageReq, &p); nil != err {
+			if errutil.IsFlaw(err) {
+				return nil, must.BeFlaw(err).Append(flaw.P{"offset": offset})
+			}
+			return nil, err
+		}
+		items = append(items, p.Items...)
+		offset += len(p.Items)
+
+		if len(p.Items) == 0 || offset >= p.TotalNumberOfItems {
+			return items, nil
+		}
+	}
+}
+
+func cloneParams(params url.Values) url.Values {
+	out := make(url.Values, len(params)+2)
+	for k, v := range params {
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
+
+func (c *Client) params(n int) url.Values {
+	params := make(url.Values, n+1)
+	params.Add("countryCode", c.region.CountryCode)
+	return params
+}
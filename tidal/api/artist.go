@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+
+	"github.com/xeptore/tgtd/config"
+)
+
+const (
+	ArtistAlbumsFilterAlbums        = "ALBUMS"
+	ArtistAlbumsFilterEPsAndSingles = "EPSANDSINGLES"
+	ArtistAlbumsFilterCompilations  = "COMPILATIONS"
+)
+
+type ArtistAlbum struct {
+	ID          int      `json:"id"`
+	Title       string   `json:"title"`
+	ReleaseDate string   `json:"releaseDate"`
+	StreamReady bool     `json:"streamReady"`
+	Artists     []Artist `json:"artists"`
+}
+
+// ArtistAlbums returns releases of the artist matching filter, which is one of
+// the ArtistAlbumsFilter constants.
+func (c *Client) ArtistAlbums(ctx context.Context, id, filter string) ([]ArtistAlbum, error) {
+	params := c.params(3)
+	params.Add("filter", filter)
+	r := request{
+		url:            baseURL + "/artists/" + id + "/albums",
+		params:         params,
+		header:         nil,
+		timeout:        config.GetPageTracksRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	return getAllPages[ArtistAlbum](ctx, c, r)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/config"
+)
+
+// ItemTypeTrack is the type of list items that are tracks, as opposed to, e.g., videos.
+const ItemTypeTrack = "track"
+
+type Playlist struct {
+	Title       string `json:"title"`
+	Created     string `json:"created"`
+	LastUpdated string `json:"lastUpdated"`
+}
+
+// PlaylistDateLayout is the layout of playlist created, and last updated dates.
+const PlaylistDateLayout = "2006-01-02T15:04:05.000-0700"
+
+func (c *Client) Playlist(ctx context.Context, id string) (*Playlist, error) {
+	r := request{
+		url:            baseURL + "/playlists/" + id,
+		params:         c.params(0),
+		header:         nil,
+		timeout:        config.PlaylistMetaRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	var p Playlist
+	if err := c.getJSON(ctx, r, &p); nil != err {
+		return nil, err
+	}
+	return &p, nil
+}
+
+type ListItem struct {
+	Type string `json:"type"`
+	// Cut is only set for playlist items that are trimmed by the playlist creator.
+	Cut *struct {
+		Start float64  `json:"start"`
+		End   *float64 `json:"end"`
+	} `json:"cut"`
+	Item Track `json:"item"`
+}
+
+func (c *Client) PlaylistItems(ctx context.Context, id string) ([]ListItem, error) {
+	r := request{
+		url:            baseURL + "/playlists/" + id + "/items",
+		params:         c.params(2),
+		header:         nil,
+		timeout:        config.GetPageTracksRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	return getAllPages[ListItem](ctx, c, r)
+}
+
+type Mix struct {
+	Title string
+}
+
+func (c *Client) Mix(ctx context.Context, id string) (*Mix, error) {
+	params := c.params(3)
+	params.Add("mixId", id)
+	params.Add("locale", c.region.Locale)
+	params.Add("deviceType", "BROWSER")
+	r := request{
+		url:    listenBaseURL + "/pages/mix",
+		params: params,
+		header: http.Header{
+			"User-Agent": {"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:132.0) Gecko/20100101 Firefox/132.0"},
+			"Accept":     {"application/json"},
+		},
+		timeout:        config.MixMetaRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	respBytes, err := c.get(ctx, r)
+	if nil != err {
+		return nil, err
+	}
+
+	flawP := r.flawP()
+	if !gjson.ValidBytes(respBytes) {
+		flawP["response_body"] = string(respBytes)
+		return nil, flaw.From(errors.New("invalid mix info response json")).Append(flawP)
+	}
+
+	switch titleKey := gjson.GetBytes(respBytes, "title"); titleKey.Type { //nolint:exhaustive
+	case gjson.String:
+		return &Mix{Title: titleKey.Str}, nil
+	default:
+		flawP["response_body"] = string(respBytes)
+		return nil, flaw.From(errors.New("unexpected mix info response")).Append(flawP)
+	}
+}
+
+func (c *Client) MixItems(ctx context.Context, id string) ([]ListItem, error) {
+	r := request{
+		url:            baseURL + "/mixes/" + id + "/items",
+		params:         c.params(2),
+		header:         nil,
+		timeout:        config.GetPageTracksRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	return getAllPages[ListItem](ctx, c, r)
+}
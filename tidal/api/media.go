@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/ratelimit"
+	"github.com/xeptore/tgtd/tidal/auth"
+)
+
+// MediaRequest is a request to a TIDAL CDN URL, e.g., of a track segment, which
+// is authorized the same way as API requests.
+type MediaRequest struct {
+	Method  string
+	URL     string
+	Header  http.Header
+	Timeout time.Duration
+	// Status is the status of successful responses, e.g., 206 for range requests.
+	Status int
+}
+
+// SendMedia sends r with accessToken through the transport shared with API
+// clients, and returns the response if it has the expected status, in which
+// case the caller must close its body. Otherwise, besides flaws, it returns
+// context errors, auth.ErrUnauthorized, and ErrTooManyRequests.
+func SendMedia(ctx context.Context, accessToken string, r MediaRequest) (_ *http.Response, err error) {
+	flawP := flaw.P{"url": r.URL, "method": r.Method}
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, nil)
+	if nil != err {
+		if errutil.IsContext(ctx) {
+			return nil, ctx.Err()
+		}
+
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to create media request: %v", err)).Append(flawP)
+	}
+	for k, vs := range r.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	if err := ratelimit.TIDAL.Wait(ctx); nil != err {
+		return nil, err
+	}
+
+	client := http.Client{Transport: transport, Timeout: r.Timeout} //nolint:exhaustruct
+	resp, err := client.Do(req)
+	if nil != err {
+		switch {
+		case errutil.IsContext(ctx):
+			return nil, ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, context.DeadlineExceeded
+		default:
+			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+			return nil, flaw.From(fmt.Errorf("failed to send media request: %v", err)).Append(flawP)
+		}
+	}
+	defer func() {
+		if nil == err {
+			return
+		}
+		if closeErr := resp.Body.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close media response body: %v", closeErr)).Append(flawP)
+			switch {
+			case errutil.IsContext(ctx):
+				err = flaw.From(errors.New("context was ended")).Join(closeErr)
+			case errors.Is(err, context.DeadlineExceeded):
+				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
+			case errors.Is(err, auth.ErrUnauthorized):
+				err = flaw.From(errors.New("unauthorized")).Join(closeErr)
+			case errors.Is(err, ErrTooManyRequests):
+				err = flaw.From(errors.New("too many requests")).Join(closeErr)
+			case errutil.IsFlaw(err):
+				err = must.BeFlaw(err).Join(closeErr)
+			default:
+				panic(errutil.UnknownError(err))
+			}
+		}
+	}()
+	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
+
+	if err := checkStatus(ctx, ctx, resp, r.Status, false, false, flawP); nil != err {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/xeptore/tgtd/config"
+)
+
+type SearchArtist struct {
+	ID      int     `json:"id"`
+	Name    string  `json:"name"`
+	Picture *string `json:"picture"`
+}
+
+type SearchAlbum struct {
+	ID          int      `json:"id"`
+	Title       string   `json:"title"`
+	CoverID     *string  `json:"cover"`
+	ReleaseDate string   `json:"releaseDate"`
+	TotalTracks int      `json:"numberOfTracks"`
+	Artists     []Artist `json:"artists"`
+}
+
+type SearchPlaylist struct {
+	UUID        string  `json:"uuid"`
+	Title       string  `json:"title"`
+	SquareImage *string `json:"squareImage"`
+	TotalTracks int     `json:"numberOfTracks"`
+}
+
+// SearchResult holds results of each type ordered by relevance.
+type SearchResult struct {
+	Artists   page[SearchArtist]   `json:"artists"`
+	Albums    page[SearchAlbum]    `json:"albums"`
+	Playlists page[SearchPlaylist] `json:"playlists"`
+	Tracks    page[Track]          `json:"tracks"`
+}
+
+// Search returns at most limit artists, albums, playlists, and tracks each that
+// match query.
+func (c *Client) Search(ctx context.Context, query string, limit int) (*SearchResult, error) {
+	params := c.params(4)
+	params.Add("query", query)
+	params.Add("limit", strconv.Itoa(limit))
+	params.Add("offset", "0")
+	params.Add("types", "ARTISTS,ALBUMS,PLAYLISTS,TRACKS")
+	r := request{
+		url:            baseURL + "/search",
+		params:         params,
+		header:         nil,
+		timeout:        config.SearchRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	var res SearchResult
+	if err := c.getJSON(ctx, r, &res); nil != err {
+		return nil, err
+	}
+	return &res, nil
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/tidwall/gjson"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/config"
+	"github.com/xeptore/tgtd/tidal"
+)
+
+type Artist struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TrackArtists converts artists to track artists, and fails on artist types
+// other than main, and featured.
+func TrackArtists(artists []Artist) ([]tidal.TrackArtist, error) {
+	out := make([]tidal.TrackArtist, len(artists))
+	for i, a := range artists {
+		switch a.Type {
+		case tidal.ArtistTypeMain, tidal.ArtistTypeFeatured:
+		default:
+			return nil, flaw.From(fmt.Errorf("unexpected artist type: %s", a.Type))
+		}
+		out[i] = tidal.TrackArtist{Name: a.Name, Type: a.Type}
+	}
+	return out, nil
+}
+
+type Track struct {
+	ID           int    `json:"id"`
+	StreamReady  bool   `json:"streamReady"`
+	TrackNumber  int    `json:"trackNumber"`
+	VolumeNumber int    `json:"volumeNumber"`
+	Title        string `json:"title"`
+	ISRC         string `json:"isrc"`
+	Copyright    string `json:"copyright"`
+	Duration     int    `json:"duration"`
+	Artist       struct {
+		Name string `json:"name"`
+	} `json:"artist"`
+	Artists []Artist `json:"artists"`
+	Album   struct {
+		ID      int    `json:"id"`
+		CoverID string `json:"cover"`
+		Title   string `json:"title"`
+	} `json:"album"`
+	Version *string `json:"version"`
+}
+
+func (c *Client) Track(ctx context.Context, id string) (*Track, error) {
+	r := request{
+		url:            baseURL + "/tracks/" + id,
+		params:         c.params(0),
+		header:         nil,
+		timeout:        config.TrackMetaRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	var t Track
+	if err := c.getJSON(ctx, r, &t); nil != err {
+		return nil, err
+	}
+	return &t, nil
+}
+
+type Credits []struct {
+	Type         string `json:"type"`
+	Contributors []struct {
+		Name string `json:"name"`
+	} `json:"contributors"`
+}
+
+func (c Credits) TrackCredits() tidal.TrackCredits {
+	var out tidal.TrackCredits
+	for _, v := range c {
+		switch v.Type {
+		case "Producer":
+			for _, v := range v.Contributors {
+				out.Producers = append(out.Producers, v.Name)
+			}
+		case "Composer":
+			for _, v := range v.Contributors {
+				out.Composers = append(out.Composers, v.Name)
+			}
+		case "Lyricist":
+			for _, v := range v.Contributors {
+				out.Lyricists = append(out.Lyricists, v.Name)
+			}
+		case "Additional Producer":
+			for _, v := range v.Contributors {
+				out.AdditionalProducers = append(out.AdditionalProducers, v.Name)
+			}
+		}
+	}
+	return out
+}
+
+func (c *Client) TrackCredits(ctx context.Context, id string) (Credits, error) {
+	params := c.params(1)
+	params.Add("includeContributors", "true")
+	r := request{
+		url:            baseURL + "/tracks/" + id + "/credits",
+		params:         params,
+		header:         nil,
+		timeout:        config.GetTrackCreditsRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	var credits Credits
+	if err := c.getJSON(ctx, r, &credits); nil != err {
+		return nil, err
+	}
+	return credits, nil
+}
+
+// TrackLyrics returns lyrics of the track, preferring time-synced ones. It
+// returns ErrNotFound if the track has no lyrics.
+func (c *Client) TrackLyrics(ctx context.Context, id string) (string, error) {
+	params := c.params(1)
+	params.Add("includeContributors", "true")
+	r := request{
+		url:            baseURL + "/tracks/" + id + "/lyrics",
+		params:         params,
+		header:         nil,
+		timeout:        config.GetTrackLyricsRequestTimeout,
+		allowNotFound:  true,
+		allowForbidden: false,
+	}
+	respBytes, err := c.get(ctx, r)
+	if nil != err {
+		return "", err
+	}
+
+	flawP := r.flawP()
+	if !gjson.ValidBytes(respBytes) {
+		flawP["response_body"] = string(respBytes)
+		return "", flaw.From(errors.New("invalid track lyrics response json")).Append(flawP)
+	}
+
+	if lyricsKey := gjson.GetBytes(respBytes, "subtitles"); lyricsKey.Type == gjson.String {
+		return lyricsKey.Str, nil
+	} else if lyricsKey := gjson.GetBytes(respBytes, "lyrics"); lyricsKey.Type == gjson.String {
+		return lyricsKey.Str, nil
+	}
+	flawP["response_body"] = string(respBytes)
+	return "", flaw.From(errors.New("unexpected track lyrics response")).Append(flawP)
+}
+
+type PlaybackInfo struct {
+	AudioQuality     tidal.Quality `json:"audioQuality"`
+	AudioMode        string        `json:"audioMode"`
+	ManifestMimeType string        `json:"manifestMimeType"`
+	Manifest         string        `json:"manifest"`
+}
+
+// PlaybackInfo returns stream manifest of the track in the given quality. If
+// immersive is set, Dolby Atmos stream is requested instead of the stereo one.
+// It returns ErrForbidden if the track cannot be streamed in the quality, e.g.,
+// due to account subscription limits.
+func (c *Client) PlaybackInfo(ctx context.Context, id string, quality tidal.Quality, immersive bool) (*PlaybackInfo, error) {
+	params := c.params(5)
+	params.Add("audioquality", string(quality))
+	params.Add("playbackmode", "STREAM")
+	params.Add("assetpresentation", "FULL")
+	params.Add("immersiveaudio", strconv.FormatBool(immersive))
+	params.Add("locale", c.region.Locale)
+	r := request{
+		url:            baseURL + "/tracks/" + id + "/playbackinfo",
+		params:         params,
+		header:         nil,
+		timeout:        config.GetStreamURLsRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: true,
+	}
+	var info PlaybackInfo
+	if err := c.getJSON(ctx, r, &info); nil != err {
+		return nil, err
+	}
+	return &info, nil
+}
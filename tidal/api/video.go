@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+
+	"github.com/xeptore/tgtd/config"
+)
+
+type Video struct {
+	Title       string   `json:"title"`
+	Version     *string  `json:"version"`
+	Duration    int      `json:"duration"`
+	ReleaseDate string   `json:"releaseDate"`
+	Artists     []Artist `json:"artists"`
+}
+
+func (c *Client) Video(ctx context.Context, id string) (*Video, error) {
+	r := request{
+		url:            baseURL + "/videos/" + id,
+		params:         c.params(0),
+		header:         nil,
+		timeout:        config.VideoMetaRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	var v Video
+	if err := c.getJSON(ctx, r, &v); nil != err {
+		return nil, err
+	}
+	return &v, nil
+}
+
+type VideoPlaybackInfo struct {
+	ManifestMimeType string `json:"manifestMimeType"`
+	Manifest         string `json:"manifest"`
+}
+
+func (c *Client) VideoPlaybackInfo(ctx context.Context, id string) (*VideoPlaybackInfo, error) {
+	params := c.params(4)
+	params.Add("videoquality", "HIGH")
+	params.Add("playbackmode", "STREAM")
+	params.Add("assetpresentation", "FULL")
+	params.Add("locale", c.region.Locale)
+	r := request{
+		url:            baseURL + "/videos/" + id + "/playbackinfo",
+		params:         params,
+		header:         nil,
+		timeout:        config.VideoMetaRequestTimeout,
+		allowNotFound:  false,
+		allowForbidden: false,
+	}
+	var info VideoPlaybackInfo
+	if err := c.getJSON(ctx, r, &info); nil != err {
+		return nil, err
+	}
+	return &info, nil
+}
@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/goccy/go-json"
@@ -48,11 +49,27 @@ func credentialsFromTokenFileContent(content tidalfs.AuthTokenFileContent) Crede
 }
 
 type Auth struct {
-	file  tidalfs.AuthTokenFile
-	creds Credentials
+	// mux guards creds, which are refreshed by concurrent API requests, and
+	// countryCode, which is updated while jobs read it.
+	mux         sync.Mutex
+	file        tidalfs.AuthTokenFile
+	creds       Credentials
+	countryCode string
+}
+
+// CountryCode returns the country code of the account session as reported by
+// the last successful VerifyAccessToken call, or an empty string if it has not
+// been called yet.
+func (a *Auth) CountryCode() string {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	return a.countryCode
 }
 
 func (a *Auth) AccessToken(ctx context.Context) (string, error) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
 	if time.Now().After(time.Unix(a.creds.ExpiresAt, 0)) {
 		creds, err := handleUnauthorized(ctx, a.creds.RefreshToken, a.file)
 		if nil != err {
@@ -66,6 +83,9 @@ func (a *Auth) AccessToken(ctx context.Context) (string, error) {
 }
 
 func (a *Auth) RefreshToken(ctx context.Context) error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
 	creds, err := handleUnauthorized(ctx, a.creds.RefreshToken, a.file)
 	if nil != err {
 		return err
@@ -81,8 +101,10 @@ func LoadFromDir(ctx context.Context, credsDir string) (*Auth, error) {
 		return nil, err
 	}
 	return &Auth{
-		file:  tokenFile,
-		creds: *creds,
+		mux:         sync.Mutex{},
+		file:        tokenFile,
+		creds:       *creds,
+		countryCode: "",
 	}, nil
 }
 
@@ -99,7 +121,7 @@ func load(ctx context.Context, tokenFile tidalfs.AuthTokenFile) (creds *Credenti
 		return handleUnauthorized(ctx, content.RefreshToken, tokenFile)
 	}
 
-	if err := verifyAccessToken(ctx, content.AccessToken); nil != err {
+	if _, err := verifyAccessToken(ctx, content.AccessToken); nil != err {
 		switch {
 		case errutil.IsContext(ctx):
 			return nil, ctx.Err()
@@ -291,18 +313,32 @@ func extractExpiresAt(accessToken string) (int64, error) {
 	return obj.ExpiresAt, nil
 }
 
+// VerifyAccessToken verifies the access token is still valid, and stores the
+// session country code reported by TIDAL.
 func (a *Auth) VerifyAccessToken(ctx context.Context) error {
-	return verifyAccessToken(ctx, a.creds.AccessToken)
+	a.mux.Lock()
+	accessToken := a.creds.AccessToken
+	a.mux.Unlock()
+
+	countryCode, err := verifyAccessToken(ctx, accessToken)
+	if nil != err {
+		return err
+	}
+
+	a.mux.Lock()
+	a.countryCode = countryCode
+	a.mux.Unlock()
+	return nil
 }
 
-func verifyAccessToken(ctx context.Context, accessToken string) (err error) {
+func verifyAccessToken(ctx context.Context, accessToken string) (countryCode string, err error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.tidal.com/v1/sessions", nil)
 	if nil != err {
 		if errutil.IsContext(ctx) {
-			return ctx.Err()
+			return "", ctx.Err()
 		}
 		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-		return flaw.From(fmt.Errorf("failed to create verify access token request: %v", err)).Append(flawP)
+		return "", flaw.From(fmt.Errorf("failed to create verify access token request: %v", err)).Append(flawP)
 	}
 	req.Header.Add("Authorization", "Bearer "+accessToken)
 
@@ -311,12 +347,12 @@ func verifyAccessToken(ctx context.Context, accessToken string) (err error) {
 	if nil != err {
 		switch {
 		case errutil.IsContext(ctx):
-			return ctx.Err()
+			return "", ctx.Err()
 		case errors.Is(err, context.DeadlineExceeded):
-			return context.DeadlineExceeded
+			return "", context.DeadlineExceeded
 		default:
 			flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-			return flaw.From(fmt.Errorf("failed to issue verify access token request: %v", err)).Append(flawP)
+			return "", flaw.From(fmt.Errorf("failed to issue verify access token request: %v", err)).Append(flawP)
 		}
 	}
 	defer func() {
@@ -341,35 +377,50 @@ func verifyAccessToken(ctx context.Context, accessToken string) (err error) {
 
 	switch code := resp.StatusCode; code {
 	case http.StatusOK:
-		return nil
 	case http.StatusUnauthorized:
 		respBytes, err := httputil.ReadResponseBody(ctx, resp)
 		if nil != err {
-			return err
+			return "", err
 		}
 
 		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return err
+			return "", err
 		} else if ok {
-			return ErrUnauthorized
+			return "", ErrUnauthorized
 		}
 
 		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return err
+			return "", err
 		} else if ok {
-			return ErrUnauthorized
+			return "", ErrUnauthorized
 		}
 
 		flawP["response_body"] = string(respBytes)
-		return flaw.From(errors.New("received 401 response")).Append(flawP)
+		return "", flaw.From(errors.New("received 401 response")).Append(flawP)
 	default:
 		respBytes, err := httputil.ReadOptionalResponseBody(ctx, resp)
 		if nil != err {
-			return err
+			return "", err
 		}
 		flawP["response_body"] = string(respBytes)
-		return flaw.From(fmt.Errorf("unexpected status code: %d", code)).Append(flawP)
+		return "", flaw.From(fmt.Errorf("unexpected status code: %d", code)).Append(flawP)
 	}
+
+	respBytes, err := httputil.ReadResponseBody(ctx, resp)
+	if nil != err {
+		return "", err
+	}
+
+	var respBody struct {
+		CountryCode string `json:"countryCode"`
+	}
+	if err := json.Unmarshal(respBytes, &respBody); nil != err {
+		flawP["response_body"] = string(respBytes)
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return "", flaw.From(fmt.Errorf("failed to decode sessions response body: %v", err)).Append(flawP)
+	}
+
+	return respBody.CountryCode, nil
 }
 
 type authorizationResponse struct {
@@ -443,8 +494,10 @@ func NewAuthorizer(ctx context.Context, credsDir string) (link *AuthorizationRes
 					return
 				}
 				done <- result.Ok(&Auth{
-					file:  tokenFile,
-					creds: *creds,
+					mux:         sync.Mutex{},
+					file:        tokenFile,
+					creds:       *creds,
+					countryCode: "",
 				})
 				return
 			}
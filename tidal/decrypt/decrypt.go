@@ -0,0 +1,73 @@
+package decrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+)
+
+// masterKey is the well-known key TIDAL uses to encrypt OLD_AES security tokens.
+const masterKey = "UIlTTEMmmLfGowo/UC60x2H45W6MdGgTRfo/umg4754="
+
+// Key holds the AES-CTR key and nonce a stream is encrypted with.
+type Key struct {
+	Key   []byte
+	Nonce []byte
+}
+
+// OldAESKey decrypts the base64-encoded key ID of an OLD_AES encrypted manifest.
+// The key ID is an AES-CBC encrypted security token prefixed with its IV, which
+// decrypts to a 16 bytes stream key followed by an 8 bytes nonce.
+func OldAESKey(keyID string) (*Key, error) {
+	master, err := base64.StdEncoding.DecodeString(masterKey)
+	if nil != err {
+		panic(fmt.Sprintf("invalid master key: %v", err))
+	}
+
+	token, err := base64.StdEncoding.DecodeString(keyID)
+	if nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return nil, flaw.From(fmt.Errorf("failed to decode key ID: %v", err)).Append(flawP)
+	}
+	flawP := flaw.P{"token_length": len(token)}
+	if len(token) < 2*aes.BlockSize || len(token)%aes.BlockSize != 0 {
+		return nil, flaw.From(errors.New("invalid security token length")).Append(flawP)
+	}
+
+	block, err := aes.NewCipher(master)
+	if nil != err {
+		panic(fmt.Sprintf("failed to create master key cipher: %v", err))
+	}
+
+	iv, encrypted := token[:aes.BlockSize], token[aes.BlockSize:]
+	decrypted := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, encrypted)
+
+	return &Key{
+		Key:   decrypted[:16],
+		Nonce: decrypted[16:24],
+	}, nil
+}
+
+// Writer returns a writer that decrypts everything written to it before writing
+// to w. It expects the stream to be written in order from its very beginning.
+func (k *Key) Writer(w io.Writer) (io.Writer, error) {
+	block, err := aes.NewCipher(k.Key)
+	if nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return nil, flaw.From(fmt.Errorf("failed to create stream cipher: %v", err)).Append(flawP)
+	}
+
+	// The counter block is the nonce followed by a 64 bits big-endian counter starting at zero.
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, k.Nonce)
+
+	return &cipher.StreamWriter{S: cipher.NewCTR(block, iv), W: w, Err: nil}, nil
+}
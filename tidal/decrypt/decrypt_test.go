@@ -0,0 +1,76 @@
+package decrypt_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/tidal/decrypt"
+)
+
+func encryptKeyID(t *testing.T, key, nonce []byte) string {
+	t.Helper()
+
+	master, err := base64.StdEncoding.DecodeString("UIlTTEMmmLfGowo/UC60x2H45W6MdGgTRfo/umg4754=")
+	require.NoError(t, err)
+	block, err := aes.NewCipher(master)
+	require.NoError(t, err)
+
+	iv := bytes.Repeat([]byte{7}, aes.BlockSize)
+	plain := make([]byte, 2*aes.BlockSize)
+	copy(plain, key)
+	copy(plain[16:], nonce)
+
+	encrypted := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, plain)
+	return base64.StdEncoding.EncodeToString(append(iv, encrypted...))
+}
+
+func TestOldAESKey(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+
+		key := []byte("0123456789abcdef")
+		nonce := []byte("noncenon")
+		k, err := decrypt.OldAESKey(encryptKeyID(t, key, nonce))
+		require.NoError(t, err)
+		assert.Exactly(t, key, k.Key)
+		assert.Exactly(t, nonce, k.Nonce)
+
+		block, err := aes.NewCipher(key)
+		require.NoError(t, err)
+		iv := make([]byte, aes.BlockSize)
+		copy(iv, nonce)
+
+		plain := bytes.Repeat([]byte("some audio bytes"), 100)
+		encrypted := make([]byte, len(plain))
+		cipher.NewCTR(block, iv).XORKeyStream(encrypted, plain)
+
+		var out bytes.Buffer
+		w, err := k.Writer(&out)
+		require.NoError(t, err)
+		// Write in uneven chunks, as parts are merged one after another.
+		_, err = w.Write(encrypted[:333])
+		require.NoError(t, err)
+		_, err = w.Write(encrypted[333:])
+		require.NoError(t, err)
+		assert.Exactly(t, plain, out.Bytes())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := decrypt.OldAESKey("not base64!")
+		require.Error(t, err)
+
+		_, err = decrypt.OldAESKey(base64.StdEncoding.EncodeToString([]byte("short")))
+		require.Error(t, err)
+	})
+}
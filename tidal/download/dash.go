@@ -19,25 +19,48 @@ import (
 	"github.com/xeptore/tgtd/httputil"
 	"github.com/xeptore/tgtd/mathutil"
 	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/progress"
+	"github.com/xeptore/tgtd/tidal"
+	"github.com/xeptore/tgtd/tidal/api"
 	"github.com/xeptore/tgtd/tidal/auth"
 	"github.com/xeptore/tgtd/tidal/mpd"
 )
 
 type DashTrackStream struct {
 	Info mpd.StreamInfo
+	// Quality is the audio quality the stream is delivered in.
+	Quality tidal.Quality
 }
 
-func (d *DashTrackStream) saveTo(ctx context.Context, accessToken string, fileName string) (err error) {
+// resumeKey identifies the stream in parts manifests, so that parts of streams
+// of other qualities, or bitrates are never reused, even if they have the same
+// number of parts.
+func (d *DashTrackStream) resumeKey() string {
+	return fmt.Sprintf("dash:%s:%s:%s:%d:%d", d.Quality, d.Info.MimeType, d.Info.Codec, d.Info.Bandwidth, d.Info.Parts.Count)
+}
+
+func (d *DashTrackStream) saveTo(ctx context.Context, accessToken string, fileName string, tracker *progress.Tracker) (err error) {
 	var (
 		numBatches = mathutil.CeilInts(d.Info.Parts.Count, maxBatchParts)
 		flawP      = flaw.P{"num_batches": numBatches}
 		wg, wgCtx  = errgroup.WithContext(ctx)
 	)
 
+	manifest, err := loadPartsManifest(fileName, d.resumeKey())
+	if nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	}
+
 	wg.SetLimit(numBatches)
 	for i := range numBatches {
 		wg.Go(func() error {
-			if err := d.downloadBatch(wgCtx, accessToken, fileName, i); nil != err {
+			partFileName := fileName + ".part." + strconv.Itoa(i)
+			if size, ok := manifest.completed(i, partFileName); ok {
+				tracker.AddDownloadedBytes(size)
+				return nil
+			}
+
+			if err := d.downloadBatch(wgCtx, accessToken, fileName, i, tracker); nil != err {
 				switch {
 				case errutil.IsContext(ctx):
 					return ctx.Err()
@@ -45,6 +68,8 @@ func (d *DashTrackStream) saveTo(ctx context.Context, accessToken string, fileNa
 					return context.DeadlineExceeded
 				case errors.Is(err, ErrTooManyRequests):
 					return ErrTooManyRequests
+				case errors.Is(err, auth.ErrUnauthorized):
+					return auth.ErrUnauthorized
 				case errutil.IsFlaw(err):
 					flawP["batch_index"] = i
 					return must.BeFlaw(err).Append(flawP)
@@ -52,7 +77,7 @@ func (d *DashTrackStream) saveTo(ctx context.Context, accessToken string, fileNa
 					panic(errutil.UnknownError(err))
 				}
 			}
-			return nil
+			return manifest.complete(i, partFileName)
 		})
 	}
 
@@ -64,6 +89,8 @@ func (d *DashTrackStream) saveTo(ctx context.Context, accessToken string, fileNa
 			return context.DeadlineExceeded
 		case errors.Is(err, ErrTooManyRequests):
 			return ErrTooManyRequests
+		case errors.Is(err, auth.ErrUnauthorized):
+			return auth.ErrUnauthorized
 		case errutil.IsFlaw(err):
 			return must.BeFlaw(err).Append(flawP)
 		default:
@@ -115,10 +142,14 @@ func (d *DashTrackStream) saveTo(ctx context.Context, accessToken string, fileNa
 		return flaw.From(fmt.Errorf("failed to sync track file: %v", err)).Append(flawP)
 	}
 
+	if err := manifest.remove(); nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	}
+
 	return nil
 }
 
-func writePartToTrackFile(f *os.File, partFileName string) (err error) {
+func writePartToTrackFile(f io.Writer, partFileName string) (err error) {
 	fp, err := os.OpenFile(partFileName, os.O_RDONLY, 0o0600)
 	if nil != err {
 		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
@@ -149,7 +180,7 @@ func writePartToTrackFile(f *os.File, partFileName string) (err error) {
 	return nil
 }
 
-func (d *DashTrackStream) downloadBatch(ctx context.Context, accessToken, fileName string, idx int) (err error) {
+func (d *DashTrackStream) downloadBatch(ctx context.Context, accessToken, fileName string, idx int, tracker *progress.Tracker) (err error) {
 	f, err := os.OpenFile(
 		fileName+".part."+strconv.Itoa(idx),
 		os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_SYNC,
@@ -201,7 +232,7 @@ func (d *DashTrackStream) downloadBatch(ctx context.Context, accessToken, fileNa
 
 		link := strings.Replace(d.Info.Parts.InitializationURLTemplate, "$Number$", strconv.Itoa(segmentIdx), 1)
 		loopFlawP["link"] = link
-		if err := d.downloadSegment(ctx, accessToken, link, f); nil != err {
+		if err := downloadSegment(ctx, accessToken, link, tracker.Writer(f)); nil != err {
 			switch {
 			case errutil.IsContext(ctx):
 				return ctx.Err()
@@ -209,6 +240,8 @@ func (d *DashTrackStream) downloadBatch(ctx context.Context, accessToken, fileNa
 				return context.DeadlineExceeded
 			case errors.Is(err, ErrTooManyRequests):
 				return ErrTooManyRequests
+			case errors.Is(err, auth.ErrUnauthorized):
+				return auth.ErrUnauthorized
 			case errutil.IsFlaw(err):
 				return must.BeFlaw(err).Append(flawP)
 			default:
@@ -220,32 +253,18 @@ func (d *DashTrackStream) downloadBatch(ctx context.Context, accessToken, fileNa
 	return nil
 }
 
-func (d *DashTrackStream) downloadSegment(ctx context.Context, accessToken, link string, f *os.File) (err error) {
+func downloadSegment(ctx context.Context, accessToken, link string, f io.Writer) (err error) {
 	flawP := flaw.P{}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	resp, err := api.SendMedia(ctx, accessToken, api.MediaRequest{
+		Method:  http.MethodGet,
+		URL:     link,
+		Header:  nil,
+		Timeout: config.DashSegmentDownloadTimeout,
+		Status:  http.StatusOK,
+	})
 	if nil != err {
-		if errutil.IsContext(ctx) {
-			return ctx.Err()
-		}
-
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return flaw.From(fmt.Errorf("failed to create get track part request: %v", err)).Append(flawP)
-	}
-	req.Header.Add("Authorization", "Bearer "+accessToken)
-
-	client := http.Client{Timeout: config.DashSegmentDownloadTimeout} //nolint:exhaustruct
-	resp, err := client.Do(req)
-	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return context.DeadlineExceeded
-		default:
-			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return flaw.From(fmt.Errorf("failed to send get track part request: %v", err)).Append(flawP)
-		}
+		return err
 	}
 	defer func() {
 		if closeErr := resp.Body.Close(); nil != closeErr {
@@ -258,8 +277,6 @@ func (d *DashTrackStream) downloadSegment(ctx context.Context, accessToken, link
 				err = flaw.From(errors.New("context was ended")).Join(closeErr)
 			case errors.Is(err, context.DeadlineExceeded):
 				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
-			case errors.Is(err, ErrTooManyRequests):
-				err = flaw.From(errors.New("too many requests")).Join(closeErr)
 			case errutil.IsFlaw(err):
 				err = must.BeFlaw(err).Join(closeErr)
 			default:
@@ -269,53 +286,6 @@ func (d *DashTrackStream) downloadSegment(ctx context.Context, accessToken, link
 	}()
 	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
 
-	switch status := resp.StatusCode; status {
-	case http.StatusOK:
-	case http.StatusUnauthorized:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return err
-		}
-
-		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return err
-		} else if ok {
-			return auth.ErrUnauthorized
-		}
-
-		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return err
-		} else if ok {
-			return auth.ErrUnauthorized
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return flaw.From(errors.New("received 401 response")).Append(flawP)
-	case http.StatusTooManyRequests:
-		return ErrTooManyRequests
-	case http.StatusForbidden:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return err
-		}
-		if ok, err := errutil.IsTooManyErrorResponse(resp, respBytes); nil != err {
-			flawP["response_body"] = string(respBytes)
-			return must.BeFlaw(err).Append(flawP)
-		} else if ok {
-			return ErrTooManyRequests
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return flaw.From(errors.New("unexpected 403 response")).Append(flawP)
-	default:
-		respBytes, err := httputil.ReadOptionalResponseBody(ctx, resp)
-		if nil != err {
-			return err
-		}
-		flawP["response_body"] = string(respBytes)
-		return flaw.From(fmt.Errorf("unexpected status code received from get track part: %d", status)).Append(flawP)
-	}
-
 	respBytes, err := httputil.ReadResponseBody(ctx, resp)
 	if nil != err {
 		return err
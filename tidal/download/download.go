@@ -5,74 +5,83 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"net/http"
-	"net/url"
 	"os"
 	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/samber/lo"
-	"github.com/tidwall/gjson"
 	"github.com/xeptore/flaw/v8"
-	"golang.org/x/sync/errgroup"
 
 	"github.com/xeptore/tgtd/cache"
-	"github.com/xeptore/tgtd/config"
 	"github.com/xeptore/tgtd/errutil"
-	"github.com/xeptore/tgtd/httputil"
 	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/progress"
 	"github.com/xeptore/tgtd/ptr"
 	"github.com/xeptore/tgtd/ratelimit"
 	"github.com/xeptore/tgtd/sliceutil"
+	"github.com/xeptore/tgtd/tag"
 	"github.com/xeptore/tgtd/tidal"
+	"github.com/xeptore/tgtd/tidal/api"
 	"github.com/xeptore/tgtd/tidal/auth"
+	"github.com/xeptore/tgtd/tidal/decrypt"
 	"github.com/xeptore/tgtd/tidal/fs"
 	"github.com/xeptore/tgtd/tidal/mpd"
 )
 
 const (
-	trackAPIFormat             = "https://api.tidal.com/v1/tracks/%s"
-	trackCreditsAPIFormat      = "https://api.tidal.com/v1/tracks/%s/credits" //nolint:gosec
-	trackLyricsAPIFormat       = "https://api.tidal.com/v1/tracks/%s/lyrics"
-	albumAPIFormat             = "https://api.tidal.com/v1/albums/%s"
-	playlistAPIFormat          = "https://api.tidal.com/v1/playlists/%s"
-	mixInfoURL                 = "https://listen.tidal.com/v1/pages/mix"
-	trackStreamAPIFormat       = "https://api.tidal.com/v1/tracks/%s/playbackinfo"
-	albumItemsCreditsAPIFormat = "https://api.tidal.com/v1/albums/%s/items/credits" //nolint:gosec
-	playlistItemsAPIFormat     = "https://api.tidal.com/v1/playlists/%s/items"
-	mixItemsAPIFormat          = "https://api.tidal.com/v1/mixes/%s/items"
-	coverURLFormat             = "https://resources.tidal.com/images/%s/1280x1280.jpg"
-	pageSize                   = 100
-	maxBatchParts              = 10
-	singlePartChunkSize        = 1024 * 1024
+	maxBatchParts       = 10
+	singlePartChunkSize = 1024 * 1024
 )
 
-var ErrTooManyRequests = errors.New("too many requests")
+var (
+	ErrTooManyRequests = api.ErrTooManyRequests
+	// ErrFFmpegRequired is returned for playlist cut items, which are trimmed with
+	// ffmpeg, if it is not available.
+	ErrFFmpegRequired = errors.New("ffmpeg is required to trim cut items")
+)
 
 type Downloader struct {
-	dir                   fs.DownloadDir
+	dir                   fs.JobDir
 	auth                  *auth.Auth
+	client                *api.Client
 	albumsMetaCache       *cache.AlbumsMetaCache
 	downloadedCoversCache *cache.DownloadedCoversCache
 	trackCreditsCache     *cache.TrackCreditsCache
+	quality               tidal.Quality
+	immersive             bool
+	progress              *progress.Tracker
+	// uploaded reports whether the track stored with the given key is already
+	// uploaded, in which case album, playlist, and mix tracks are not downloaded.
+	uploaded func(trackKey string) bool
 }
 
 func NewDownloader(
-	dir fs.DownloadDir,
+	dir fs.JobDir,
 	auth *auth.Auth,
+	client *api.Client,
 	albumsMetaCache *cache.AlbumsMetaCache,
 	downloadedCoversCache *cache.DownloadedCoversCache,
 	trackCreditsCache *cache.TrackCreditsCache,
+	quality tidal.Quality,
+	immersive bool,
+	progress *progress.Tracker,
+	uploaded func(trackKey string) bool,
 ) *Downloader {
 	return &Downloader{
 		dir:                   dir,
 		auth:                  auth,
+		client:                client,
 		albumsMetaCache:       albumsMetaCache,
 		downloadedCoversCache: downloadedCoversCache,
 		trackCreditsCache:     trackCreditsCache,
+		quality:               quality,
+		immersive:             immersive,
+		progress:              progress,
+		uploaded:              uploaded,
 	}
 }
 
@@ -82,16 +91,22 @@ func (d *Downloader) Single(ctx context.Context, id string) (err error) {
 		return err
 	}
 
-	track, err := getSingleTrackMeta(ctx, accessToken, id)
+	track, err := d.getSingleTrackMeta(ctx, id)
 	if nil != err {
 		return err
 	}
+	d.progress.AddTracks(1)
+	defer func() {
+		if nil == err {
+			d.progress.TracksDownloaded(1)
+		}
+	}()
 
 	trackFs := d.dir.Single(id)
 	if exists, err := trackFs.Cover.Exists(); nil != err {
 		return err
 	} else if !exists {
-		coverBytes, err := d.getCover(ctx, accessToken, track.CoverID)
+		coverBytes, err := d.getCover(ctx, track.CoverID)
 		if nil != err {
 			return err
 		}
@@ -103,36 +118,26 @@ func (d *Downloader) Single(ctx context.Context, id string) (err error) {
 	if exists, err := trackFs.Exists(); nil != err {
 		return err
 	} else if exists {
-		return nil
+		return trackFs.Touch()
 	}
-	defer func() {
-		if nil != err {
-			if removeErr := trackFs.Remove(); nil != removeErr {
-				flawP := flaw.P{
-					"err_debug_tree":  errutil.Tree(removeErr).FlawP(),
-					"track_file_path": trackFs.Path,
-				}
-				err = flaw.From(fmt.Errorf("failed to remove track file: %v", removeErr)).Join(err).Append(flawP)
-			}
-		}
-	}()
+	defer removeTrackOnError(trackFs.Path, trackFs.Remove, &err)
 
-	format, err := downloadTrack(ctx, accessToken, id, trackFs.Path)
+	format, err := d.downloadTrack(ctx, accessToken, id, trackFs.Path)
 	if nil != err {
 		return err
 	}
 
-	trackCredits, err := d.getTrackCredits(ctx, accessToken, id)
+	trackCredits, err := d.getTrackCredits(ctx, id)
 	if nil != err {
 		return err
 	}
 
-	trackLyrics, err := fetchTrackLyrics(ctx, accessToken, id)
+	trackLyrics, err := d.getTrackLyrics(ctx, id)
 	if nil != err {
 		return err
 	}
 
-	album, err := d.getAlbumMeta(ctx, accessToken, track.AlbumID)
+	album, err := d.getAlbumMeta(ctx, track.AlbumID)
 	if nil != err {
 		return err
 	}
@@ -182,11 +187,17 @@ func trackCaption(album tidal.AlbumMeta) string {
 	return fmt.Sprintf("%s (%s)", album.Title, album.ReleaseDate.Format(tidal.ReleaseDateLayout))
 }
 
-func (d *Downloader) getTrackCredits(ctx context.Context, accessToken, id string) (*tidal.TrackCredits, error) {
+func (d *Downloader) getTrackCredits(ctx context.Context, id string) (*tidal.TrackCredits, error) {
 	cachedTrackCredits, err := d.trackCreditsCache.Fetch(
 		id,
-		cache.DefaultTrackCreditsTTL,
-		func() (*tidal.TrackCredits, error) { return fetchTrackCredits(ctx, accessToken, id) },
+		d.trackCreditsCache.TTL(),
+		func() (*tidal.TrackCredits, error) {
+			credits, err := d.client.TrackCredits(ctx, id)
+			if nil != err {
+				return nil, err
+			}
+			return ptr.Of(credits.TrackCredits()), nil
+		},
 	)
 	if nil != err {
 		return nil, err
@@ -194,291 +205,18 @@ func (d *Downloader) getTrackCredits(ctx context.Context, accessToken, id string
 	return cachedTrackCredits.Value(), nil
 }
 
-func fetchTrackCredits(ctx context.Context, accessToken string, id string) (c *tidal.TrackCredits, err error) {
-	reqURL, err := url.Parse(fmt.Sprintf(trackCreditsAPIFormat, id))
-	if nil != err {
-		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-		return nil, flaw.From(fmt.Errorf("failed to parse track credits URL: %v", err)).Append(flawP)
-	}
-
-	reqParams := make(url.Values, 2)
-	reqParams.Add("countryCode", "US")
-	reqParams.Add("includeContributors", "true")
-	reqURL.RawQuery = reqParams.Encode()
-	flawP := flaw.P{"encoded_query_params": reqURL.RawQuery}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
-	if nil != err {
-		if errutil.IsContext(ctx) {
-			return nil, ctx.Err()
-		}
-
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to create get track credits request: %v", err)).Append(flawP)
-	}
-	req.Header.Add("Authorization", "Bearer "+accessToken)
-
-	client := http.Client{Timeout: config.GetTrackCreditsRequestTimeout} //nolint:exhaustruct
-	resp, err := client.Do(req)
-	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return nil, ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, context.DeadlineExceeded
-		default:
-			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return nil, flaw.From(fmt.Errorf("failed to send get track credits request: %v", err)).Append(flawP)
-		}
-	}
-	defer func() {
-		if closeErr := resp.Body.Close(); nil != closeErr {
-			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
-			closeErr = flaw.From(fmt.Errorf("failed to close get track credits response body: %v", closeErr)).Append(flawP)
-			switch {
-			case nil == err:
-				err = closeErr
-			case errutil.IsContext(ctx):
-				err = flaw.From(errors.New("context was ended")).Join(closeErr)
-			case errors.Is(err, context.DeadlineExceeded):
-				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
-			case errors.Is(err, ErrTooManyRequests):
-				err = flaw.From(errors.New("too many requests")).Join(closeErr)
-			case errutil.IsFlaw(err):
-				err = must.BeFlaw(err).Join(closeErr)
-			default:
-				panic(errutil.UnknownError(err))
-			}
-		}
-	}()
-	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
-
-	switch code := resp.StatusCode; code {
-	case http.StatusOK:
-	case http.StatusUnauthorized:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-
-		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
-		}
-
-		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("received 401 response")).Append(flawP)
-	case http.StatusTooManyRequests:
-		return nil, ErrTooManyRequests
-	case http.StatusForbidden:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-		if ok, err := errutil.IsTooManyErrorResponse(resp, respBytes); nil != err {
-			flawP["response_body"] = string(respBytes)
-			return nil, must.BeFlaw(err)
-		} else if ok {
-			return nil, ErrTooManyRequests
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("unexpected 403 response")).Append(flawP)
-	default:
-		respBytes, err := httputil.ReadOptionalResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(fmt.Errorf("unexpected status code: %d", code)).Append(flawP)
-	}
-
-	respBytes, err := httputil.ReadResponseBody(ctx, resp)
-	if nil != err {
-		return nil, err
-	}
-
-	var respBody TrackCreditsResponse
-	if err := json.Unmarshal(respBytes, &respBody); nil != err {
-		flawP["response_body"] = string(respBytes)
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to decode track credits response: %v", err)).Append(flawP)
-	}
-
-	return ptr.Of(respBody.toTrackCredits()), nil
-}
-
-func fetchTrackLyrics(ctx context.Context, accessToken string, id string) (l string, err error) {
-	reqURL, err := url.Parse(fmt.Sprintf(trackLyricsAPIFormat, id))
-	if nil != err {
-		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-		return "", flaw.From(fmt.Errorf("failed to parse track lyrics URL: %v", err)).Append(flawP)
-	}
-
-	reqParams := make(url.Values, 2)
-	reqParams.Add("countryCode", "US")
-	reqParams.Add("includeContributors", "true")
-	reqURL.RawQuery = reqParams.Encode()
-	flawP := flaw.P{"encoded_query_params": reqURL.RawQuery}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
-	if nil != err {
-		if errutil.IsContext(ctx) {
-			return "", ctx.Err()
-		}
-
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return "", flaw.From(fmt.Errorf("failed to create get track lyrics request: %v", err)).Append(flawP)
-	}
-	req.Header.Add("Authorization", "Bearer "+accessToken)
-
-	client := http.Client{Timeout: config.GetTrackLyricsRequestTimeout} //nolint:exhaustruct
-	resp, err := client.Do(req)
+// getTrackLyrics returns lyrics of the track, or an empty string if it has none.
+func (d *Downloader) getTrackLyrics(ctx context.Context, id string) (string, error) {
+	lyrics, err := d.client.TrackLyrics(ctx, id)
 	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return "", ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return "", context.DeadlineExceeded
-		default:
-			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return "", flaw.From(fmt.Errorf("failed to send get track lyrics request: %v", err)).Append(flawP)
-		}
-	}
-	defer func() {
-		if closeErr := resp.Body.Close(); nil != closeErr {
-			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
-			closeErr = flaw.From(fmt.Errorf("failed to close get track lyrics response body: %v", closeErr)).Append(flawP)
-			switch {
-			case nil == err:
-				err = closeErr
-			case errutil.IsContext(ctx):
-				err = flaw.From(errors.New("context was ended")).Join(closeErr)
-			case errors.Is(err, context.DeadlineExceeded):
-				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
-			case errors.Is(err, ErrTooManyRequests):
-				err = flaw.From(errors.New("too many requests")).Join(closeErr)
-			case errutil.IsFlaw(err):
-				err = must.BeFlaw(err).Join(closeErr)
-			default:
-				panic(errutil.UnknownError(err))
-			}
-		}
-	}()
-	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
-
-	switch code := resp.StatusCode; code {
-	case http.StatusOK:
-	case http.StatusNotFound:
-		return "", nil
-	case http.StatusUnauthorized:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return "", err
-		}
-
-		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return "", err
-		} else if ok {
-			return "", auth.ErrUnauthorized
-		}
-
-		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return "", err
-		} else if ok {
-			return "", auth.ErrUnauthorized
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return "", flaw.From(errors.New("received 401 response")).Append(flawP)
-	case http.StatusTooManyRequests:
-		return "", ErrTooManyRequests
-	case http.StatusForbidden:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return "", err
-		}
-		if ok, err := errutil.IsTooManyErrorResponse(resp, respBytes); nil != err {
-			flawP["response_body"] = string(respBytes)
-			return "", must.BeFlaw(err)
-		} else if ok {
-			return "", ErrTooManyRequests
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return "", flaw.From(errors.New("unexpected 403 response")).Append(flawP)
-	default:
-		respBytes, err := httputil.ReadOptionalResponseBody(ctx, resp)
-		if nil != err {
-			return "", err
+		if errors.Is(err, api.ErrNotFound) {
+			return "", nil
 		}
-		flawP["response_body"] = string(respBytes)
-		return "", flaw.From(fmt.Errorf("unexpected status code: %d", code)).Append(flawP)
-	}
-
-	respBytes, err := httputil.ReadResponseBody(ctx, resp)
-	if nil != err {
 		return "", err
 	}
-
-	if !gjson.ValidBytes(respBytes) {
-		flawP["response_body"] = string(respBytes)
-		return "", flaw.From(fmt.Errorf("invalid track lyrics response json: %v", err)).Append(flawP)
-	}
-
-	var lyrics string
-	if lyricsKey := gjson.GetBytes(respBytes, "subtitles"); lyricsKey.Type == gjson.String {
-		lyrics = lyricsKey.Str
-	} else if lyricsKey := gjson.GetBytes(respBytes, "lyrics"); lyricsKey.Type == gjson.String {
-		lyrics = lyricsKey.Str
-	} else {
-		flawP["response_body"] = string(respBytes)
-		return "", flaw.From(fmt.Errorf("unexpected track lyrics response: %v", err)).Append(flawP)
-	}
-
 	return lyrics, nil
 }
 
-type TrackCreditsResponse []struct {
-	Type         string `json:"type"`
-	Contributors []struct {
-		Name string `json:"name"`
-		ID   int    `json:"id"`
-	} `json:"contributors"`
-}
-
-func (t TrackCreditsResponse) toTrackCredits() tidal.TrackCredits {
-	var out tidal.TrackCredits
-	for _, v := range t {
-		switch v.Type {
-		case "Producer":
-			for _, v := range v.Contributors {
-				out.Producers = append(out.Producers, v.Name)
-			}
-		case "Composer":
-			for _, v := range v.Contributors {
-				out.Composers = append(out.Composers, v.Name)
-			}
-		case "Lyricist":
-			for _, v := range v.Contributors {
-				out.Lyricists = append(out.Lyricists, v.Name)
-			}
-		case "Additional Producer":
-			for _, v := range v.Contributors {
-				out.AdditionalProducers = append(out.AdditionalProducers, v.Name)
-			}
-		}
-	}
-	return out
-}
-
 type TrackEmbeddedAttrs struct {
 	LeadArtist   string
 	Album        string
@@ -499,7 +237,60 @@ type TrackEmbeddedAttrs struct {
 	Lyrics       string
 }
 
-func embedTrackAttributes(ctx context.Context, trackFilePath string, attrs TrackEmbeddedAttrs) (err error) {
+// embedTrackAttributes writes attrs into the track file natively, and falls back
+// to ffmpeg, if available, for files the tag package does not support.
+func embedTrackAttributes(ctx context.Context, trackFilePath string, attrs TrackEmbeddedAttrs) error {
+	cover, err := os.ReadFile(attrs.CoverPath)
+	if nil != err {
+		flawP := flaw.P{
+			"err_debug_tree": errutil.Tree(err).FlawP(),
+			"cover_path":     attrs.CoverPath,
+		}
+		return flaw.From(fmt.Errorf("failed to read track cover file: %v", err)).Append(flawP)
+	}
+
+	tags := tag.Tags{
+		Title:         attrs.Title,
+		Artist:        tidal.JoinArtists(attrs.Artists),
+		LeadPerformer: attrs.LeadArtist,
+		Album:         attrs.Album,
+		AlbumArtist:   attrs.AlbumArtist,
+		Copyright:     attrs.Copyright,
+		ISRC:          attrs.ISRC,
+		TrackNumber:   attrs.TrackNumber,
+		TrackTotal:    attrs.TotalTracks,
+		DiscNumber:    attrs.VolumeNumber,
+		DiscTotal:     attrs.TotalVolumes,
+		Date:          attrs.ReleaseDate,
+		Lyrics:        attrs.Lyrics,
+		Composer:      tidal.JoinNames(attrs.Credits.Composers),
+		Lyricist:      tidal.JoinNames(attrs.Credits.Lyricists),
+		Producer:      tidal.JoinNames(attrs.Credits.Producers),
+		CoProducer:    tidal.JoinNames(attrs.Credits.AdditionalProducers),
+		Version:       lo.FromPtr(attrs.Version),
+		Comment:       lo.Ternary(attrs.Format.IsDolbyAtmos(), "Dolby Atmos", ""),
+		Cover:         cover,
+	}
+
+	write := tag.WriteMP4
+	if attrs.Format.InferTrackExt() == "flac" {
+		write = tag.WriteFLAC
+	}
+	if err := write(trackFilePath, tags); nil != err {
+		if errors.Is(err, tag.ErrUnsupported) {
+			if _, lookErr := exec.LookPath("ffmpeg"); nil == lookErr {
+				return embedTrackAttributesWithFFmpeg(ctx, trackFilePath, attrs)
+			}
+			flawP := flaw.P{"format": attrs.Format}
+			return flaw.From(errors.New("track file format is not supported by tag writer and ffmpeg is not available")).Append(flawP)
+		}
+		flawP := flaw.P{"track_file_path": trackFilePath}
+		return must.BeFlaw(err).Append(flawP)
+	}
+	return nil
+}
+
+func embedTrackAttributesWithFFmpeg(ctx context.Context, trackFilePath string, attrs TrackEmbeddedAttrs) (err error) {
 	ext := attrs.Format.InferTrackExt()
 	trackFilePathWithExt := trackFilePath + "." + ext
 
@@ -537,9 +328,13 @@ func embedTrackAttributes(ctx context.Context, trackFilePath string, attrs Track
 		metaTags = append(metaTags, "version="+*attrs.Version)
 	}
 
+	if attrs.Format.IsDolbyAtmos() {
+		metaTags = append(metaTags, "comment=Dolby Atmos")
+	}
+
 	metaArgs := make([]string, 0, len(metaTags)*2)
-	for _, tag := range metaTags {
-		metaArgs = append(metaArgs, "-metadata", tag)
+	for _, metaTag := range metaTags {
+		metaArgs = append(metaArgs, "-metadata", metaTag)
 	}
 
 	args := []string{
@@ -556,6 +351,10 @@ func embedTrackAttributes(ctx context.Context, trackFilePath string, attrs Track
 		"-disposition:v",
 		"attached_pic",
 	}
+	if attrs.Format.IsDolbyAtmos() {
+		// The ipod muxer, which is inferred from the m4a extension, does not support E-AC-3.
+		args = append(args, "-f", "mp4")
+	}
 	args = append(args, metaArgs...)
 	args = append(args, trackFilePathWithExt)
 
@@ -578,616 +377,211 @@ func embedTrackAttributes(ctx context.Context, trackFilePath string, attrs Track
 	return nil
 }
 
-func getSingleTrackMeta(ctx context.Context, accessToken, id string) (m *SingleTrackMeta, err error) {
-	trackURL := fmt.Sprintf(trackAPIFormat, id)
-	flawP := flaw.P{"url": trackURL}
-
-	reqURL, err := url.Parse(trackURL)
-	if nil != err {
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to parse track URL: %v", err)).Append(flawP)
+// trimTrack trims the track file in place to the given cut. It tries to avoid
+// re-encoding the audio by copying the stream, and falls back to re-encoding it
+// with the default encoder of the track format if copying fails.
+func trimTrack(ctx context.Context, trackFilePath string, format tidal.TrackFormat, cut TrackCut) error {
+	if _, err := exec.LookPath("ffmpeg"); nil != err {
+		return ErrFFmpegRequired
 	}
 
-	reqParams := make(url.Values, 1)
-	reqParams.Add("countryCode", "US")
-	reqURL.RawQuery = reqParams.Encode()
-	flawP["encoded_query_params"] = reqURL.RawQuery
+	trimmedFilePath := trackFilePath + ".cut." + format.InferTrackExt()
+	flawP := flaw.P{"cut": cut.flawP(), "track_file_path": trackFilePath}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
-	if nil != err {
+	inputArgs := []string{"-y", "-ss", strconv.FormatFloat(cut.Start.Seconds(), 'f', 3, 64)}
+	if cut.End > 0 {
+		inputArgs = append(inputArgs, "-to", strconv.FormatFloat(cut.End.Seconds(), 'f', 3, 64))
+	}
+	inputArgs = append(inputArgs, "-i", trackFilePath, "-map", "0:a")
+
+	copyArgs := append(slices.Clone(inputArgs), "-c", "copy", trimmedFilePath)
+	cmd := exec.CommandContext(ctx, "ffmpeg", copyArgs...)
+	if err := cmd.Run(); nil != err {
 		if errutil.IsContext(ctx) {
-			return nil, ctx.Err()
+			return ctx.Err()
 		}
+		flawP["copy_cmd"] = cmd.String()
+		flawP["copy_err_debug_tree"] = errutil.Tree(err).FlawP()
 
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to create get track info request: %v", err)).Append(flawP)
-	}
-	req.Header.Add("Authorization", "Bearer "+accessToken)
-
-	client := http.Client{Timeout: 5 * time.Second} //nolint:exhaustruct
-	resp, err := client.Do(req)
-	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return nil, ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, context.DeadlineExceeded
-		default:
+		encodeArgs := append(slices.Clone(inputArgs), trimmedFilePath)
+		cmd := exec.CommandContext(ctx, "ffmpeg", encodeArgs...)
+		if err := cmd.Run(); nil != err {
+			if errutil.IsContext(ctx) {
+				return ctx.Err()
+			}
+			flawP["cmd"] = cmd.String()
 			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return nil, flaw.From(fmt.Errorf("failed to send get track info request: %v", err)).Append(flawP)
-		}
-	}
-	defer func() {
-		if closeErr := resp.Body.Close(); nil != closeErr {
-			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
-			closeErr = flaw.From(fmt.Errorf("failed to close get track info response body: %v", closeErr)).Append(flawP)
-			switch {
-			case nil == err:
-				err = closeErr
-			case errutil.IsContext(ctx):
-				err = flaw.From(errors.New("context was ended")).Join(closeErr)
-			case errors.Is(err, context.DeadlineExceeded):
-				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
-			case errors.Is(err, auth.ErrUnauthorized):
-				err = flaw.From(errors.New("unauthorized")).Join(closeErr)
-			case errors.Is(err, ErrTooManyRequests):
-				err = flaw.From(errors.New("too many requests")).Join(closeErr)
-			case errutil.IsFlaw(err):
-				err = must.BeFlaw(err).Join(closeErr)
-			default:
-				panic(errutil.UnknownError(err))
+			if removeErr := os.Remove(trimmedFilePath); nil != removeErr && !errors.Is(removeErr, os.ErrNotExist) {
+				return flaw.From(fmt.Errorf("failed to trim track: %v", err)).Join(removeErr).Append(flawP)
 			}
+			return flaw.From(fmt.Errorf("failed to trim track: %v", err)).Append(flawP)
 		}
-	}()
-	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
-
-	switch code := resp.StatusCode; code {
-	case http.StatusOK:
-	case http.StatusUnauthorized:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-
-		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
-		}
-
-		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("received 401 response")).Append(flawP)
-	case http.StatusTooManyRequests:
-		return nil, ErrTooManyRequests
-	case http.StatusForbidden:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-		if ok, err := errutil.IsTooManyErrorResponse(resp, respBytes); nil != err {
-			flawP["response_body"] = string(respBytes)
-			return nil, must.BeFlaw(err).Append(flawP)
-		} else if ok {
-			return nil, ErrTooManyRequests
-		}
+	}
 
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("unexpected 403 response")).Append(flawP)
-	default:
-		respBytes, err := httputil.ReadOptionalResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(fmt.Errorf("unexpected status code: %d", code)).Append(flawP)
+	if err := os.Rename(trimmedFilePath, trackFilePath); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to rename trimmed track file: %v", err)).Append(flawP)
 	}
+	return nil
+}
 
-	respBytes, err := httputil.ReadResponseBody(ctx, resp)
+func (d *Downloader) getSingleTrackMeta(ctx context.Context, id string) (*SingleTrackMeta, error) {
+	resp, err := d.client.Track(ctx, id)
 	if nil != err {
 		return nil, err
 	}
-	var respBody struct {
-		Duration     int    `json:"duration"`
-		Title        string `json:"title"`
-		TrackNumber  int    `json:"trackNumber"`
-		VolumeNumber int    `json:"volumeNumber"`
-		Copyright    string `json:"copyright"`
-		ISRC         string `json:"isrc"`
-		Artist       struct {
-			Name string `json:"name"`
-		} `json:"artist"`
-		Artists []struct {
-			Name string `json:"name"`
-			Type string `json:"type"`
-		} `json:"artists"`
-		Album struct {
-			ID      int    `json:"id"`
-			CoverID string `json:"cover"`
-			Title   string `json:"title"`
-		} `json:"album"`
-		Version *string `json:"version"`
-	}
-	if err := json.Unmarshal(respBytes, &respBody); nil != err {
-		flawP["response_body"] = string(respBytes)
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to decode track info response body: %v", err)).Append(flawP)
-	}
 
-	artists := make([]tidal.TrackArtist, len(respBody.Artists))
-	for i, artist := range respBody.Artists {
-		switch artist.Type {
-		case tidal.ArtistTypeMain, tidal.ArtistTypeFeatured:
-		default:
-			return nil, flaw.From(fmt.Errorf("unexpected artist type: %s", artist.Type)).Append(flawP)
-		}
-		artists[i] = tidal.TrackArtist{Name: artist.Name, Type: artist.Type}
+	artists, err := api.TrackArtists(resp.Artists)
+	if nil != err {
+		return nil, must.BeFlaw(err).Append(flaw.P{"id": id})
 	}
 
 	track := SingleTrackMeta{
-		Artist:       respBody.Artist.Name,
-		AlbumID:      strconv.Itoa(respBody.Album.ID),
-		AlbumTitle:   respBody.Album.Title,
+		Artist:       resp.Artist.Name,
+		AlbumID:      strconv.Itoa(resp.Album.ID),
+		AlbumTitle:   resp.Album.Title,
 		Artists:      artists,
-		ISRC:         respBody.ISRC,
-		Copyright:    respBody.Copyright,
-		CoverID:      respBody.Album.CoverID,
-		Duration:     respBody.Duration,
-		Title:        respBody.Title,
-		TrackNumber:  respBody.TrackNumber,
-		Version:      respBody.Version,
-		VolumeNumber: respBody.VolumeNumber,
+		ISRC:         resp.ISRC,
+		Copyright:    resp.Copyright,
+		CoverID:      resp.Album.CoverID,
+		Duration:     resp.Duration,
+		Title:        resp.Title,
+		TrackNumber:  resp.TrackNumber,
+		Version:      resp.Version,
+		VolumeNumber: resp.VolumeNumber,
 	}
 	return &track, nil
 }
-
-func (d *Downloader) getCover(ctx context.Context, accessToken, coverID string) (b []byte, err error) {
+func (d *Downloader) getCover(ctx context.Context, coverID string) (b []byte, err error) {
 	cachedCoverBytes, err := d.downloadedCoversCache.Fetch(
 		coverID,
-		cache.DefaultDownloadedCoverTTL,
-		func() ([]byte, error) { return downloadCover(ctx, accessToken, coverID) },
+		d.downloadedCoversCache.TTL(),
+		func() ([]byte, error) { return d.client.Cover(ctx, coverID) },
 	)
 	if nil != err {
 		return nil, err
 	}
 	return cachedCoverBytes.Value(), nil
 }
-
-func downloadCover(ctx context.Context, accessToken, coverID string) (b []byte, err error) {
-	coverURL, err := url.JoinPath(fmt.Sprintf(coverURLFormat, strings.ReplaceAll(coverID, "-", "/")))
+func (d *Downloader) getAlbumMeta(ctx context.Context, id string) (*tidal.AlbumMeta, error) {
+	cachedAlbumMeta, err := d.albumsMetaCache.Fetch(
+		id,
+		d.albumsMetaCache.TTL(),
+		func() (*tidal.AlbumMeta, error) { return d.fetchAlbumMeta(ctx, id) },
+	)
 	if nil != err {
-		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-		return nil, flaw.From(fmt.Errorf("failed to join cover base URL with cover filepath: %v", err)).Append(flawP)
+		return nil, err
 	}
-	flawP := flaw.P{"cover_url": coverURL}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, coverURL, nil)
+	return cachedAlbumMeta.Value(), nil
+}
+func (d *Downloader) fetchAlbumMeta(ctx context.Context, id string) (*tidal.AlbumMeta, error) {
+	resp, err := d.client.Album(ctx, id)
 	if nil != err {
-		if errutil.IsContext(ctx) {
-			return nil, ctx.Err()
-		}
+		return nil, err
+	}
 
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to create get cover request: %v", err)).Append(flawP)
+	releaseDate, err := time.Parse("2006-01-02", resp.ReleaseDate)
+	if nil != err {
+		flawP := flaw.P{"id": id, "release_date": resp.ReleaseDate, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return nil, flaw.From(fmt.Errorf("failed to parse album release date: %v", err)).Append(flawP)
 	}
-	req.Header.Add("Authorization", "Bearer "+accessToken)
 
-	client := http.Client{Timeout: config.CoverDownloadTimeout} //nolint:exhaustruct
-	resp, err := client.Do(req)
+	return &tidal.AlbumMeta{
+		Artist:       resp.Artist.Name,
+		Title:        resp.Title,
+		ReleaseDate:  releaseDate,
+		CoverID:      resp.CoverID,
+		TotalTracks:  resp.TotalTracks,
+		TotalVolumes: resp.TotalVolumes,
+	}, nil
+}
+func (d *Downloader) downloadTrack(ctx context.Context, accessToken, id, fileName string) (*tidal.TrackFormat, error) {
+	flawP := make(flaw.P)
+	stream, format, err := d.getStream(ctx, id)
 	if nil != err {
+		return nil, err
+	}
+
+	if err := stream.saveTo(ctx, accessToken, fileName, d.progress); nil != err {
 		switch {
 		case errutil.IsContext(ctx):
 			return nil, ctx.Err()
 		case errors.Is(err, context.DeadlineExceeded):
 			return nil, context.DeadlineExceeded
+		case errors.Is(err, ErrTooManyRequests):
+			return nil, ErrTooManyRequests
+		case errors.Is(err, auth.ErrUnauthorized):
+			return nil, auth.ErrUnauthorized
+		case errutil.IsFlaw(err):
+			return nil, must.BeFlaw(err).Append(flawP)
 		default:
-			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return nil, flaw.From(fmt.Errorf("failed to send get track cover request: %v", err)).Append(flawP)
+			panic(errutil.UnknownError(err))
 		}
 	}
-	defer func() {
-		if closeErr := resp.Body.Close(); nil != closeErr {
-			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
-			closeErr = flaw.From(fmt.Errorf("failed to close get track cover response body: %v", closeErr)).Append(flawP)
-			switch {
-			case nil == err:
-				err = closeErr
-			case errutil.IsContext(ctx):
-				err = flaw.From(errors.New("context has ended")).Join(closeErr)
-			case errors.Is(err, context.DeadlineExceeded):
-				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
-			case errors.Is(err, ErrTooManyRequests):
-				err = flaw.From(errors.New("too many requests")).Join(closeErr)
-			case errutil.IsFlaw(err):
-				err = must.BeFlaw(err).Join(closeErr)
-			default:
-				panic(errutil.UnknownError(err))
-			}
-		}
-	}()
-	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
-
-	switch code := resp.StatusCode; code {
-	case http.StatusOK:
-	case http.StatusUnauthorized:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
 
-		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
-		}
-
-		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
-		}
+	return format, nil
+}
 
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("received 401 response")).Append(flawP)
-	case http.StatusTooManyRequests:
-		return nil, ErrTooManyRequests
-	case http.StatusForbidden:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-		if ok, err := errutil.IsTooManyErrorResponse(resp, respBytes); nil != err {
-			flawP["response_body"] = string(respBytes)
-			return nil, must.BeFlaw(err).Append(flawP)
-		} else if ok {
-			return nil, ErrTooManyRequests
-		}
+type Stream interface {
+	saveTo(ctx context.Context, accessToken string, fileName string, tracker *progress.Tracker) error
+}
 
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("unexpected 403 response")).Append(flawP)
-	default:
-		respBytes, err := httputil.ReadOptionalResponseBody(ctx, resp)
+// errQualityUnavailable is returned by getQualityStream if the track cannot be
+// streamed in the requested quality, e.g., due to account subscription limits.
+var errQualityUnavailable = errors.New("track is not available in requested quality")
+
+// getStream returns the track stream in the requested quality, stepping down to
+// lower quality tiers if the requested one is not available. If immersive is set,
+// Dolby Atmos stream is requested instead of the stereo one. The returned format
+// holds the quality tier and audio mode that is actually delivered.
+func (d *Downloader) getStream(ctx context.Context, id string) (Stream, *tidal.TrackFormat, error) {
+	fallbacks := d.quality.Fallbacks()
+	for _, q := range fallbacks {
+		s, f, err := d.getQualityStream(ctx, id, q)
 		if nil != err {
-			return nil, err
+			if errors.Is(err, errQualityUnavailable) {
+				continue
+			}
+			return nil, nil, err
 		}
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(fmt.Errorf("unexpected status code received from get track cover: %d", code)).Append(flawP)
+		return s, f, nil
 	}
 
-	respBytes, err := httputil.ReadResponseBody(ctx, resp)
-	if nil != err {
-		return nil, err
-	}
-	return respBytes, nil
+	flawP := flaw.P{"id": id, "quality": d.quality, "immersive": d.immersive, "fallbacks": fallbacks}
+	return nil, nil, flaw.From(fmt.Errorf("track is not available in %s or lower qualities", d.quality)).Append(flawP)
 }
 
-func (d *Downloader) getAlbumMeta(ctx context.Context, accessToken, id string) (*tidal.AlbumMeta, error) {
-	cachedAlbumMeta, err := d.albumsMetaCache.Fetch(
-		id,
-		cache.DefaultAlbumTTL,
-		func() (*tidal.AlbumMeta, error) { return fetchAlbumMeta(ctx, accessToken, id) },
-	)
-	if nil != err {
-		return nil, err
-	}
-	return cachedAlbumMeta.Value(), nil
-}
-
-func fetchAlbumMeta(ctx context.Context, accessToken, id string) (m *tidal.AlbumMeta, err error) {
-	albumURL, err := url.JoinPath(fmt.Sprintf(albumAPIFormat, id))
-	if nil != err {
-		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-		return nil, flaw.From(fmt.Errorf("failed to join album base URL with album id: %v", err)).Append(flawP)
-	}
-	flawP := flaw.P{"url": albumURL}
-
-	reqURL, err := url.Parse(albumURL)
-	if nil != err {
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to parse album URL: %v", err)).Append(flawP)
-	}
-
-	params := make(url.Values, 1)
-	params.Add("countryCode", "US")
-	reqURL.RawQuery = params.Encode()
-	flawP["encoded_query_params"] = reqURL.RawQuery
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
-	if nil != err {
-		if errutil.IsContext(ctx) {
-			return nil, ctx.Err()
-		}
-
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to create get album info request: %v", err)).Append(flawP)
-	}
-	req.Header.Add("Authorization", "Bearer "+accessToken)
-
-	client := http.Client{Timeout: config.AlbumMetaRequestTimeout} //nolint:exhaustruct
-	resp, err := client.Do(req)
-	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return nil, ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, context.DeadlineExceeded
-		default:
-			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return nil, flaw.From(fmt.Errorf("failed to send get album info request: %v", err)).Append(flawP)
-		}
-	}
-	defer func() {
-		if closeErr := resp.Body.Close(); nil != closeErr {
-			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
-			closeErr = flaw.From(fmt.Errorf("failed to close get album info response body: %v", closeErr)).Append(flawP)
-			switch {
-			case nil == err:
-				err = closeErr
-			case errutil.IsContext(ctx):
-				err = flaw.From(errors.New("context was ended")).Join(closeErr)
-			case errors.Is(err, context.DeadlineExceeded):
-				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
-			case errors.Is(err, ErrTooManyRequests):
-				err = flaw.From(errors.New("too many requests")).Join(closeErr)
-			case errutil.IsFlaw(err):
-				err = must.BeFlaw(err).Join(closeErr)
-			default:
-				panic(errutil.UnknownError(err))
-			}
-		}
-	}()
-	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
-
-	switch code := resp.StatusCode; code {
-	case http.StatusOK:
-	case http.StatusUnauthorized:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-
-		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
-		}
-
-		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("received 401 response")).Append(flawP)
-	case http.StatusTooManyRequests:
-		return nil, ErrTooManyRequests
-	case http.StatusForbidden:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-		if ok, err := errutil.IsTooManyErrorResponse(resp, respBytes); nil != err {
-			flawP["response_body"] = string(respBytes)
-			return nil, must.BeFlaw(err)
-		} else if ok {
-			return nil, ErrTooManyRequests
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("unexpected 403 response")).Append(flawP)
-	default:
-		respBytes, err := httputil.ReadOptionalResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(fmt.Errorf("unexpected status code: %d", code)).Append(flawP)
-	}
-
-	respBytes, err := httputil.ReadResponseBody(ctx, resp)
-	if nil != err {
-		return nil, err
-	}
-	var respBody struct {
-		Artist struct {
-			Name string `json:"name"`
-		} `json:"artist"`
-		Title        string `json:"title"`
-		ReleaseDate  string `json:"releaseDate"`
-		CoverID      string `json:"cover"`
-		TotalTracks  int    `json:"numberOfTracks"`
-		TotalVolumes int    `json:"numberOfVolumes"`
-	}
-	if err := json.Unmarshal(respBytes, &respBody); nil != err {
-		flawP["response_body"] = string(respBytes)
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to decode album info response: %v", err)).Append(flawP)
-	}
-
-	releaseDate, err := time.Parse("2006-01-02", respBody.ReleaseDate)
-	if nil != err {
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to parse album release date: %v", err)).Append(flawP)
-	}
-
-	return &tidal.AlbumMeta{
-		Artist:       respBody.Artist.Name,
-		Title:        respBody.Title,
-		ReleaseDate:  releaseDate,
-		CoverID:      respBody.CoverID,
-		TotalTracks:  respBody.TotalTracks,
-		TotalVolumes: respBody.TotalVolumes,
-	}, nil
-}
-
-func downloadTrack(ctx context.Context, accessToken, id string, fileName string) (*tidal.TrackFormat, error) {
-	flawP := make(flaw.P)
-	stream, format, err := getStream(ctx, accessToken, id)
-	if nil != err {
-		return nil, err
-	}
-
-	waitTime := ratelimit.TrackDownloadSleepMS()
-	flawP["wait_time"] = waitTime
-	time.Sleep(waitTime)
-
-	if err := stream.saveTo(ctx, accessToken, fileName); nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return nil, ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, context.DeadlineExceeded
-		case errors.Is(err, ErrTooManyRequests):
-			return nil, ErrTooManyRequests
-		case errutil.IsFlaw(err):
-			return nil, must.BeFlaw(err).Append(flawP)
-		default:
-			panic(errutil.UnknownError(err))
-		}
-	}
-
-	return format, nil
-}
-
-type Stream interface {
-	saveTo(ctx context.Context, accessToken string, fileName string) error
-}
-
-func getStream(ctx context.Context, accessToken, id string) (s Stream, f *tidal.TrackFormat, err error) {
-	trackURL := fmt.Sprintf(trackStreamAPIFormat, id)
-	flawP := flaw.P{"url": trackURL}
-
-	reqURL, err := url.Parse(trackURL)
-	if nil != err {
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, nil, flaw.From(fmt.Errorf("failed to parse track URL to build track stream URLs: %v", err)).Append(flawP)
-	}
-
-	params := make(url.Values, 6)
-	params.Add("countryCode", "US")
-	params.Add("audioquality", "HI_RES_LOSSLESS")
-	params.Add("playbackmode", "STREAM")
-	params.Add("assetpresentation", "FULL")
-	params.Add("immersiveaudio", "false")
-	params.Add("locale", "en")
-
-	reqURL.RawQuery = params.Encode()
-	flawP["encoded_query_params"] = reqURL.RawQuery
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
-	if nil != err {
-		if errutil.IsContext(ctx) {
-			return nil, nil, ctx.Err()
-		}
-
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, nil, flaw.From(fmt.Errorf("failed to create get track stream URLs request: %v", err)).Append(flawP)
-	}
-	req.Header.Add("Authorization", "Bearer "+accessToken)
+func (d *Downloader) getQualityStream(ctx context.Context, id string, quality tidal.Quality) (Stream, *tidal.TrackFormat, error) {
+	flawP := flaw.P{"id": id, "quality": quality, "immersive": d.immersive}
 
-	client := http.Client{Timeout: config.GetStreamURLsRequestTimeout} //nolint:exhaustruct
-	resp, err := client.Do(req)
+	respBody, err := d.client.PlaybackInfo(ctx, id, quality, d.immersive)
 	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return nil, nil, ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, nil, context.DeadlineExceeded
-		case errors.Is(err, ErrTooManyRequests):
-			return nil, nil, ErrTooManyRequests
-		default:
-			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return nil, nil, flaw.From(fmt.Errorf("failed to send get track stream URLs request: %v", err)).Append(flawP)
-		}
-	}
-	defer func() {
-		if closeErr := resp.Body.Close(); nil != closeErr {
-			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
-			closeErr = flaw.From(fmt.Errorf("failed to close get track stream URLs response body: %v", closeErr)).Append(flawP)
-			switch {
-			case nil == err:
-				err = closeErr
-			case errutil.IsContext(ctx):
-				err = flaw.From(errors.New("context was ended")).Join(closeErr)
-			case errors.Is(err, context.DeadlineExceeded):
-				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
-			case errors.Is(err, ErrTooManyRequests):
-				err = flaw.From(errors.New("too many requests")).Join(closeErr)
-			case errutil.IsFlaw(err):
-				err = must.BeFlaw(err).Join(closeErr)
-			default:
-				panic(errutil.UnknownError(err))
-			}
+		// Forbidden responses mean the track is not playable in the requested
+		// quality with the current subscription.
+		if errors.Is(err, api.ErrForbidden) {
+			return nil, nil, errQualityUnavailable
 		}
-	}()
-	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
-
-	switch code := resp.StatusCode; code {
-	case http.StatusOK:
-	case http.StatusUnauthorized:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, nil, err
-		}
-
-		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return nil, nil, err
-		} else if ok {
-			return nil, nil, auth.ErrUnauthorized
-		}
-
-		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return nil, nil, err
-		} else if ok {
-			return nil, nil, auth.ErrUnauthorized
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return nil, nil, flaw.From(errors.New("received 401 response")).Append(flawP)
-	case http.StatusTooManyRequests:
-		return nil, nil, ErrTooManyRequests
-	case http.StatusForbidden:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, nil, err
-		}
-		if ok, err := errutil.IsTooManyErrorResponse(resp, respBytes); nil != err {
-			flawP["response_body"] = string(respBytes)
+		if errutil.IsFlaw(err) {
 			return nil, nil, must.BeFlaw(err).Append(flawP)
-		} else if ok {
-			return nil, nil, ErrTooManyRequests
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return nil, nil, flaw.From(errors.New("unexpected 403 response")).Append(flawP)
-	default:
-		respBytes, err := httputil.ReadOptionalResponseBody(ctx, resp)
-		if nil != err {
-			return nil, nil, err
 		}
-		flawP["response_body"] = string(respBytes)
-		return nil, nil, flaw.From(fmt.Errorf("unexpected status code received from get track stream URLs: %d", code)).Append(flawP)
-	}
-
-	respBytes, err := httputil.ReadResponseBody(ctx, resp)
-	if nil != err {
 		return nil, nil, err
 	}
-	var respBody struct {
-		ManifestMimeType string `json:"manifestMimeType"`
-		Manifest         string `json:"manifest"`
+	flawP["stream"] = flaw.P{
+		"manifest_mime_type": respBody.ManifestMimeType,
+		"audio_quality":      respBody.AudioQuality,
+		"audio_mode":         respBody.AudioMode,
 	}
-	if err := json.Unmarshal(respBytes, &respBody); nil != err {
-		flawP["response_body"] = string(respBytes)
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, nil, flaw.From(fmt.Errorf("failed to decode track stream response body: %v", err)).Append(flawP)
+
+	// TIDAL may silently deliver a lower quality than the requested one.
+	delivered := respBody.AudioQuality
+	if delivered == "" {
+		delivered = quality
+	}
+	// Tracks without an immersive version are delivered in stereo.
+	audioMode := respBody.AudioMode
+	if audioMode == "" {
+		audioMode = tidal.AudioModeStereo
 	}
-	flawP["stream"] = flaw.P{"manifest_mime_type": respBody.ManifestMimeType}
 
 	switch mimeType := respBody.ManifestMimeType; mimeType {
 	case "application/dash+xml", "dash+xml":
@@ -1202,9 +596,9 @@ func getStream(ctx context.Context, accessToken, id string) (s Stream, f *tidal.
 		if _, err := tidal.InferTrackExt(info.MimeType, info.Codec); nil != err {
 			return nil, nil, flaw.From(err).Append(flawP)
 		}
-		format := tidal.TrackFormat{MimeType: info.MimeType, Codec: info.Codec}
+		format := tidal.TrackFormat{MimeType: info.MimeType, Codec: info.Codec, Quality: delivered, AudioMode: audioMode}
 
-		return &DashTrackStream{Info: *info}, &format, nil
+		return &DashTrackStream{Info: *info, Quality: delivered}, &format, nil
 	case "application/vnd.tidal.bts", "vnd.tidal.bt":
 		var manifest VNDManifest
 		dec := base64.NewDecoder(base64.StdEncoding, strings.NewReader(respBody.Manifest))
@@ -1220,23 +614,33 @@ func getStream(ctx context.Context, accessToken, id string) (s Stream, f *tidal.
 			"urls":            manifest.URLs,
 		}
 
+		var key *decrypt.Key
 		switch manifest.EncryptionType {
 		case "NONE":
+		case "OLD_AES":
+			if nil == manifest.KeyID {
+				return nil, nil, flaw.From(errors.New("missing key ID of encrypted vnd.tidal.bt manifest")).Append(flawP)
+			}
+			k, err := decrypt.OldAESKey(*manifest.KeyID)
+			if nil != err {
+				return nil, nil, must.BeFlaw(err).Append(flawP)
+			}
+			key = k
 		default:
 			return nil, nil, flaw.
-				From(fmt.Errorf("encrypted vnd.tidal.bt manifest is not yet implemented: %s", manifest.EncryptionType)).
+				From(fmt.Errorf("unsupported vnd.tidal.bt manifest encryption type: %s", manifest.EncryptionType)).
 				Append(flawP)
 		}
 
 		if _, err := tidal.InferTrackExt(manifest.MimeType, manifest.Codec); nil != err {
 			return nil, nil, flaw.From(err).Append(flawP)
 		}
-		format := &tidal.TrackFormat{MimeType: manifest.MimeType, Codec: manifest.Codec}
+		format := &tidal.TrackFormat{MimeType: manifest.MimeType, Codec: manifest.Codec, Quality: delivered, AudioMode: audioMode}
 
 		if len(manifest.URLs) == 0 {
 			return nil, nil, flaw.From(errors.New("empty vnd.tidal.bt manifest URLs")).Append(flawP)
 		}
-		return &VndTrackStream{URL: manifest.URLs[0]}, format, nil
+		return &VndTrackStream{URL: manifest.URLs[0], Key: key}, format, nil
 	default:
 		return nil, nil, flaw.From(fmt.Errorf("unexpected manifest mime type: %s", mimeType)).Append(flawP)
 	}
@@ -1271,7 +675,14 @@ type AlbumTrackMeta struct {
 	Credits      tidal.TrackCredits
 }
 
+func (t AlbumTrackMeta) ref() trackRef {
+	return trackRef{ID: t.ID, Key: fs.TrackKey(t.ID, 0, 0), Title: t.Title}
+}
+
 type ListTrackMeta struct {
+	// Cut is the portion of the track included in the list, or nil if the whole
+	// track is included.
+	Cut          *TrackCut
 	AlbumID      string
 	AlbumTitle   string
 	ISRC         string
@@ -1287,279 +698,212 @@ type ListTrackMeta struct {
 	VolumeNumber int
 }
 
-func (d *Downloader) Playlist(ctx context.Context, id string) error {
-	accessToken, err := d.auth.AccessToken(ctx)
-	if nil != err {
-		return err
+func (t ListTrackMeta) ref() trackRef {
+	return trackRef{ID: t.ID, Key: t.key(), Title: t.Title}
+}
+
+// key returns the key of the track in the track store.
+func (t ListTrackMeta) key() string {
+	if nil == t.Cut {
+		return fs.TrackKey(t.ID, 0, 0)
 	}
+	return fs.TrackKey(t.ID, t.Cut.Start, t.Cut.End)
+}
 
-	playlist, err := getPlaylistMeta(ctx, accessToken, id)
-	if nil != err {
-		return err
+// TrackCut is an edit of a track, which only includes the audio between Start and
+// End offsets. Zero End means the cut lasts until the end of the track.
+type TrackCut struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// duration returns duration of the cut in seconds, given the full track duration.
+func (c TrackCut) duration(trackDuration int) int {
+	end := time.Duration(trackDuration) * time.Second
+	if c.End > 0 && c.End < end {
+		end = c.End
 	}
+	return int((end - c.Start).Round(time.Second).Seconds())
+}
+
+func (c TrackCut) flawP() flaw.P {
+	return flaw.P{"start": c.Start.String(), "end": c.End.String()}
+}
 
-	tracks, err := getPlaylistTracks(ctx, accessToken, id)
+// Playlist downloads streamable tracks of the playlist, passing them in batches
+// to onBatch as they are downloaded, and returns tracks which are skipped as they
+// are not streamable, or failed to download. If sel is not nil, only selected
+// tracks are downloaded, or reported as skipped.
+func (d *Downloader) Playlist(ctx context.Context, id string, sel *tidal.Selection, onBatch BatchFunc) ([]SkippedTrack, error) {
+	playlist, err := d.getPlaylistMeta(ctx, id)
 	if nil != err {
-		return err
+		return nil, err
 	}
 
-	var (
-		playlistFs = d.dir.Playlist(id)
-		wg, wgCtx  = errgroup.WithContext(ctx)
-	)
-
-	wg.SetLimit(ratelimit.PlaylistDownloadConcurrency)
-	for _, track := range tracks {
-		wg.Go(func() (err error) {
-			trackFs := playlistFs.Track(track.ID)
-			if exists, err := trackFs.Cover.Exists(); nil != err {
-				return err
-			} else if !exists {
-				coverBytes, err := d.getCover(ctx, accessToken, track.CoverID)
-				if nil != err {
-					return err
-				}
-				if err := trackFs.Cover.Write(coverBytes); nil != err {
-					return err
-				}
-			}
+	tracks, skipped, err := d.getPlaylistTracks(ctx, id, sel)
+	if nil != err {
+		return nil, err
+	}
 
-			if exists, err := trackFs.Exists(); nil != err {
-				return err
-			} else if exists {
-				return nil
-			}
-			defer func() {
-				if nil != err {
-					if removeErr := trackFs.Remove(); nil != removeErr {
-						flawP := flaw.P{
-							"err_debug_tree": errutil.Tree(removeErr).FlawP(),
-							"path":           trackFs.Path,
-						}
-						err = flaw.From(fmt.Errorf("failed to remove track file: %v", removeErr)).Join(err).Append(flawP)
-					}
-				}
-			}()
-
-			trackCredits, err := d.getTrackCredits(ctx, accessToken, track.ID)
-			if nil != err {
-				return err
-			}
+	playlistFs := d.dir.Playlist(id)
+	info := fs.StoredPlaylist{
+		Caption:   fmt.Sprintf("%s (%d - %d)", playlist.Title, playlist.StartYear, playlist.EndYear),
+		TrackKeys: sliceutil.Map(tracks, ListTrackMeta.key),
+	}
+	if err := playlistFs.InfoFile.Write(info); nil != err {
+		return nil, err
+	}
 
-			trackLyrics, err := fetchTrackLyrics(ctx, accessToken, track.ID)
-			if nil != err {
-				return err
-			}
+	failed, err := d.downloadTracks(
+		ctx,
+		ratelimit.PlaylistDownloadConcurrency,
+		sliceutil.Map(tracks, ListTrackMeta.ref),
+		trackBatches(0, 0, len(tracks)),
+		func(ctx context.Context, accessToken string, i int) error {
+			return d.downloadListTrack(ctx, accessToken, playlistFs.Track(tracks[i].key()), tracks[i])
+		},
+		onBatch,
+	)
+	if nil != err {
+		return nil, err
+	}
 
-			format, err := downloadTrack(wgCtx, accessToken, track.ID, trackFs.Path)
-			if nil != err {
-				return err
-			}
+	return append(skipped, failed...), nil
+}
 
-			album, err := d.getAlbumMeta(ctx, accessToken, track.AlbumID)
-			if nil != err {
-				return err
-			}
+// PlaylistSnapshot returns title of the playlist, and IDs of its streamable
+// tracks in order, without downloading them.
+func (d *Downloader) PlaylistSnapshot(ctx context.Context, id string) (string, []string, error) {
+	playlist, err := d.getPlaylistMeta(ctx, id)
+	if nil != err {
+		return "", nil, err
+	}
 
-			attrs := TrackEmbeddedAttrs{
-				LeadArtist:   track.Artist,
-				Album:        track.AlbumTitle,
-				AlbumArtist:  album.Artist,
-				Artists:      track.Artists,
-				Copyright:    track.Copyright,
-				CoverPath:    trackFs.Cover.Path,
-				Format:       *format,
-				ISRC:         track.ISRC,
-				ReleaseDate:  album.ReleaseDate,
-				Title:        track.Title,
-				TrackNumber:  track.TrackNumber,
-				TotalTracks:  album.TotalTracks,
-				Version:      track.Version,
-				VolumeNumber: track.VolumeNumber,
-				TotalVolumes: album.TotalVolumes,
-				Credits:      *trackCredits,
-				Lyrics:       trackLyrics,
-			}
-			if err := embedTrackAttributes(ctx, trackFs.Path, attrs); nil != err {
-				return err
-			}
+	tracks, _, err := d.getPlaylistTracks(ctx, id, nil)
+	if nil != err {
+		return "", nil, err
+	}
 
-			info := fs.StoredSingleTrack{
-				TrackInfo: fs.TrackInfo{
-					Artists:  track.Artists,
-					Title:    track.Title,
-					Duration: track.Duration,
-					Version:  track.Version,
-					Format:   *format,
-					CoverID:  track.CoverID,
-				},
-				Caption: trackCaption(*album),
-			}
-			if err := trackFs.InfoFile.Write(info); nil != err {
-				return err
-			}
+	return playlist.Title, sliceutil.Map(tracks, func(t ListTrackMeta) string { return t.ID }), nil
+}
 
-			return nil
-		})
+// downloadListTrack downloads a track of a playlist, or mix.
+func (d *Downloader) downloadListTrack(ctx context.Context, accessToken string, trackFs fs.SingleTrack, track ListTrackMeta) (err error) {
+	if exists, err := trackFs.Cover.Exists(); nil != err {
+		return err
+	} else if !exists {
+		coverBytes, err := d.getCover(ctx, track.CoverID)
+		if nil != err {
+			return err
+		}
+		if err := trackFs.Cover.Write(coverBytes); nil != err {
+			return err
+		}
 	}
 
-	if err := wg.Wait(); nil != err {
+	if exists, err := trackFs.Exists(); nil != err {
 		return err
+	} else if exists {
+		return trackFs.Touch()
 	}
-
-	info := fs.StoredPlaylist{
-		Caption:  fmt.Sprintf("%s (%d - %d)", playlist.Title, playlist.StartYear, playlist.EndYear),
-		TrackIDs: sliceutil.Map(tracks, func(t ListTrackMeta) string { return t.ID }),
+	if nil != track.Cut {
+		// Checked upfront to avoid downloading a track which cannot be trimmed.
+		if _, err := exec.LookPath("ffmpeg"); nil != err {
+			return ErrFFmpegRequired
+		}
 	}
-	if err := playlistFs.InfoFile.Write(info); nil != err {
+	defer removeTrackOnError(trackFs.Path, trackFs.Remove, &err)
+
+	trackCredits, err := d.getTrackCredits(ctx, track.ID)
+	if nil != err {
 		return err
 	}
 
-	return nil
-}
-
-func getPlaylistMeta(ctx context.Context, accessToken, id string) (m *PlaylistMeta, err error) {
-	playlistURL, err := url.JoinPath(fmt.Sprintf(playlistAPIFormat, id))
+	trackLyrics, err := d.getTrackLyrics(ctx, track.ID)
 	if nil != err {
-		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-		return nil, flaw.From(fmt.Errorf("failed to join playlist base URL with playlist id: %v", err)).Append(flawP)
+		return err
 	}
-	flawP := flaw.P{"url": playlistURL}
 
-	reqURL, err := url.Parse(playlistURL)
+	format, err := d.downloadTrack(ctx, accessToken, track.ID, trackFs.Path)
 	if nil != err {
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to parse playlist URL: %v", err)).Append(flawP)
+		return err
 	}
 
-	queryParams := make(url.Values, 1)
-	queryParams.Add("countryCode", "US")
-	reqURL.RawQuery = queryParams.Encode()
-	flawP["encoded_query_params"] = reqURL.RawQuery
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
-	if nil != err {
-		if errutil.IsContext(ctx) {
-			return nil, ctx.Err()
+	duration := track.Duration
+	if nil != track.Cut {
+		if err := trimTrack(ctx, trackFs.Path, *format, *track.Cut); nil != err {
+			return err
 		}
-
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to create get playlist info request: %v", err)).Append(flawP)
+		duration = track.Cut.duration(track.Duration)
 	}
-	req.Header.Add("Authorization", "Bearer "+accessToken)
 
-	client := http.Client{Timeout: config.PlaylistMetaRequestTimeout} //nolint:exhaustruct
-	resp, err := client.Do(req)
+	album, err := d.getAlbumMeta(ctx, track.AlbumID)
 	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return nil, ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, context.DeadlineExceeded
-		default:
-			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return nil, flaw.From(fmt.Errorf("failed to send get playlist info request: %v", err)).Append(flawP)
-		}
+		return err
 	}
-	defer func() {
-		if closeErr := resp.Body.Close(); nil != closeErr {
-			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
-			closeErr = flaw.From(fmt.Errorf("failed to close get playlist info response body: %v", closeErr)).Append(flawP)
-			switch {
-			case nil == err:
-				err = closeErr
-			case errutil.IsContext(ctx):
-				err = flaw.From(errors.New("context was ended")).Join(closeErr)
-			case errors.Is(err, context.DeadlineExceeded):
-				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
-			case errors.Is(err, ErrTooManyRequests):
-				err = flaw.From(errors.New("too many requests")).Join(closeErr)
-			case errutil.IsFlaw(err):
-				err = must.BeFlaw(err).Join(closeErr)
-			default:
-				panic(errutil.UnknownError(err))
-			}
-		}
-	}()
-	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
 
-	switch code := resp.StatusCode; code {
-	case http.StatusOK:
-	case http.StatusUnauthorized:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-
-		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
-		}
-
-		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("received 401 response")).Append(flawP)
-	case http.StatusTooManyRequests:
-		return nil, ErrTooManyRequests
-	case http.StatusForbidden:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-		if ok, err := errutil.IsTooManyErrorResponse(resp, respBytes); nil != err {
-			flawP["response_body"] = string(respBytes)
-			return nil, must.BeFlaw(err).Append(flawP)
-		} else if ok {
-			return nil, ErrTooManyRequests
-		}
+	attrs := TrackEmbeddedAttrs{
+		LeadArtist:   track.Artist,
+		Album:        track.AlbumTitle,
+		AlbumArtist:  album.Artist,
+		Artists:      track.Artists,
+		Copyright:    track.Copyright,
+		CoverPath:    trackFs.Cover.Path,
+		Format:       *format,
+		ISRC:         track.ISRC,
+		ReleaseDate:  album.ReleaseDate,
+		Title:        track.Title,
+		TrackNumber:  track.TrackNumber,
+		TotalTracks:  album.TotalTracks,
+		Version:      track.Version,
+		VolumeNumber: track.VolumeNumber,
+		TotalVolumes: album.TotalVolumes,
+		Credits:      *trackCredits,
+		Lyrics:       trackLyrics,
+	}
+	if err := embedTrackAttributes(ctx, trackFs.Path, attrs); nil != err {
+		return err
+	}
 
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("received 403 response")).Append(flawP)
-	default:
-		respBytes, err := httputil.ReadOptionalResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(fmt.Errorf("unexpected status code: %d", code)).Append(flawP)
+	info := fs.StoredSingleTrack{
+		TrackInfo: fs.TrackInfo{
+			Artists:  track.Artists,
+			Title:    track.Title,
+			Duration: duration,
+			Version:  track.Version,
+			Format:   *format,
+			CoverID:  track.CoverID,
+		},
+		Caption: trackCaption(*album),
 	}
+	if err := trackFs.InfoFile.Write(info); nil != err {
+		return err
+	}
+
+	return nil
+}
 
-	respBytes, err := httputil.ReadResponseBody(ctx, resp)
+func (d *Downloader) getPlaylistMeta(ctx context.Context, id string) (*PlaylistMeta, error) {
+	resp, err := d.client.Playlist(ctx, id)
 	if nil != err {
 		return nil, err
 	}
-	var respBody struct {
-		Title       string `json:"title"`
-		Created     string `json:"created"`
-		LastUpdated string `json:"lastUpdated"`
-	}
-	if err := json.Unmarshal(respBytes, &respBody); nil != err {
-		flawP["response_body"] = string(respBytes)
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to decode playlist response: %v", err)).Append(flawP)
-	}
+	flawP := flaw.P{"id": id}
 
-	const dateLayout = "2006-01-02T15:04:05.000-0700"
-	createdAt, err := time.Parse(dateLayout, respBody.Created)
+	createdAt, err := time.Parse(api.PlaylistDateLayout, resp.Created)
 	if nil != err {
 		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
 		return nil, flaw.From(fmt.Errorf("failed to parse playlist created date: %v", err)).Append(flawP)
 	}
 
-	lastUpdatedAt, err := time.Parse(dateLayout, respBody.LastUpdated)
+	lastUpdatedAt, err := time.Parse(api.PlaylistDateLayout, resp.LastUpdated)
 	if nil != err {
 		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
 		return nil, flaw.From(fmt.Errorf("failed to parse playlist last updated date: %v", err)).Append(flawP)
 	}
 
 	return &PlaylistMeta{
-		Title:     respBody.Title,
+		Title:     resp.Title,
 		StartYear: createdAt.Year(),
 		EndYear:   lastUpdatedAt.Year(),
 	}, nil
@@ -1571,130 +915,65 @@ type PlaylistMeta struct {
 	EndYear   int
 }
 
-func getPlaylistTracks(ctx context.Context, accessToken, id string) ([]ListTrackMeta, error) {
-	var tracks []ListTrackMeta
-	var loopFlawPs []flaw.P
-	flawP := flaw.P{"loop_flaw_payloads": loopFlawPs}
-	for i := 0; ; i++ {
-		loopFlawP := flaw.P{"page": i}
-		loopFlawPs = append(loopFlawPs, loopFlawP)
-		flawP["loop_flaw_payloads"] = loopFlawPs
-
-		pageTracks, rem, err := playlistTracksPage(ctx, accessToken, id, i)
-		if nil != err {
-			switch {
-			case errutil.IsContext(ctx):
-				return nil, ctx.Err()
-			case errors.Is(err, os.ErrNotExist):
-				break
-			case errors.Is(err, context.DeadlineExceeded):
-				return nil, context.DeadlineExceeded
-			case errors.Is(err, ErrTooManyRequests):
-				return nil, ErrTooManyRequests
-			case errutil.IsFlaw(err):
-				return nil, must.BeFlaw(err).Append(flawP)
-			default:
-				panic(errutil.UnknownError(err))
-			}
-		}
-		flawP["remaining"] = rem
-
-		tracks = append(tracks, pageTracks...)
-
-		if rem == 0 {
-			break
-		}
-	}
-
-	return tracks, nil
-}
-
-const pageItemTypeTrack = "track"
-
-func playlistTracksPage(ctx context.Context, accessToken, id string, page int) (ts []ListTrackMeta, rem int, err error) {
-	playlistURL, err := url.JoinPath(fmt.Sprintf(playlistItemsAPIFormat, id))
+func (d *Downloader) getPlaylistTracks(ctx context.Context, id string, sel *tidal.Selection) ([]ListTrackMeta, []SkippedTrack, error) {
+	items, err := d.client.PlaylistItems(ctx, id)
 	if nil != err {
-		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-		return nil, 0, flaw.From(fmt.Errorf("failed to create playlist URL: %v", err)).Append(flawP)
+		return nil, nil, err
 	}
-	flawP := flaw.P{"url": playlistURL}
 
-	respBytes, err := getListPagedItems(ctx, accessToken, playlistURL, page)
+	tracks, skipped, err := listTracks(items, sel)
 	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return nil, 0, ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, 0, context.DeadlineExceeded
-		case errors.Is(err, ErrTooManyRequests):
-			return nil, 0, ErrTooManyRequests
-		case errutil.IsFlaw(err):
-			return nil, 0, must.BeFlaw(err).Append(flawP)
-		default:
-			panic(errutil.UnknownError(err))
-		}
-	}
-
-	var respBody struct {
-		TotalNumberOfItems int `json:"totalNumberOfItems"`
-		Items              []struct {
-			Type string `json:"type"`
-			Cut  any    `json:"any"`
-			Item struct {
-				ID           int    `json:"id"`
-				StreamReady  bool   `json:"streamReady"`
-				TrackNumber  int    `json:"trackNumber"`
-				VolumeNumber int    `json:"volumeNumber"`
-				Title        string `json:"title"`
-				ISRC         string `json:"isrc"`
-				Copyright    string `json:"copyright"`
-				Duration     int    `json:"duration"`
-				Artist       struct {
-					Name string `json:"name"`
-				} `json:"artist"`
-				Artists []struct {
-					Name string `json:"name"`
-					Type string `json:"type"`
-				} `json:"artists"`
-				Album struct {
-					ID      int    `json:"id"`
-					CoverID string `json:"cover"`
-					Title   string `json:"title"`
-				} `json:"album"`
-				Version *string `json:"version"`
-			} `json:"item"`
-		} `json:"items"`
-	}
-	if err := json.Unmarshal(respBytes, &respBody); nil != err {
-		flawP["response_body"] = string(respBytes)
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, 0, flaw.From(fmt.Errorf("failed to decode playlist response: %v", err)).Append(flawP)
-	}
-
-	thisPageItemsCount := len(respBody.Items)
-	if thisPageItemsCount == 0 {
-		return nil, 0, os.ErrNotExist
+		return nil, nil, must.BeFlaw(err).Append(flaw.P{"playlist_id": id})
 	}
+	return tracks, skipped, nil
+}
 
-	for _, v := range respBody.Items {
-		if v.Type != pageItemTypeTrack || !v.Item.StreamReady {
+// listTracks returns streamable tracks of playlist, or mix items, and tracks
+// which are skipped as they are not streamable. If sel is not nil, tracks which
+// are not selected by their ID, or position among track items are left out.
+func listTracks(items []api.ListItem, sel *tidal.Selection) ([]ListTrackMeta, []SkippedTrack, error) {
+	var (
+		ts       []ListTrackMeta
+		skipped  []SkippedTrack
+		position int
+		includes = sel.Matcher()
+	)
+	for _, v := range items {
+		if v.Type != api.ItemTypeTrack {
 			continue
 		}
-		if v.Cut != nil {
-			return nil, 0, flaw.From(errors.New("cut items are not supported")).Append(flawP)
+		position++
+		if !includes(0, position, strconv.Itoa(v.Item.ID)) {
+			continue
 		}
+		if !v.Item.StreamReady {
+			skipped = append(skipped, notStreamReadyTrack(strconv.Itoa(v.Item.ID), v.Item.Title))
+			continue
+		}
+		flawP := flaw.P{"track_id": v.Item.ID}
 
-		artists := make([]tidal.TrackArtist, len(v.Item.Artists))
-		for i, a := range v.Item.Artists {
-			switch a.Type {
-			case tidal.ArtistTypeMain, tidal.ArtistTypeFeatured:
-			default:
-				return nil, 0, flaw.From(fmt.Errorf("unexpected artist type: %s", a.Type)).Append(flawP)
+		var cut *TrackCut
+		if nil != v.Cut {
+			cut = &TrackCut{
+				Start: time.Duration(v.Cut.Start * float64(time.Second)),
+				End:   0,
+			}
+			if nil != v.Cut.End {
+				cut.End = time.Duration(*v.Cut.End * float64(time.Second))
+			}
+			if cut.Start < 0 || (cut.End > 0 && cut.End <= cut.Start) {
+				flawP["cut"] = cut.flawP()
+				return nil, nil, flaw.From(errors.New("invalid item cut offsets")).Append(flawP)
 			}
-			artists[i] = tidal.TrackArtist{Name: a.Name, Type: a.Type}
+		}
+
+		artists, err := api.TrackArtists(v.Item.Artists)
+		if nil != err {
+			return nil, nil, must.BeFlaw(err).Append(flawP)
 		}
 
 		t := ListTrackMeta{
+			Cut:          cut,
 			AlbumID:      strconv.Itoa(v.Item.Album.ID),
 			AlbumTitle:   v.Item.Album.Title,
 			ISRC:         v.Item.ISRC,
@@ -1711,683 +990,263 @@ func playlistTracksPage(ctx context.Context, accessToken, id string, page int) (
 		}
 		ts = append(ts, t)
 	}
-
-	return ts, respBody.TotalNumberOfItems - (thisPageItemsCount + page*pageSize), nil
+	return ts, skipped, nil
 }
 
-func (d *Downloader) Mix(ctx context.Context, id string) error {
-	accessToken, err := d.auth.AccessToken(ctx)
+// Mix downloads streamable tracks of the mix, passing them in batches to onBatch
+// as they are downloaded, and returns tracks which are skipped as they are not
+// streamable, or failed to download. If sel is not nil, only selected tracks are
+// downloaded, or reported as skipped.
+func (d *Downloader) Mix(ctx context.Context, id string, sel *tidal.Selection, onBatch BatchFunc) ([]SkippedTrack, error) {
+	mix, err := d.getMixMeta(ctx, id)
 	if nil != err {
-		return err
+		return nil, err
 	}
 
-	flawP := flaw.P{}
-
-	mix, err := getMixMeta(ctx, accessToken, id)
+	tracks, skipped, err := d.getMixTracks(ctx, id, sel)
 	if nil != err {
-		return err
+		return nil, err
 	}
 
-	tracks, err := getMixTracks(ctx, accessToken, id)
-	if nil != err {
-		return err
+	mixFs := d.dir.Mix(id)
+	info := fs.StoredMix{
+		Caption:   mix.Title,
+		TrackKeys: sliceutil.Map(tracks, ListTrackMeta.key),
+	}
+	if err := mixFs.InfoFile.Write(info); nil != err {
+		return nil, err
 	}
 
-	var (
-		mixFs     = d.dir.Mix(id)
-		wg, wgCtx = errgroup.WithContext(ctx)
+	failed, err := d.downloadTracks(
+		ctx,
+		ratelimit.MixDownloadConcurrency,
+		sliceutil.Map(tracks, ListTrackMeta.ref),
+		trackBatches(0, 0, len(tracks)),
+		func(ctx context.Context, accessToken string, i int) error {
+			return d.downloadListTrack(ctx, accessToken, mixFs.Track(tracks[i].key()), tracks[i])
+		},
+		onBatch,
 	)
-
-	wg.SetLimit(ratelimit.MixDownloadConcurrency)
-	for _, track := range tracks {
-		wg.Go(func() (err error) {
-			trackFs := mixFs.Track(track.ID)
-			if exists, err := trackFs.Cover.Exists(); nil != err {
-				return err
-			} else if !exists {
-				coverBytes, err := d.getCover(ctx, accessToken, track.CoverID)
-				if nil != err {
-					return err
-				}
-				if err := trackFs.Cover.Write(coverBytes); nil != err {
-					return err
-				}
-			}
-
-			if exists, err := trackFs.Exists(); nil != err {
-				return err
-			} else if exists {
-				return nil
-			}
-			defer func() {
-				if nil != err {
-					if removeErr := trackFs.Remove(); nil != removeErr && !errors.Is(err, os.ErrNotExist) {
-						err = flaw.From(fmt.Errorf("failed to remove track file: %v", removeErr)).Append(flawP)
-					}
-				}
-			}()
-
-			trackCredits, err := d.getTrackCredits(ctx, accessToken, track.ID)
-			if nil != err {
-				return err
-			}
-
-			trackLyrics, err := fetchTrackLyrics(ctx, accessToken, track.ID)
-			if nil != err {
-				return err
-			}
-
-			format, err := downloadTrack(wgCtx, accessToken, track.ID, trackFs.Path)
-			if nil != err {
-				return err
-			}
-
-			album, err := d.getAlbumMeta(ctx, accessToken, track.AlbumID)
-			if nil != err {
-				return err
-			}
-
-			attrs := TrackEmbeddedAttrs{
-				LeadArtist:   track.Artist,
-				Album:        track.AlbumTitle,
-				AlbumArtist:  album.Artist,
-				Artists:      track.Artists,
-				Copyright:    track.Copyright,
-				CoverPath:    trackFs.Cover.Path,
-				Format:       *format,
-				ISRC:         track.ISRC,
-				ReleaseDate:  album.ReleaseDate,
-				Title:        track.Title,
-				TrackNumber:  track.TrackNumber,
-				TotalTracks:  album.TotalTracks,
-				Version:      track.Version,
-				VolumeNumber: track.VolumeNumber,
-				TotalVolumes: album.TotalVolumes,
-				Credits:      *trackCredits,
-				Lyrics:       trackLyrics,
-			}
-			if err := embedTrackAttributes(ctx, trackFs.Path, attrs); nil != err {
-				return err
-			}
-
-			info := fs.StoredSingleTrack{
-				TrackInfo: fs.TrackInfo{
-					Artists:  track.Artists,
-					Title:    track.Title,
-					Duration: track.Duration,
-					Version:  track.Version,
-					Format:   *format,
-					CoverID:  track.CoverID,
-				},
-				Caption: trackCaption(*album),
-			}
-			if err := trackFs.InfoFile.Write(info); nil != err {
-				return err
-			}
-
-			return nil
-		})
+	if nil != err {
+		return nil, err
 	}
 
-	if err := wg.Wait(); nil != err {
-		return err
-	}
+	return append(skipped, failed...), nil
+}
 
-	info := fs.StoredMix{
-		Caption:  mix.Title,
-		TrackIDs: sliceutil.Map(tracks, func(t ListTrackMeta) string { return t.ID }),
-	}
-	if err := mixFs.InfoFile.Write(info); nil != err {
-		return err
+func (d *Downloader) getMixMeta(ctx context.Context, id string) (*MixMeta, error) {
+	resp, err := d.client.Mix(ctx, id)
+	if nil != err {
+		return nil, err
 	}
+	return &MixMeta{Title: resp.Title}, nil
+}
 
-	return nil
+type MixMeta struct {
+	Title string
 }
 
-func getMixMeta(ctx context.Context, accessToken, id string) (m *MixMeta, err error) {
-	flawP := flaw.P{}
-	reqURL, err := url.Parse(mixInfoURL)
+func (d *Downloader) getMixTracks(ctx context.Context, id string, sel *tidal.Selection) ([]ListTrackMeta, []SkippedTrack, error) {
+	items, err := d.client.MixItems(ctx, id)
 	if nil != err {
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to parse playlist URL: %v", err)).Append(flawP)
+		return nil, nil, err
 	}
 
-	reqParams := make(url.Values, 4)
-	reqParams.Add("mixId", id)
-	reqParams.Add("countryCode", "US")
-	reqParams.Add("locale", "en_US")
-	reqParams.Add("deviceType", "BROWSER")
-	reqURL.RawQuery = reqParams.Encode()
-	flawP["encoded_query_params"] = reqURL.RawQuery
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
+	tracks, skipped, err := listTracks(items, sel)
 	if nil != err {
-		if errutil.IsContext(ctx) {
-			return nil, ctx.Err()
-		}
-
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to create get mix info request: %v", err)).Append(flawP)
+		return nil, nil, must.BeFlaw(err).Append(flaw.P{"mix_id": id})
 	}
-	req.Header.Add("Authorization", "Bearer "+accessToken)
-	req.Header.Add("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:132.0) Gecko/20100101 Firefox/132.0")
-	req.Header.Add("Accept", "application/json")
+	return tracks, skipped, nil
+}
 
-	client := http.Client{Timeout: config.MixMetaRequestTimeout} //nolint:exhaustruct
-	resp, err := client.Do(req)
+// Album downloads streamable tracks of the album, passing them in batches of
+// each volume to onBatch as they are downloaded, and returns tracks which are
+// skipped as they are not streamable, or failed to download. If sel is not nil,
+// only selected tracks are downloaded, or reported as skipped.
+func (d *Downloader) Album(ctx context.Context, id string, sel *tidal.Selection, onBatch BatchFunc) ([]SkippedTrack, error) {
+	album, err := d.getAlbumMeta(ctx, id)
 	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return nil, ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, context.DeadlineExceeded
-		default:
-			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return nil, flaw.From(fmt.Errorf("failed to send get mix info request: %v", err)).Append(flawP)
-		}
+		return nil, err
 	}
-	defer func() {
-		if closeErr := resp.Body.Close(); nil != closeErr {
-			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
-			closeErr = flaw.From(fmt.Errorf("failed to close get mix info response body: %v", closeErr)).Append(flawP)
-			switch {
-			case nil == err:
-				err = closeErr
-			case errutil.IsContext(ctx):
-				err = flaw.From(errors.New("context was ended")).Join(closeErr)
-			case errors.Is(err, context.DeadlineExceeded):
-				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
-			case errors.Is(err, ErrTooManyRequests):
-				err = flaw.From(errors.New("too many requests")).Join(closeErr)
-			case errutil.IsFlaw(err):
-				err = must.BeFlaw(err).Join(closeErr)
-			default:
-				panic(errutil.UnknownError(err))
-			}
-		}
-	}()
-	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
-
-	switch code := resp.StatusCode; code {
-	case http.StatusOK:
-	case http.StatusUnauthorized:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
-		}
-
-		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
-		}
 
-		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("received 401 response")).Append(flawP)
-	case http.StatusTooManyRequests:
-		return nil, ErrTooManyRequests
-	case http.StatusForbidden:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
+	albumFs := d.dir.Album(id)
+	if exists, err := albumFs.Cover.Exists(); nil != err {
+		return nil, err
+	} else if !exists {
+		coverBytes, err := d.getCover(ctx, album.CoverID)
 		if nil != err {
 			return nil, err
 		}
-		if ok, err := errutil.IsTooManyErrorResponse(resp, respBytes); nil != err {
-			flawP["response_body"] = string(respBytes)
-			return nil, must.BeFlaw(err).Append(flawP)
-		} else if ok {
-			return nil, ErrTooManyRequests
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("unexpected 403 response")).Append(flawP)
-	default:
-		respBytes, err := httputil.ReadOptionalResponseBody(ctx, resp)
-		if nil != err {
+		if err := albumFs.Cover.Write(coverBytes); nil != err {
 			return nil, err
 		}
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(fmt.Errorf("unexpected status code: %d", code)).Append(flawP)
 	}
 
-	respBytes, err := httputil.ReadResponseBody(ctx, resp)
+	volumes, skipped, err := d.getAlbumVolumes(ctx, id, sel)
 	if nil != err {
 		return nil, err
 	}
 
-	if !gjson.ValidBytes(respBytes) {
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(fmt.Errorf("invalid mix info response json: %v", err)).Append(flawP)
+	for _, volTracks := range volumes {
+		for _, track := range volTracks {
+			d.trackCreditsCache.Set(track.ID, &track.Credits, d.trackCreditsCache.TTL())
+		}
 	}
 
-	var title string
-	switch titleKey := gjson.GetBytes(respBytes, "title"); titleKey.Type { //nolint:exhaustive
-	case gjson.String:
-		title = titleKey.Str
-	default:
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(fmt.Errorf("unexpected mix info response: %v", err)).Append(flawP)
+	info := fs.StoredAlbum{
+		Caption: fmt.Sprintf("%s (%s)", album.Title, album.ReleaseDate.Format(tidal.ReleaseDateLayout)),
+		VolumeTrackIDs: sliceutil.Map(volumes, func(tracks []AlbumTrackMeta) []string {
+			return sliceutil.Map(tracks, func(t AlbumTrackMeta) string { return t.ID })
+		}),
+	}
+	if err := albumFs.InfoFile.Write(info); nil != err {
+		return nil, err
 	}
 
-	return &MixMeta{Title: title}, nil
-}
-
-type MixMeta struct {
-	Title string
-}
-
-func getMixTracks(ctx context.Context, accessToken, id string) ([]ListTrackMeta, error) {
 	var (
-		tracks     []ListTrackMeta
-		loopFlawPs []flaw.P
-		flawP      = flaw.P{"loop_flaw_payloads": loopFlawPs}
+		tracks  []AlbumTrackMeta
+		batches []trackBatch
+	)
+	for i, volTracks := range volumes {
+		batches = append(batches, trackBatches(i+1, len(tracks), len(volTracks))...)
+		tracks = append(tracks, volTracks...)
+	}
+	failed, err := d.downloadTracks(
+		ctx,
+		ratelimit.AlbumDownloadConcurrency,
+		sliceutil.Map(tracks, AlbumTrackMeta.ref),
+		batches,
+		func(ctx context.Context, accessToken string, i int) error {
+			return d.downloadAlbumTrack(ctx, accessToken, *album, albumFs, tracks[i])
+		},
+		onBatch,
 	)
-
-	for i := 0; ; i++ {
-		loopFlawP := flaw.P{"page": i}
-		loopFlawPs = append(loopFlawPs, loopFlawP)
-
-		pageTracks, rem, err := mixTracksPage(ctx, accessToken, id, i)
-		if nil != err {
-			switch {
-			case errutil.IsContext(ctx):
-				return nil, ctx.Err()
-			case errors.Is(err, os.ErrNotExist):
-				break
-			case errors.Is(err, context.DeadlineExceeded):
-				return nil, context.DeadlineExceeded
-			case errors.Is(err, ErrTooManyRequests):
-				return nil, ErrTooManyRequests
-			case errutil.IsFlaw(err):
-				return nil, must.BeFlaw(err).Append(flawP)
-			default:
-				panic(errutil.UnknownError(err))
-			}
-		}
-		tracks = append(tracks, pageTracks...)
-
-		if rem == 0 {
-			break
-		}
-	}
-
-	return tracks, nil
-}
-
-func mixTracksPage(ctx context.Context, accessToken, id string, page int) (ts []ListTrackMeta, rem int, err error) {
-	mixURL, err := url.JoinPath(fmt.Sprintf(mixItemsAPIFormat, id))
-	if nil != err {
-		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-		return nil, 0, flaw.From(fmt.Errorf("failed to create mix URL: %v", err)).Append(flawP)
-	}
-	flawP := flaw.P{"mix_url": mixURL}
-
-	respBytes, err := getListPagedItems(ctx, accessToken, mixURL, page)
 	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return nil, 0, ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, 0, context.DeadlineExceeded
-		case errors.Is(err, ErrTooManyRequests):
-			return nil, 0, ErrTooManyRequests
-		case errutil.IsFlaw(err):
-			return nil, 0, must.BeFlaw(err).Append(flawP)
-		default:
-			panic(errutil.UnknownError(err))
-		}
-	}
-
-	var respBody struct {
-		TotalNumberOfItems int `json:"totalNumberOfItems"`
-		Items              []struct {
-			Type string `json:"type"`
-			Item struct {
-				ID           int    `json:"id"`
-				StreamReady  bool   `json:"streamReady"`
-				TrackNumber  int    `json:"trackNumber"`
-				VolumeNumber int    `json:"volumeNumber"`
-				Title        string `json:"title"`
-				Copyright    string `json:"copyright"`
-				ISRC         string `json:"isrc"`
-				Duration     int    `json:"duration"`
-				Artist       struct {
-					Name string `json:"name"`
-				} `json:"artist"`
-				Artists []struct {
-					Name string `json:"name"`
-					Type string `json:"type"`
-				} `json:"artists"`
-				Album struct {
-					ID    int    `json:"id"`
-					Cover string `json:"cover"`
-					Title string `json:"title"`
-				} `json:"album"`
-				Version *string `json:"version"`
-			} `json:"item"`
-		} `json:"items"`
-	}
-	if err := json.Unmarshal(respBytes, &respBody); nil != err {
-		flawP["response_body"] = string(respBytes)
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, 0, flaw.From(fmt.Errorf("failed to decode mix response: %v", err)).Append(flawP)
-	}
-
-	thisPageItemsCount := len(respBody.Items)
-	if thisPageItemsCount == 0 {
-		return nil, 0, nil
-	}
-
-	for _, v := range respBody.Items {
-		if v.Type != pageItemTypeTrack || !v.Item.StreamReady {
-			continue
-		}
-
-		artists := make([]tidal.TrackArtist, len(v.Item.Artists))
-		for i, a := range v.Item.Artists {
-			switch a.Type {
-			case tidal.ArtistTypeMain, tidal.ArtistTypeFeatured:
-			default:
-				return nil, 0, flaw.From(fmt.Errorf("unexpected artist type: %s", a.Type)).Append(flawP)
-			}
-			artists[i] = tidal.TrackArtist{Name: a.Name, Type: a.Type}
-		}
-
-		t := ListTrackMeta{
-			AlbumID:      strconv.Itoa(v.Item.Album.ID),
-			AlbumTitle:   v.Item.Album.Title,
-			ISRC:         v.Item.ISRC,
-			Copyright:    v.Item.Copyright,
-			Artist:       v.Item.Artist.Name,
-			Artists:      artists,
-			CoverID:      v.Item.Album.Cover,
-			Duration:     v.Item.Duration,
-			ID:           strconv.Itoa(v.Item.ID),
-			Title:        v.Item.Title,
-			TrackNumber:  v.Item.TrackNumber,
-			Version:      v.Item.Version,
-			VolumeNumber: v.Item.VolumeNumber,
-		}
-		ts = append(ts, t)
+		return nil, err
 	}
 
-	return ts, respBody.TotalNumberOfItems - (thisPageItemsCount + page*pageSize), nil
+	return append(skipped, failed...), nil
 }
 
-func (d *Downloader) Album(ctx context.Context, id string) error {
-	accessToken, err := d.auth.AccessToken(ctx)
-	if nil != err {
+func (d *Downloader) downloadAlbumTrack(ctx context.Context, accessToken string, album tidal.AlbumMeta, albumFs fs.Album, track AlbumTrackMeta) (err error) {
+	trackFs := albumFs.Track(track.ID)
+	if exists, err := trackFs.Exists(); nil != err {
 		return err
+	} else if exists {
+		return trackFs.Touch()
 	}
+	defer removeTrackOnError(trackFs.Path, trackFs.Remove, &err)
 
-	album, err := d.getAlbumMeta(ctx, accessToken, id)
+	trackLyrics, err := d.getTrackLyrics(ctx, track.ID)
 	if nil != err {
 		return err
 	}
 
-	albumFs := d.dir.Album(id)
-	if exists, err := albumFs.Cover.Exists(); nil != err {
-		return err
-	} else if !exists {
-		coverBytes, err := d.getCover(ctx, accessToken, album.CoverID)
-		if nil != err {
-			return err
-		}
-		if err := albumFs.Cover.Write(coverBytes); nil != err {
-			return err
-		}
-	}
-
-	volumes, err := getAlbumVolumes(ctx, accessToken, id)
+	format, err := d.downloadTrack(ctx, accessToken, track.ID, trackFs.Path)
 	if nil != err {
 		return err
 	}
 
-	for _, volTracks := range volumes {
-		for _, track := range volTracks {
-			d.trackCreditsCache.Set(track.ID, &track.Credits, cache.DefaultTrackCreditsTTL)
-		}
-	}
-
-	var (
-		wg, wgCtx           = errgroup.WithContext(ctx)
-		albumVolumeTrackIDs = make([][]string, len(volumes))
-	)
-
-	wg.SetLimit(ratelimit.AlbumDownloadConcurrency)
-	for i, tracks := range volumes {
-		albumVolumeTrackIDs[i] = sliceutil.Map(tracks, func(t AlbumTrackMeta) string { return t.ID })
-
-		volNum := i + 1
-		for _, track := range tracks {
-			wg.Go(func() (err error) {
-				trackFs := albumFs.Track(volNum, track.ID)
-				if exists, err := trackFs.Exists(); nil != err {
-					return err
-				} else if exists {
-					return nil
-				}
-				defer func() {
-					if nil != err {
-						if removeErr := trackFs.Remove(); nil != removeErr {
-							flawP := flaw.P{
-								"err_debug_tree": errutil.Tree(removeErr).FlawP(),
-								"path":           trackFs.Path,
-							}
-							err = flaw.From(fmt.Errorf("failed to remove track file: %v", removeErr)).Join(err).Append(flawP)
-						}
-					}
-				}()
-
-				trackLyrics, err := fetchTrackLyrics(ctx, accessToken, id)
-				if nil != err {
-					return err
-				}
-
-				format, err := downloadTrack(wgCtx, accessToken, track.ID, trackFs.Path)
-				if nil != err {
-					return err
-				}
-
-				attrs := TrackEmbeddedAttrs{
-					LeadArtist:   track.Artist,
-					Album:        album.Title,
-					AlbumArtist:  album.Artist,
-					Artists:      track.Artists,
-					Copyright:    track.Copyright,
-					CoverPath:    albumFs.Cover.Path,
-					Format:       *format,
-					ISRC:         track.ISRC,
-					ReleaseDate:  album.ReleaseDate,
-					Title:        track.Title,
-					TrackNumber:  track.TrackNumber,
-					TotalTracks:  album.TotalTracks,
-					Version:      track.Version,
-					VolumeNumber: track.VolumeNumber,
-					TotalVolumes: album.TotalVolumes,
-					Credits:      track.Credits,
-					Lyrics:       trackLyrics,
-				}
-				if err := embedTrackAttributes(ctx, trackFs.Path, attrs); nil != err {
-					return err
-				}
-
-				info := fs.StoredSingleTrack{
-					TrackInfo: fs.TrackInfo{
-						Artists:  track.Artists,
-						Title:    track.Title,
-						Duration: track.Duration,
-						Version:  track.Version,
-						Format:   *format,
-						CoverID:  album.CoverID,
-					},
-					Caption: trackCaption(*album),
-				}
-				if err := trackFs.InfoFile.Write(info); nil != err {
-					return err
-				}
-
-				return nil
-			})
-		}
+	attrs := TrackEmbeddedAttrs{
+		LeadArtist:   track.Artist,
+		Album:        album.Title,
+		AlbumArtist:  album.Artist,
+		Artists:      track.Artists,
+		Copyright:    track.Copyright,
+		CoverPath:    albumFs.Cover.Path,
+		Format:       *format,
+		ISRC:         track.ISRC,
+		ReleaseDate:  album.ReleaseDate,
+		Title:        track.Title,
+		TrackNumber:  track.TrackNumber,
+		TotalTracks:  album.TotalTracks,
+		Version:      track.Version,
+		VolumeNumber: track.VolumeNumber,
+		TotalVolumes: album.TotalVolumes,
+		Credits:      track.Credits,
+		Lyrics:       trackLyrics,
 	}
-
-	if err := wg.Wait(); nil != err {
+	if err := embedTrackAttributes(ctx, trackFs.Path, attrs); nil != err {
 		return err
 	}
 
-	info := fs.StoredAlbum{
-		Caption:        fmt.Sprintf("%s (%s)", album.Title, album.ReleaseDate.Format(tidal.ReleaseDateLayout)),
-		VolumeTrackIDs: albumVolumeTrackIDs,
+	info := fs.StoredSingleTrack{
+		TrackInfo: fs.TrackInfo{
+			Artists:  track.Artists,
+			Title:    track.Title,
+			Duration: track.Duration,
+			Version:  track.Version,
+			Format:   *format,
+			CoverID:  album.CoverID,
+		},
+		Caption: trackCaption(album),
 	}
-	if err := albumFs.InfoFile.Write(info); nil != err {
+	if err := trackFs.InfoFile.Write(info); nil != err {
 		return err
 	}
 
 	return nil
 }
 
-func getAlbumVolumes(ctx context.Context, accessToken, id string) ([][]AlbumTrackMeta, error) {
+// getAlbumVolumes returns streamable tracks of each album volume, and tracks
+// which are skipped as they are not streamable. If sel is not nil, tracks which
+// are not selected are left out, leaving volumes without selected tracks empty.
+func (d *Downloader) getAlbumVolumes(ctx context.Context, id string, sel *tidal.Selection) ([][]AlbumTrackMeta, []SkippedTrack, error) {
+	items, err := d.client.AlbumItems(ctx, id)
+	if nil != err {
+		return nil, nil, err
+	}
+
 	var (
 		tracks              [][]AlbumTrackMeta
 		currentVolumeTracks []AlbumTrackMeta
 		currentVolume       = 1
+		skipped             []SkippedTrack
+		flawP               = flaw.P{"album_id": id}
+		// position, and volumePosition are positions of the track among track
+		// items of the album, and its volume respectively.
+		position       int
+		volumePosition int
+		includes       = sel.Matcher()
 	)
-
-	loopFlawPs := []flaw.P{}
-	flawP := flaw.P{"loop_flaws": loopFlawPs}
-
-	for i := 0; ; i++ {
-		loopFlaw := flaw.P{"page": i}
-		loopFlawPs = append(loopFlawPs, loopFlaw)
-		flawP["loop_flaws"] = loopFlawPs
-		pageTracks, rem, err := albumTracksPage(ctx, accessToken, id, i)
-		if nil != err {
-			switch {
-			case errutil.IsContext(ctx):
-				return nil, ctx.Err()
-			case errors.Is(err, os.ErrNotExist):
-				break
-			case errors.Is(err, context.DeadlineExceeded):
-				return nil, context.DeadlineExceeded
-			case errors.Is(err, ErrTooManyRequests):
-				return nil, ErrTooManyRequests
-			case errutil.IsFlaw(err):
-				return nil, must.BeFlaw(err).Append(flawP)
-			default:
-				panic(errutil.UnknownError(err))
-			}
-		}
-		loopFlaw["remaining"] = rem
-
-		for _, track := range pageTracks {
-			switch track.VolumeNumber {
-			case currentVolume:
-				currentVolumeTracks = append(currentVolumeTracks, track)
-			case currentVolume + 1:
-				tracks = append(tracks, currentVolumeTracks)
-				currentVolumeTracks = []AlbumTrackMeta{track}
-				currentVolume++
-			default:
-				return nil, flaw.From(fmt.Errorf("unexpected volume number: %d", track.VolumeNumber)).Append(flawP)
-			}
-		}
-
-		if rem == 0 {
-			break
+	for _, v := range items {
+		if v.Type != api.ItemTypeTrack {
+			continue
 		}
-	}
 
-	tracks = append(tracks, currentVolumeTracks)
-
-	return tracks, nil
-}
-
-func albumTracksPage(ctx context.Context, accessToken, id string, page int) (ts []AlbumTrackMeta, rem int, err error) {
-	albumURL, err := url.JoinPath(fmt.Sprintf(albumItemsCreditsAPIFormat, id))
-	if nil != err {
-		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-		return nil, 0, flaw.From(fmt.Errorf("failed to join album tracks credits URL with id: %v", err)).Append(flawP)
-	}
-	flawP := flaw.P{"url": albumURL}
-
-	respBytes, err := getAlbumPagedItems(ctx, accessToken, albumURL, page)
-	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return nil, 0, ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, 0, context.DeadlineExceeded
-		case errors.Is(err, ErrTooManyRequests):
-			return nil, 0, ErrTooManyRequests
-		case errutil.IsFlaw(err):
-			return nil, 0, must.BeFlaw(err).Append(flawP)
+		switch v.Item.VolumeNumber {
+		case currentVolume:
+		case currentVolume + 1:
+			tracks = append(tracks, currentVolumeTracks)
+			currentVolumeTracks = nil
+			currentVolume++
+			volumePosition = 0
 		default:
-			panic(errutil.UnknownError(err))
+			return nil, nil, flaw.From(fmt.Errorf("unexpected volume number: %d", v.Item.VolumeNumber)).Append(flawP)
+		}
+		position++
+		volumePosition++
+		if nil != sel {
+			pos := position
+			if sel.Disc != 0 {
+				pos = volumePosition
+			}
+			if !includes(currentVolume, pos, strconv.Itoa(v.Item.ID)) {
+				continue
+			}
 		}
-	}
-
-	var respBody struct {
-		TotalNumberOfItems int `json:"totalNumberOfItems"`
-		Items              []struct {
-			Type    string               `json:"type"`
-			Credits TrackCreditsResponse `json:"credits"`
-			Item    struct {
-				ID           int    `json:"id"`
-				StreamReady  bool   `json:"streamReady"`
-				TrackNumber  int    `json:"trackNumber"`
-				VolumeNumber int    `json:"volumeNumber"`
-				Title        string `json:"title"`
-				Copyright    string `json:"copyright"`
-				ISRC         string `json:"isrc"`
-				Duration     int    `json:"duration"`
-				Artist       struct {
-					Name string `json:"name"`
-				} `json:"artist"`
-				Artists []struct {
-					Name string `json:"name"`
-					Type string `json:"type"`
-				} `json:"artists"`
-				Album struct {
-					ID    int    `json:"id"`
-					Cover string `json:"cover"`
-				} `json:"album"`
-				Version *string `json:"version"`
-			} `json:"item"`
-		} `json:"items"`
-	}
-	if err := json.Unmarshal(respBytes, &respBody); nil != err {
-		flawP["response_body"] = string(respBytes)
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, 0, flaw.From(fmt.Errorf("failed to decode album items page response: %v", err)).Append(flawP)
-	}
-
-	thisPageItemsCount := len(respBody.Items)
-	if thisPageItemsCount == 0 {
-		return nil, 0, os.ErrNotExist
-	}
 
-	for _, v := range respBody.Items {
-		if v.Type != pageItemTypeTrack || !v.Item.StreamReady {
+		if !v.Item.StreamReady {
+			skipped = append(skipped, notStreamReadyTrack(strconv.Itoa(v.Item.ID), v.Item.Title))
 			continue
 		}
 
-		artists := make([]tidal.TrackArtist, len(v.Item.Artists))
-		for i, a := range v.Item.Artists {
-			switch a.Type {
-			case tidal.ArtistTypeMain, tidal.ArtistTypeFeatured:
-			default:
-				return nil, 0, flaw.From(fmt.Errorf("unexpected artist type: %s", a.Type)).Append(flawP)
-			}
-			artists[i] = tidal.TrackArtist{Name: a.Name, Type: a.Type}
+		artists, err := api.TrackArtists(v.Item.Artists)
+		if nil != err {
+			return nil, nil, must.BeFlaw(err).Append(flawP)
 		}
 
-		t := AlbumTrackMeta{
+		track := AlbumTrackMeta{
 			Artist:       v.Item.Artist.Name,
 			Artists:      artists,
 			Duration:     v.Item.Duration,
@@ -2398,136 +1257,95 @@ func albumTracksPage(ctx context.Context, accessToken, id string, page int) (ts
 			TrackNumber:  v.Item.TrackNumber,
 			Version:      v.Item.Version,
 			VolumeNumber: v.Item.VolumeNumber,
-			Credits:      v.Credits.toTrackCredits(),
+			Credits:      v.Credits.TrackCredits(),
 		}
-		ts = append(ts, t)
+
+		currentVolumeTracks = append(currentVolumeTracks, track)
 	}
 
-	return ts, respBody.TotalNumberOfItems - (thisPageItemsCount + page*pageSize), nil
-}
+	tracks = append(tracks, currentVolumeTracks)
 
-func getAlbumPagedItems(ctx context.Context, accessToken, itemsURL string, page int) ([]byte, error) {
-	reqParams := make(url.Values, 3)
-	reqParams.Add("countryCode", "US")
-	reqParams.Add("limit", strconv.Itoa(pageSize))
-	reqParams.Add("offset", strconv.Itoa(page*pageSize))
-	return getPagedItems(ctx, accessToken, itemsURL, reqParams)
+	return tracks, skipped, nil
 }
 
-func getListPagedItems(ctx context.Context, accessToken, itemsURL string, page int) ([]byte, error) {
-	reqParams := make(url.Values, 3)
-	reqParams.Add("countryCode", "US")
-	reqParams.Add("limit", strconv.Itoa(pageSize))
-	reqParams.Add("offset", strconv.Itoa(page*pageSize))
-	return getPagedItems(ctx, accessToken, itemsURL, reqParams)
+type ArtistAlbumMeta struct {
+	ID          string
+	Title       string
+	ReleaseDate time.Time
 }
 
-func getPagedItems(ctx context.Context, accessToken, itemsURL string, reqParams url.Values) (b []byte, err error) {
-	reqURL, err := url.Parse(itemsURL)
-	if nil != err {
-		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
-		return nil, flaw.From(fmt.Errorf("failed to parse page items URL: %v", err)).Append(flawP)
+// Artist lists albums, EPs, and singles of the artist sorted by release date, oldest first.
+// Compilations, and releases the artist only appears on are skipped unless includeCompilations is set.
+func (d *Downloader) Artist(ctx context.Context, id string, includeCompilations bool) ([]ArtistAlbumMeta, error) {
+	filters := []string{api.ArtistAlbumsFilterAlbums, api.ArtistAlbumsFilterEPsAndSingles}
+	if includeCompilations {
+		filters = append(filters, api.ArtistAlbumsFilterCompilations)
 	}
 
-	reqURL.RawQuery = reqParams.Encode()
-	flawP := flaw.P{"encoded_query_params": reqURL.RawQuery}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
-	if nil != err {
-		if errutil.IsContext(ctx) {
-			return nil, ctx.Err()
+	var (
+		albums []ArtistAlbumMeta
+		seen   = make(map[string]struct{})
+	)
+	for _, filter := range filters {
+		filterAlbums, err := d.getArtistAlbums(ctx, id, filter, includeCompilations)
+		if nil != err {
+			return nil, err
+		}
+		for _, album := range filterAlbums {
+			if _, ok := seen[album.ID]; ok {
+				continue
+			}
+			seen[album.ID] = struct{}{}
+			albums = append(albums, album)
 		}
-
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return nil, flaw.From(fmt.Errorf("failed to create get page items request: %v", err)).Append(flawP)
 	}
-	req.Header.Add("Authorization", "Bearer "+accessToken)
 
-	client := http.Client{Timeout: config.GetPageTracksRequestTimeout} //nolint:exhaustruct
-	resp, err := client.Do(req)
+	slices.SortStableFunc(albums, func(a, b ArtistAlbumMeta) int { return a.ReleaseDate.Compare(b.ReleaseDate) })
+
+	return albums, nil
+}
+
+func (d *Downloader) getArtistAlbums(ctx context.Context, id, filter string, includeAppearances bool) ([]ArtistAlbumMeta, error) {
+	items, err := d.client.ArtistAlbums(ctx, id, filter)
 	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return nil, ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, context.DeadlineExceeded
-		default:
-			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return nil, flaw.From(fmt.Errorf("failed to send get page items request: %v", err)).Append(flawP)
-		}
+		return nil, err
 	}
-	defer func() {
-		if closeErr := resp.Body.Close(); nil != closeErr {
-			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
-			closeErr = flaw.From(fmt.Errorf("failed to close get page items response body: %v", closeErr)).Append(flawP)
-			switch {
-			case nil == err:
-				err = closeErr
-			case errutil.IsContext(ctx):
-				err = flaw.From(errors.New("context was ended")).Join(closeErr)
-			case errors.Is(err, context.DeadlineExceeded):
-				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
-			case errors.Is(err, ErrTooManyRequests):
-				err = flaw.From(errors.New("too many requests")).Join(closeErr)
-			case errutil.IsFlaw(err):
-				err = must.BeFlaw(err).Join(closeErr)
-			default:
-				panic(errutil.UnknownError(err))
-			}
-		}
-	}()
-	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
 
-	switch code := resp.StatusCode; code {
-	case http.StatusOK:
-	case http.StatusUnauthorized:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
+	var albums []ArtistAlbumMeta
+	for _, v := range items {
+		if !v.StreamReady {
+			continue
 		}
 
-		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
+		var isMainArtist bool
+		for _, a := range v.Artists {
+			if strconv.Itoa(a.ID) == id && a.Type == tidal.ArtistTypeMain {
+				isMainArtist = true
+				break
+			}
 		}
-
-		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return nil, err
-		} else if ok {
-			return nil, auth.ErrUnauthorized
+		if !isMainArtist && !includeAppearances {
+			continue
 		}
 
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("received 401 response")).Append(flawP)
-	case http.StatusTooManyRequests:
-		return nil, ErrTooManyRequests
-	case http.StatusForbidden:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
+		releaseDate, err := time.Parse("2006-01-02", v.ReleaseDate)
 		if nil != err {
-			return nil, err
-		}
-		if ok, err := errutil.IsTooManyErrorResponse(resp, respBytes); nil != err {
-			flawP["response_body"] = string(respBytes)
-			return nil, must.BeFlaw(err)
-		} else if ok {
-			return nil, ErrTooManyRequests
+			flawP := flaw.P{
+				"artist_id":      id,
+				"filter":         filter,
+				"release_date":   v.ReleaseDate,
+				"err_debug_tree": errutil.Tree(err).FlawP(),
+			}
+			return nil, flaw.From(fmt.Errorf("failed to parse artist album release date: %v", err)).Append(flawP)
 		}
 
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(errors.New("unexpected 403 response")).Append(flawP)
-	default:
-		respBytes, err := httputil.ReadOptionalResponseBody(ctx, resp)
-		if nil != err {
-			return nil, err
+		a := ArtistAlbumMeta{
+			ID:          strconv.Itoa(v.ID),
+			Title:       v.Title,
+			ReleaseDate: releaseDate,
 		}
-		flawP["response_body"] = string(respBytes)
-		return nil, flaw.From(fmt.Errorf("unexpected status code: %d", code)).Append(flawP)
+		albums = append(albums, a)
 	}
 
-	respBytes, err := httputil.ReadResponseBody(ctx, resp)
-	if nil != err {
-		return nil, err
-	}
-	return respBytes, nil
+	return albums, nil
 }
@@ -0,0 +1,46 @@
+package download
+
+import (
+	"os"
+
+	"github.com/xeptore/tgtd/tidal"
+	"github.com/xeptore/tgtd/tidal/mpd"
+)
+
+// DashResumeKey returns the key parts manifests of the DASH stream are stored with.
+func DashResumeKey(info mpd.StreamInfo, quality tidal.Quality) string {
+	s := DashTrackStream{Info: info, Quality: quality}
+	return s.resumeKey()
+}
+
+// CompletePart records part idx of fileName, downloaded from stream, as completed.
+func CompletePart(fileName, stream string, idx int, partFileName string) error {
+	m, err := loadPartsManifest(fileName, stream)
+	if nil != err {
+		return err
+	}
+	return m.complete(idx, partFileName)
+}
+
+// PartCompleted reports whether part idx of fileName is reused when it is
+// downloaded from stream.
+func PartCompleted(fileName, stream string, idx int, partFileName string) (bool, error) {
+	m, err := loadPartsManifest(fileName, stream)
+	if nil != err {
+		return false, err
+	}
+	_, ok := m.completed(idx, partFileName)
+	return ok, nil
+}
+
+// RemoveTrackOnError returns err after removing the track file at path the way
+// track downloads do on failure.
+func RemoveTrackOnError(path string, err error) error {
+	removeTrackOnError(path, func() error { return os.Remove(path) }, &err)
+	return err
+}
+
+// SkipReason returns the reason reported for a track skipped due to err.
+func SkipReason(err error) string {
+	return failedTrack("", "", err).Reason
+}
@@ -0,0 +1,170 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/tidal"
+)
+
+// Preview describes what downloading an album, playlist, mix, or track would
+// produce.
+type Preview struct {
+	Title string
+	// Tracks is the number of streamable tracks.
+	Tracks int
+	// Duration is the total duration of streamable tracks.
+	Duration time.Duration
+	// Unavailable are tracks which are not available for streaming, and would be
+	// skipped.
+	Unavailable []SkippedTrack
+	// Format is the format the first streamable track is delivered in, or nil if
+	// there are no streamable tracks.
+	Format *tidal.TrackFormat
+	// EstimatedSize is the estimated total size of streamable tracks in bytes,
+	// assuming all of them have the bitrate of the first one.
+	EstimatedSize int64
+}
+
+// previewTrack is a streamable track of a previewed item.
+type previewTrack struct {
+	ID string
+	// Duration is the duration of the track in seconds.
+	Duration int
+}
+
+// Preview returns what downloading the album, playlist, mix, or track with the
+// given ID would produce, without downloading it. Only the stream of the first
+// streamable track is requested to estimate the total size.
+func (d *Downloader) Preview(ctx context.Context, kind, id string) (*Preview, error) {
+	var (
+		preview Preview
+		tracks  []previewTrack
+	)
+	switch kind {
+	case "album":
+		album, err := d.getAlbumMeta(ctx, id)
+		if nil != err {
+			return nil, err
+		}
+		volumes, skipped, err := d.getAlbumVolumes(ctx, id, nil)
+		if nil != err {
+			return nil, err
+		}
+		preview.Title = fmt.Sprintf("%s - %s (%s)", album.Artist, album.Title, album.ReleaseDate.Format(tidal.ReleaseDateLayout))
+		preview.Unavailable = skipped
+		for _, volTracks := range volumes {
+			for _, t := range volTracks {
+				tracks = append(tracks, previewTrack{ID: t.ID, Duration: t.Duration})
+			}
+		}
+	case "playlist":
+		playlist, err := d.getPlaylistMeta(ctx, id)
+		if nil != err {
+			return nil, err
+		}
+		listTracks, skipped, err := d.getPlaylistTracks(ctx, id, nil)
+		if nil != err {
+			return nil, err
+		}
+		preview.Title = playlist.Title
+		preview.Unavailable = skipped
+		tracks = listPreviewTracks(listTracks)
+	case "mix":
+		mix, err := d.getMixMeta(ctx, id)
+		if nil != err {
+			return nil, err
+		}
+		listTracks, skipped, err := d.getMixTracks(ctx, id, nil)
+		if nil != err {
+			return nil, err
+		}
+		preview.Title = mix.Title
+		preview.Unavailable = skipped
+		tracks = listPreviewTracks(listTracks)
+	case "track":
+		resp, err := d.client.Track(ctx, id)
+		if nil != err {
+			return nil, err
+		}
+		preview.Title = resp.Artist.Name + " - " + resp.Title
+		if resp.StreamReady {
+			tracks = []previewTrack{{ID: id, Duration: resp.Duration}}
+		} else {
+			preview.Unavailable = []SkippedTrack{notStreamReadyTrack(id, resp.Title)}
+		}
+	default:
+		panic("unexpected preview kind: " + kind)
+	}
+
+	var totalSeconds int
+	for _, t := range tracks {
+		totalSeconds += t.Duration
+	}
+	preview.Tracks = len(tracks)
+	preview.Duration = time.Duration(totalSeconds) * time.Second
+	if len(tracks) == 0 {
+		return &preview, nil
+	}
+
+	bitrate, format, err := d.sampleBitrate(ctx, tracks[0])
+	if nil != err {
+		if errutil.IsFlaw(err) {
+			return nil, must.BeFlaw(err).Append(flaw.P{"kind": kind, "id": id})
+		}
+		return nil, err
+	}
+	preview.Format = format
+	preview.EstimatedSize = bitrate * int64(totalSeconds) / 8
+	return &preview, nil
+}
+
+func listPreviewTracks(tracks []ListTrackMeta) []previewTrack {
+	out := make([]previewTrack, len(tracks))
+	for i, t := range tracks {
+		duration := t.Duration
+		if nil != t.Cut {
+			duration = t.Cut.duration(t.Duration)
+		}
+		out[i] = previewTrack{ID: t.ID, Duration: duration}
+	}
+	return out
+}
+
+// sampleBitrate returns the bitrate of track in bits per second, and the format
+// it is delivered in. Bitrate of DASH streams is read from their manifest, while
+// for vnd.tidal.bt streams it is derived from the file size.
+func (d *Downloader) sampleBitrate(ctx context.Context, track previewTrack) (int64, *tidal.TrackFormat, error) {
+	stream, format, err := d.getStream(ctx, track.ID)
+	if nil != err {
+		return 0, nil, err
+	}
+
+	switch s := stream.(type) {
+	case *DashTrackStream:
+		return int64(s.Info.Bandwidth), format, nil
+	case *VndTrackStream:
+		if track.Duration <= 0 {
+			return 0, format, nil
+		}
+		accessToken, err := d.auth.AccessToken(ctx)
+		if nil != err {
+			return 0, nil, err
+		}
+		size, err := s.fileSize(ctx, accessToken)
+		if nil != err {
+			if errutil.IsFlaw(err) {
+				return 0, nil, must.BeFlaw(err).Append(flaw.P{"track_id": track.ID})
+			}
+			return 0, nil, err
+		}
+		return int64(size) * 8 / int64(track.Duration), format, nil
+	default:
+		panic(fmt.Sprintf("unexpected stream type %T", stream))
+	}
+}
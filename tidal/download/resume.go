@@ -0,0 +1,118 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+)
+
+// partsManifest records part files of a track that are completely downloaded,
+// so that a download interrupted by an error, a cancellation, or a restart only
+// fetches the missing parts. It is stored next to the track file.
+type partsManifest struct {
+	mux  sync.Mutex
+	path string
+	// Stream identifies the stream parts are downloaded from. Parts of another
+	// stream, e.g., of a different quality, are never reused.
+	Stream string `json:"stream"`
+	// Parts maps index of completed parts to their size.
+	Parts map[int]int64 `json:"parts"`
+}
+
+func partsManifestPath(fileName string) string {
+	return fileName + ".parts.json"
+}
+
+// loadPartsManifest loads the parts manifest of fileName, or returns an empty
+// one if it does not exist, or belongs to a stream other than stream.
+func loadPartsManifest(fileName, stream string) (*partsManifest, error) {
+	m := &partsManifest{
+		mux:    sync.Mutex{},
+		path:   partsManifestPath(fileName),
+		Stream: stream,
+		Parts:  make(map[int]int64),
+	}
+
+	b, err := os.ReadFile(m.path)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			return m, nil
+		}
+		flawP := flaw.P{"path": m.path, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return nil, flaw.From(fmt.Errorf("failed to read parts manifest file: %v", err)).Append(flawP)
+	}
+
+	var stored partsManifest
+	if err := json.Unmarshal(b, &stored); nil != err || stored.Stream != stream {
+		// A corrupted manifest, or one of another stream only costs downloading
+		// the parts again.
+		return m, nil
+	}
+	for idx, size := range stored.Parts {
+		m.Parts[idx] = size
+	}
+	return m, nil
+}
+
+// completed reports whether the part idx is completely downloaded to
+// partFileName, and returns its size.
+func (m *partsManifest) completed(idx int, partFileName string) (int64, bool) {
+	m.mux.Lock()
+	size, ok := m.Parts[idx]
+	m.mux.Unlock()
+	if !ok {
+		return 0, false
+	}
+
+	info, err := os.Stat(partFileName)
+	if nil != err || info.Size() != size {
+		return 0, false
+	}
+	return size, true
+}
+
+// complete records the part idx downloaded to partFileName as completed, and
+// persists the manifest.
+func (m *partsManifest) complete(idx int, partFileName string) error {
+	info, err := os.Stat(partFileName)
+	if nil != err {
+		flawP := flaw.P{"part_file_name": partFileName, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to stat track part file: %v", err)).Append(flawP)
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.Parts[idx] = info.Size()
+	b, err := json.Marshal(m)
+	if nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to marshal parts manifest: %v", err)).Append(flawP)
+	}
+
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, b, 0o0600); nil != err {
+		flawP := flaw.P{"path": tmpPath, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to write parts manifest file: %v", err)).Append(flawP)
+	}
+	if err := os.Rename(tmpPath, m.path); nil != err {
+		flawP := flaw.P{"path": m.path, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to replace parts manifest file: %v", err)).Append(flawP)
+	}
+	return nil
+}
+
+// remove removes the manifest file once parts are merged into the track file.
+func (m *partsManifest) remove() error {
+	if err := os.Remove(m.path); nil != err && !errors.Is(err, os.ErrNotExist) {
+		flawP := flaw.P{"path": m.path, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to remove parts manifest file: %v", err)).Append(flawP)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package download_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/tidal"
+	"github.com/xeptore/tgtd/tidal/download"
+	"github.com/xeptore/tgtd/tidal/mpd"
+)
+
+func TestDashResume(t *testing.T) {
+	t.Parallel()
+
+	lossless := mpd.StreamInfo{
+		Codec:     "flac",
+		MimeType:  "audio/mp4",
+		Bandwidth: 1_000_000,
+		Parts:     mpd.Parts{InitializationURLTemplate: "", Count: 30},
+	}
+	hiRes := lossless
+	hiRes.Bandwidth = 3_000_000
+
+	t.Run("key", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NotEqual(t, download.DashResumeKey(lossless, tidal.QualityLossless), download.DashResumeKey(hiRes, tidal.QualityLossless))
+		assert.NotEqual(t, download.DashResumeKey(lossless, tidal.QualityLossless), download.DashResumeKey(lossless, tidal.QualityHiResLossless))
+		assert.Equal(t, download.DashResumeKey(lossless, tidal.QualityLossless), download.DashResumeKey(lossless, tidal.QualityLossless))
+	})
+
+	t.Run("parts_of_other_bandwidth_are_not_reused", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			fileName     = filepath.Join(t.TempDir(), "track.flac")
+			partFileName = fileName + ".part.0"
+			losslessKey  = download.DashResumeKey(lossless, tidal.QualityLossless)
+			hiResKey     = download.DashResumeKey(hiRes, tidal.QualityLossless)
+		)
+		require.NoError(t, os.WriteFile(partFileName, []byte("part"), 0o0600))
+		require.NoError(t, download.CompletePart(fileName, losslessKey, 0, partFileName))
+
+		ok, err := download.PartCompleted(fileName, losslessKey, 0, partFileName)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = download.PartCompleted(fileName, hiResKey, 0, partFileName)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,290 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/xeptore/flaw/v8"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/mathutil"
+	"github.com/xeptore/tgtd/tidal/api"
+	"github.com/xeptore/tgtd/tidal/auth"
+)
+
+// maxTrackDownloadAttempts is the number of times a track of an album, playlist,
+// or mix is tried before it is skipped.
+const maxTrackDownloadAttempts = 3
+
+// SkippedTrack is a track of an album, playlist, or mix which is not downloaded.
+type SkippedTrack struct {
+	ID    string
+	Title string
+	// Reason is a short explanation of why the track is skipped.
+	Reason string
+	// Err is the error of the last download attempt, or nil if the track was not
+	// attempted at all, e.g., as it is not available for streaming.
+	Err error
+}
+
+func notStreamReadyTrack(id, title string) SkippedTrack {
+	return SkippedTrack{
+		ID:     id,
+		Title:  title,
+		Reason: "not available for streaming in the account region",
+		Err:    nil,
+	}
+}
+
+func failedTrack(id, title string, err error) SkippedTrack {
+	var reason string
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		reason = "timed out"
+	case errors.Is(err, ErrTooManyRequests):
+		reason = "rate limited by TIDAL"
+	case errors.Is(err, auth.ErrUnauthorized):
+		reason = "unauthorized by TIDAL"
+	case errors.Is(err, api.ErrNotFound):
+		reason = "not found on TIDAL"
+	case errors.Is(err, ErrFFmpegRequired):
+		reason = ErrFFmpegRequired.Error()
+	default:
+		// Details of the error are logged along with the skipped tracks summary.
+		reason = "download failed"
+	}
+	return SkippedTrack{ID: id, Title: title, Reason: reason, Err: err}
+}
+
+// removeTrackOnError removes the track file at path via remove if *err is not
+// nil, joining the error of removing it to *err. A track file which was never created is not an
+// error, so that *err is kept as is to be classified by callers.
+func removeTrackOnError(path string, remove func() error, err *error) {
+	if nil == *err {
+		return
+	}
+	if removeErr := remove(); nil != removeErr && !errors.Is(removeErr, os.ErrNotExist) {
+		flawP := flaw.P{
+			"err_debug_tree": errutil.Tree(removeErr).FlawP(),
+			"path":           path,
+		}
+		*err = flaw.From(fmt.Errorf("failed to remove track file: %v", removeErr)).Join(*err).Append(flawP)
+	}
+}
+
+type trackRef struct {
+	ID string
+	// Key is the key of the track in the track store.
+	Key   string
+	Title string
+}
+
+// TrackBatch is a group of consecutive tracks of an album volume, playlist, or
+// mix that are uploaded together.
+type TrackBatch struct {
+	// Volume is the album volume number of tracks, or zero for playlists, and mixes.
+	Volume int
+	// Index is the zero-based index of the batch among Total batches of the album
+	// volume, playlist, or mix.
+	Index int
+	Total int
+	// TrackKeys are track store keys of downloaded tracks of the batch, excluding
+	// failed ones.
+	TrackKeys []string
+}
+
+// BatchFunc is called with every batch of tracks once all of them are either
+// downloaded, or failed. Batches are passed in order, one at a time, while tracks
+// of the next batches are still downloading.
+type BatchFunc func(ctx context.Context, batch TrackBatch) error
+
+type trackBatch struct {
+	TrackBatch
+	start int
+	end   int
+}
+
+// trackBatches splits n tracks starting at offset into batches of the size
+// tracks are uploaded in.
+func trackBatches(volume, offset, n int) []trackBatch {
+	if n == 0 {
+		return nil
+	}
+
+	var (
+		size    = mathutil.OptimalAlbumSize(n)
+		total   = mathutil.CeilInts(n, size)
+		batches = make([]trackBatch, total)
+	)
+	for i := range total {
+		batches[i] = trackBatch{
+			TrackBatch: TrackBatch{
+				Volume:    volume,
+				Index:     i,
+				Total:     total,
+				TrackKeys: nil,
+			},
+			start: offset + i*size,
+			end:   offset + min((i+1)*size, n),
+		}
+	}
+	return batches
+}
+
+// downloadTracks calls download for every track, except already uploaded ones,
+// with the index of the track, retrying each track on its own with an
+// exponential backoff, and calls onBatch with every batch of batches as soon as
+// its tracks are done. Tracks still failing after maxTrackDownloadAttempts are
+// returned as skipped, in order of tracks. It only returns an error if ctx is done, or onBatch fails.
+func (d *Downloader) downloadTracks(
+	ctx context.Context,
+	concurrency int,
+	tracks []trackRef,
+	batches []trackBatch,
+	download func(ctx context.Context, accessToken string, i int) error,
+	onBatch BatchFunc,
+) ([]SkippedTrack, error) {
+	var (
+		wg, wgCtx = errgroup.WithContext(ctx)
+		failed    = make([]*SkippedTrack, len(tracks))
+		done      = make([]chan struct{}, len(tracks))
+	)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	d.progress.AddTracks(len(tracks))
+
+	// Batches are handed off in a separate goroutine, so that it does not occupy
+	// a download slot.
+	wg.Go(func() error {
+		for _, b := range batches {
+			for i := b.start; i < b.end; i++ {
+				select {
+				case <-wgCtx.Done():
+					return wgCtx.Err()
+				case <-done[i]:
+				}
+			}
+
+			batch := b.TrackBatch
+			for i := b.start; i < b.end; i++ {
+				if nil == failed[i] {
+					batch.TrackKeys = append(batch.TrackKeys, tracks[i].Key)
+				}
+			}
+			if len(batch.TrackKeys) == 0 {
+				continue
+			}
+			if err := onBatch(wgCtx, batch); nil != err {
+				return err
+			}
+		}
+		return nil
+	})
+
+	sem := make(chan struct{}, concurrency)
+	for i, track := range tracks {
+		if d.uploaded(track.Key) {
+			// The track is sent again by its uploaded document.
+			d.progress.TracksDownloaded(1)
+			close(done[i])
+			continue
+		}
+
+		select {
+		case <-wgCtx.Done():
+		case sem <- struct{}{}:
+		}
+		if nil != wgCtx.Err() {
+			break
+		}
+
+		wg.Go(func() error {
+			defer func() { <-sem }()
+			defer close(done[i])
+
+			err := d.retryTrack(wgCtx, func(accessToken string) error {
+				return download(wgCtx, accessToken, i)
+			})
+			if nil != err {
+				if errutil.IsContext(wgCtx) {
+					return wgCtx.Err()
+				}
+				skipped := failedTrack(track.ID, track.Title, err)
+				failed[i] = &skipped
+				return nil
+			}
+
+			d.progress.TracksDownloaded(1)
+			return nil
+		})
+	}
+
+	if err := wg.Wait(); nil != err {
+		if errutil.IsContext(ctx) {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	var skipped []SkippedTrack
+	for _, v := range failed {
+		if nil != v {
+			skipped = append(skipped, *v)
+		}
+	}
+	return skipped, nil
+}
+
+var refreshTokenMux sync.Mutex
+
+// retryTrack calls download with a valid access token until it succeeds, or
+// maxTrackDownloadAttempts is reached, in which case it returns the error of
+// the last attempt.
+func (d *Downloader) retryTrack(ctx context.Context, download func(accessToken string) error) error {
+	b := backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxTrackDownloadAttempts-1), ctx)
+	return backoff.Retry(func() error {
+		accessToken, err := d.auth.AccessToken(ctx)
+		if nil != err {
+			return backoff.Permanent(err)
+		}
+
+		err = download(accessToken)
+		switch {
+		case nil == err:
+			return nil
+		case errutil.IsContext(ctx):
+			return backoff.Permanent(ctx.Err())
+		case errors.Is(err, ErrFFmpegRequired):
+			return backoff.Permanent(err)
+		case errors.Is(err, auth.ErrUnauthorized):
+			if err := d.refreshToken(ctx, accessToken); nil != err {
+				return backoff.Permanent(err)
+			}
+			return err
+		default:
+			return err
+		}
+	}, b)
+}
+
+// refreshToken refreshes the access token unless it is already refreshed by a
+// concurrent track download since expired was issued.
+func (d *Downloader) refreshToken(ctx context.Context, expired string) error {
+	refreshTokenMux.Lock()
+	defer refreshTokenMux.Unlock()
+
+	current, err := d.auth.AccessToken(ctx)
+	if nil != err {
+		return err
+	}
+	if current != expired {
+		return nil
+	}
+	return d.auth.RefreshToken(ctx)
+}
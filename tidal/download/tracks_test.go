@@ -0,0 +1,34 @@
+package download_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/tidal/auth"
+	"github.com/xeptore/tgtd/tidal/download"
+)
+
+func TestSkippedTrack(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unauthorized_is_classified_without_track_file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "track.flac")
+		err := download.RemoveTrackOnError(path, auth.ErrUnauthorized)
+		require.ErrorIs(t, err, auth.ErrUnauthorized)
+		assert.Equal(t, "unauthorized by TIDAL", download.SkipReason(err))
+	})
+
+	t.Run("flaw_reason_is_short", func(t *testing.T) {
+		t.Parallel()
+
+		err := flaw.From(errors.New("failed to decode response body:\nunexpected end of JSON input"))
+		assert.Equal(t, "download failed", download.SkipReason(err))
+	})
+}
@@ -0,0 +1,523 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/xeptore/flaw/v8"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/mathutil"
+	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/progress"
+	"github.com/xeptore/tgtd/tidal"
+	"github.com/xeptore/tgtd/tidal/api"
+	"github.com/xeptore/tgtd/tidal/auth"
+	"github.com/xeptore/tgtd/tidal/fs"
+	"github.com/xeptore/tgtd/tidal/hls"
+)
+
+const thumbnailMaxSize = 320
+
+type VideoMeta struct {
+	Artists     []tidal.TrackArtist
+	Title       string
+	Version     *string
+	Duration    int
+	ReleaseDate time.Time
+}
+
+// Video downloads the music video with the highest resolution not exceeding maxHeight,
+// or the lowest available resolution if all of them exceed it, and remuxes it to MP4.
+func (d *Downloader) Video(ctx context.Context, id string, maxHeight int) (err error) {
+	accessToken, err := d.auth.AccessToken(ctx)
+	if nil != err {
+		return err
+	}
+
+	video, err := d.getVideoMeta(ctx, id)
+	if nil != err {
+		return err
+	}
+	d.progress.AddTracks(1)
+	defer func() {
+		if nil == err {
+			d.progress.TracksDownloaded(1)
+		}
+	}()
+
+	videoFs := d.dir.Video(id)
+	if exists, err := videoFs.Exists(); nil != err {
+		return err
+	} else if exists {
+		return nil
+	}
+	defer func() {
+		if nil != err {
+			if removeErr := videoFs.Remove(); nil != removeErr {
+				flawP := flaw.P{
+					"err_debug_tree":  errutil.Tree(removeErr).FlawP(),
+					"video_file_path": videoFs.Path,
+				}
+				err = flaw.From(fmt.Errorf("failed to remove video file: %v", removeErr)).Join(err).Append(flawP)
+			}
+		}
+	}()
+
+	masterURL, err := d.getVideoStreamURL(ctx, id)
+	if nil != err {
+		return err
+	}
+
+	variant, err := selectVideoVariant(ctx, accessToken, masterURL, maxHeight)
+	if nil != err {
+		return err
+	}
+	flawP := flaw.P{"variant": variant.FlawP()}
+
+	media, err := getVideoMediaPlaylist(ctx, accessToken, variant.URL)
+	if nil != err {
+		switch {
+		case errutil.IsContext(ctx):
+			return ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			return context.DeadlineExceeded
+		case errors.Is(err, ErrTooManyRequests):
+			return ErrTooManyRequests
+		case errors.Is(err, auth.ErrUnauthorized):
+			return auth.ErrUnauthorized
+		case errutil.IsFlaw(err):
+			return must.BeFlaw(err).Append(flawP)
+		default:
+			panic(errutil.UnknownError(err))
+		}
+	}
+
+	stream := HLSVideoStream{SegmentURLs: media.SegmentURLs}
+	tsFilePath := videoFs.Path + ".ts"
+	if err := stream.saveTo(ctx, accessToken, tsFilePath, d.progress); nil != err {
+		switch {
+		case errutil.IsContext(ctx):
+			return ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			return context.DeadlineExceeded
+		case errors.Is(err, ErrTooManyRequests):
+			return ErrTooManyRequests
+		case errors.Is(err, auth.ErrUnauthorized):
+			return auth.ErrUnauthorized
+		case errutil.IsFlaw(err):
+			return must.BeFlaw(err).Append(flawP)
+		default:
+			panic(errutil.UnknownError(err))
+		}
+	}
+
+	if err := remuxVideo(ctx, tsFilePath, videoFs.Path); nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	}
+
+	if err := extractVideoThumbnail(ctx, videoFs.Path, videoFs.Thumbnail.Path, video.Duration); nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	}
+
+	info := fs.StoredVideo{
+		Artists:  video.Artists,
+		Title:    video.Title,
+		Version:  video.Version,
+		Duration: video.Duration,
+		Width:    variant.Width,
+		Height:   variant.Height,
+		Caption:  fmt.Sprintf("%s (%s)", video.Title, video.ReleaseDate.Format(tidal.ReleaseDateLayout)),
+	}
+	if err := videoFs.InfoFile.Write(info); nil != err {
+		return err
+	}
+
+	return nil
+}
+
+func selectVideoVariant(ctx context.Context, accessToken, masterURL string, maxHeight int) (*hls.Variant, error) {
+	flawP := flaw.P{"master_url": masterURL, "max_height": maxHeight}
+
+	base, err := url.Parse(masterURL)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to parse video master playlist URL: %v", err)).Append(flawP)
+	}
+
+	var buf bytes.Buffer
+	if err := downloadSegment(ctx, accessToken, masterURL, &buf); nil != err {
+		switch {
+		case errutil.IsContext(ctx):
+			return nil, ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, context.DeadlineExceeded
+		case errors.Is(err, ErrTooManyRequests):
+			return nil, ErrTooManyRequests
+		case errors.Is(err, auth.ErrUnauthorized):
+			return nil, auth.ErrUnauthorized
+		case errutil.IsFlaw(err):
+			return nil, must.BeFlaw(err).Append(flawP)
+		default:
+			panic(errutil.UnknownError(err))
+		}
+	}
+
+	variants, err := hls.ParseMaster(&buf, base)
+	if nil != err {
+		return nil, must.BeFlaw(err).Append(flawP)
+	}
+
+	var selected *hls.Variant
+	for _, v := range variants {
+		switch {
+		case nil == selected:
+			selected = &v
+		case selected.Height > maxHeight && v.Height < selected.Height:
+			selected = &v
+		case v.Height <= maxHeight && (v.Height > selected.Height || v.Height == selected.Height && v.Bandwidth > selected.Bandwidth):
+			selected = &v
+		}
+	}
+
+	return selected, nil
+}
+
+func getVideoMediaPlaylist(ctx context.Context, accessToken, mediaURL string) (*hls.MediaPlaylist, error) {
+	flawP := flaw.P{"media_url": mediaURL}
+
+	base, err := url.Parse(mediaURL)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to parse video media playlist URL: %v", err)).Append(flawP)
+	}
+
+	var buf bytes.Buffer
+	if err := downloadSegment(ctx, accessToken, mediaURL, &buf); nil != err {
+		switch {
+		case errutil.IsContext(ctx):
+			return nil, ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, context.DeadlineExceeded
+		case errors.Is(err, ErrTooManyRequests):
+			return nil, ErrTooManyRequests
+		case errors.Is(err, auth.ErrUnauthorized):
+			return nil, auth.ErrUnauthorized
+		case errutil.IsFlaw(err):
+			return nil, must.BeFlaw(err).Append(flawP)
+		default:
+			panic(errutil.UnknownError(err))
+		}
+	}
+
+	media, err := hls.ParseMedia(&buf, base)
+	if nil != err {
+		return nil, must.BeFlaw(err).Append(flawP)
+	}
+
+	return media, nil
+}
+
+func remuxVideo(ctx context.Context, tsFilePath, videoFilePath string) error {
+	args := []string{
+		"-y",
+		"-i",
+		tsFilePath,
+		"-map",
+		"0",
+		"-c",
+		"copy",
+		"-bsf:a",
+		"aac_adtstoasc",
+		"-movflags",
+		"+faststart",
+		"-f",
+		"mp4",
+		videoFilePath,
+	}
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	if err := cmd.Run(); nil != err {
+		flawP := flaw.P{
+			"err_debug_tree": errutil.Tree(err).FlawP(),
+			"cmd":            cmd.String(),
+		}
+		return flaw.From(fmt.Errorf("failed to remux video: %v", err)).Append(flawP)
+	}
+
+	if err := os.Remove(tsFilePath); nil != err {
+		flawP := flaw.P{
+			"err_debug_tree": errutil.Tree(err).FlawP(),
+			"path":           tsFilePath,
+		}
+		return flaw.From(fmt.Errorf("failed to remove video transport stream file: %v", err)).Append(flawP)
+	}
+
+	return nil
+}
+
+func extractVideoThumbnail(ctx context.Context, videoFilePath, thumbnailPath string, duration int) error {
+	args := []string{
+		"-y",
+		"-ss",
+		strconv.Itoa(min(duration/2, 1)),
+		"-i",
+		videoFilePath,
+		"-frames:v",
+		"1",
+		"-vf",
+		fmt.Sprintf("scale=w=%d:h=%d:force_original_aspect_ratio=decrease", thumbnailMaxSize, thumbnailMaxSize),
+		"-f",
+		"image2",
+		thumbnailPath,
+	}
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	if err := cmd.Run(); nil != err {
+		flawP := flaw.P{
+			"err_debug_tree": errutil.Tree(err).FlawP(),
+			"cmd":            cmd.String(),
+		}
+		return flaw.From(fmt.Errorf("failed to extract video thumbnail: %v", err)).Append(flawP)
+	}
+	return nil
+}
+
+type HLSVideoStream struct {
+	SegmentURLs []string
+}
+
+func (s *HLSVideoStream) saveTo(ctx context.Context, accessToken string, fileName string, tracker *progress.Tracker) (err error) {
+	var (
+		numBatches = mathutil.CeilInts(len(s.SegmentURLs), maxBatchParts)
+		flawP      = flaw.P{"num_batches": numBatches}
+		wg, wgCtx  = errgroup.WithContext(ctx)
+	)
+
+	wg.SetLimit(numBatches)
+	for i := range numBatches {
+		wg.Go(func() error {
+			if err := s.downloadBatch(wgCtx, accessToken, fileName, i, tracker); nil != err {
+				switch {
+				case errutil.IsContext(ctx):
+					return ctx.Err()
+				case errors.Is(err, context.DeadlineExceeded):
+					return context.DeadlineExceeded
+				case errors.Is(err, ErrTooManyRequests):
+					return ErrTooManyRequests
+				case errors.Is(err, auth.ErrUnauthorized):
+					return auth.ErrUnauthorized
+				case errutil.IsFlaw(err):
+					flawP["batch_index"] = i
+					return must.BeFlaw(err).Append(flawP)
+				default:
+					panic(errutil.UnknownError(err))
+				}
+			}
+			return nil
+		})
+	}
+
+	if err := wg.Wait(); nil != err {
+		switch {
+		case errutil.IsContext(ctx):
+			return ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			return context.DeadlineExceeded
+		case errors.Is(err, ErrTooManyRequests):
+			return ErrTooManyRequests
+		case errors.Is(err, auth.ErrUnauthorized):
+			return auth.ErrUnauthorized
+		case errutil.IsFlaw(err):
+			return must.BeFlaw(err).Append(flawP)
+		default:
+			panic(errutil.UnknownError(err))
+		}
+	}
+
+	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_SYNC|os.O_TRUNC|os.O_WRONLY, 0o0600)
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to create video file: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if nil != err {
+			if removeErr := os.Remove(fileName); nil != removeErr {
+				flawP["err_debug_tree"] = errutil.Tree(removeErr).FlawP()
+				err = flaw.From(fmt.Errorf("failed to remove incomplete video file: %v", removeErr)).Join(err).Append(flawP)
+			}
+		}
+
+		if closeErr := f.Close(); nil != closeErr {
+			flawP["err_debug_tree"] = errutil.Tree(closeErr).FlawP()
+			closeErr = flaw.From(fmt.Errorf("failed to close video file: %v", closeErr)).Append(flawP)
+			switch {
+			case nil == err:
+				err = closeErr
+			case errutil.IsFlaw(err):
+				err = must.BeFlaw(err).Join(closeErr).Append(flawP)
+			default:
+				panic(errutil.UnknownError(err))
+			}
+		}
+	}()
+
+	loopFlawPs := make([]flaw.P, numBatches)
+	flawP["loop_flaws"] = loopFlawPs
+	for i := range numBatches {
+		partFileName := fileName + ".part." + strconv.Itoa(i)
+		loopFlawP := flaw.P{"part_file_name": partFileName}
+		loopFlawPs[i] = loopFlawP
+
+		if err := writePartToTrackFile(f, partFileName); nil != err {
+			return must.BeFlaw(err).Append(flawP)
+		}
+	}
+
+	if err := f.Sync(); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return flaw.From(fmt.Errorf("failed to sync video file: %v", err)).Append(flawP)
+	}
+
+	return nil
+}
+
+func (s *HLSVideoStream) downloadBatch(ctx context.Context, accessToken, fileName string, idx int, tracker *progress.Tracker) (err error) {
+	f, err := os.OpenFile(
+		fileName+".part."+strconv.Itoa(idx),
+		os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_SYNC,
+		0o600,
+	)
+	if nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to create video part file: %v", err)).Append(flawP)
+	}
+	defer func() {
+		if nil != err {
+			if removeErr := os.Remove(f.Name()); nil != removeErr {
+				flawP := flaw.P{"err_debug_tree": errutil.Tree(removeErr).FlawP()}
+				err = flaw.From(fmt.Errorf("failed to remove incomplete video part file: %v", removeErr)).Join(err).Append(flawP)
+			}
+		}
+
+		if closeErr := f.Close(); nil != closeErr {
+			flawP := flaw.P{"err_debug_tree": errutil.Tree(closeErr).FlawP()}
+			closeErr = flaw.From(fmt.Errorf("failed to close video part file: %v", closeErr)).Append(flawP)
+			switch {
+			case nil == err:
+				err = closeErr
+			case errutil.IsContext(ctx):
+				err = flaw.From(errors.New("context was ended")).Join(closeErr)
+			case errors.Is(err, context.DeadlineExceeded):
+				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
+			case errors.Is(err, ErrTooManyRequests):
+				err = flaw.From(errors.New("too many requests")).Join(closeErr)
+			case errutil.IsFlaw(err):
+				err = must.BeFlaw(err).Join(closeErr)
+			default:
+				panic(errutil.UnknownError(err))
+			}
+		}
+	}()
+
+	start := idx * maxBatchParts
+	end := min(len(s.SegmentURLs), (idx+1)*maxBatchParts)
+
+	flawP := flaw.P{"start_segment_index": start, "end_segment_index": end}
+	loopFlawPs := make([]flaw.P, end-start)
+	flawP["loop_flaws"] = loopFlawPs
+
+	for i, link := range s.SegmentURLs[start:end] {
+		loopFlawP := flaw.P{"segment_index": start + i, "link": link}
+		loopFlawPs[i] = loopFlawP
+
+		if err := downloadSegment(ctx, accessToken, link, tracker.Writer(f)); nil != err {
+			switch {
+			case errutil.IsContext(ctx):
+				return ctx.Err()
+			case errors.Is(err, context.DeadlineExceeded):
+				return context.DeadlineExceeded
+			case errors.Is(err, ErrTooManyRequests):
+				return ErrTooManyRequests
+			case errors.Is(err, auth.ErrUnauthorized):
+				return auth.ErrUnauthorized
+			case errutil.IsFlaw(err):
+				return must.BeFlaw(err).Append(flawP)
+			default:
+				panic(errutil.UnknownError(err))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *Downloader) getVideoMeta(ctx context.Context, id string) (*VideoMeta, error) {
+	resp, err := d.client.Video(ctx, id)
+	if nil != err {
+		return nil, err
+	}
+	flawP := flaw.P{"id": id}
+
+	artists, err := api.TrackArtists(resp.Artists)
+	if nil != err {
+		return nil, must.BeFlaw(err).Append(flawP)
+	}
+
+	// Video release dates are full timestamps, e.g., 2019-05-17T00:00:00.000+0000.
+	releaseDateStr, _, _ := strings.Cut(resp.ReleaseDate, "T")
+	releaseDate, err := time.Parse(time.DateOnly, releaseDateStr)
+	if nil != err {
+		flawP["release_date"] = resp.ReleaseDate
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return nil, flaw.From(fmt.Errorf("failed to parse video release date: %v", err)).Append(flawP)
+	}
+
+	return &VideoMeta{
+		Artists:     artists,
+		Title:       resp.Title,
+		Version:     resp.Version,
+		Duration:    resp.Duration,
+		ReleaseDate: releaseDate,
+	}, nil
+}
+func (d *Downloader) getVideoStreamURL(ctx context.Context, id string) (string, error) {
+	respBody, err := d.client.VideoPlaybackInfo(ctx, id)
+	if nil != err {
+		return "", err
+	}
+	flawP := flaw.P{"id": id}
+	flawP["stream"] = flaw.P{"manifest_mime_type": respBody.ManifestMimeType}
+
+	switch mimeType := respBody.ManifestMimeType; mimeType {
+	case "application/vnd.tidal.emu":
+	default:
+		return "", flaw.From(fmt.Errorf("unexpected video manifest mime type: %s", mimeType)).Append(flawP)
+	}
+
+	var manifest struct {
+		MimeType string   `json:"mimeType"`
+		URLs     []string `json:"urls"`
+	}
+	dec := base64.NewDecoder(base64.StdEncoding, strings.NewReader(respBody.Manifest))
+	if err := json.NewDecoder(dec).Decode(&manifest); nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+		return "", flaw.From(fmt.Errorf("failed to decode vnd.tidal.emu manifest: %v", err)).Append(flawP)
+	}
+	flawP["manifest"] = flaw.P{"mime_type": manifest.MimeType, "urls": manifest.URLs}
+
+	if len(manifest.URLs) == 0 {
+		return "", flaw.From(errors.New("empty vnd.tidal.emu manifest URLs")).Append(flawP)
+	}
+
+	return manifest.URLs[0], nil
+}
@@ -18,15 +18,20 @@ import (
 	"github.com/xeptore/tgtd/httputil"
 	"github.com/xeptore/tgtd/mathutil"
 	"github.com/xeptore/tgtd/must"
+	"github.com/xeptore/tgtd/progress"
 	"github.com/xeptore/tgtd/ratelimit"
+	"github.com/xeptore/tgtd/tidal/api"
 	"github.com/xeptore/tgtd/tidal/auth"
+	"github.com/xeptore/tgtd/tidal/decrypt"
 )
 
 type VndTrackStream struct {
 	URL string
+	// Key is the key the stream is encrypted with, or nil if it is not encrypted.
+	Key *decrypt.Key
 }
 
-func (d *VndTrackStream) saveTo(ctx context.Context, accessToken string, fileName string) (err error) {
+func (d *VndTrackStream) saveTo(ctx context.Context, accessToken string, fileName string, tracker *progress.Tracker) (err error) {
 	fileSize, err := d.fileSize(ctx, accessToken)
 	if nil != err {
 		return err
@@ -38,6 +43,12 @@ func (d *VndTrackStream) saveTo(ctx context.Context, accessToken string, fileNam
 	numBatches := mathutil.CeilInts(fileSize, singlePartChunkSize)
 	loopFlawPs := make([]flaw.P, numBatches)
 	flawP := flaw.P{"download_loop_flaw_ps": loopFlawPs, "num_batches": numBatches}
+
+	stream := fmt.Sprintf("vnd:%d:%t", fileSize, nil != d.Key)
+	manifest, err := loadPartsManifest(fileName, stream)
+	if nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	}
 	for i := range numBatches {
 		wg.Go(func() (err error) {
 			start := i * singlePartChunkSize
@@ -48,6 +59,11 @@ func (d *VndTrackStream) saveTo(ctx context.Context, accessToken string, fileNam
 			partFileName := fileName + ".part." + strconv.Itoa(i)
 			loopFlawP["part_file_name"] = partFileName
 
+			if size, ok := manifest.completed(i, partFileName); ok {
+				tracker.AddDownloadedBytes(size)
+				return nil
+			}
+
 			f, err := os.OpenFile(partFileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_SYNC, 0o0600)
 			if nil != err {
 				flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
@@ -81,7 +97,7 @@ func (d *VndTrackStream) saveTo(ctx context.Context, accessToken string, fileNam
 				}
 			}()
 
-			if err := d.downloadRange(wgCtx, accessToken, start, end, f); nil != err {
+			if err := d.downloadRange(wgCtx, accessToken, start, end, tracker.Writer(f)); nil != err {
 				switch {
 				case errutil.IsContext(wgCtx):
 					return wgCtx.Err()
@@ -89,13 +105,15 @@ func (d *VndTrackStream) saveTo(ctx context.Context, accessToken string, fileNam
 					return context.DeadlineExceeded
 				case errors.Is(err, ErrTooManyRequests):
 					return ErrTooManyRequests
+				case errors.Is(err, auth.ErrUnauthorized):
+					return auth.ErrUnauthorized
 				case errutil.IsFlaw(err):
 					return must.BeFlaw(err).Append(flawP)
 				default:
 					panic(errutil.UnknownError(err))
 				}
 			}
-			return nil
+			return manifest.complete(i, partFileName)
 		})
 	}
 
@@ -107,6 +125,8 @@ func (d *VndTrackStream) saveTo(ctx context.Context, accessToken string, fileNam
 			return context.DeadlineExceeded
 		case errors.Is(err, ErrTooManyRequests):
 			return ErrTooManyRequests
+		case errors.Is(err, auth.ErrUnauthorized):
+			return auth.ErrUnauthorized
 		case errutil.IsFlaw(err):
 			return must.BeFlaw(err).Append(flawP)
 		default:
@@ -141,6 +161,14 @@ func (d *VndTrackStream) saveTo(ctx context.Context, accessToken string, fileNam
 		}
 	}()
 
+	var w io.Writer = f
+	if nil != d.Key {
+		w, err = d.Key.Writer(f)
+		if nil != err {
+			return must.BeFlaw(err).Append(flawP)
+		}
+	}
+
 	mergeLoopFlawPs := make([]flaw.P, numBatches)
 	flawP["merge_loop_flaws"] = mergeLoopFlawPs
 	for i := range numBatches {
@@ -148,7 +176,7 @@ func (d *VndTrackStream) saveTo(ctx context.Context, accessToken string, fileNam
 		loopFlawP := flaw.P{"part_file_name": partFileName}
 		loopFlawPs[i] = loopFlawP
 
-		if err := writePartToTrackFile(f, partFileName); nil != err {
+		if err := writePartToTrackFile(w, partFileName); nil != err {
 			return must.BeFlaw(err).Append(flawP)
 		}
 	}
@@ -158,34 +186,24 @@ func (d *VndTrackStream) saveTo(ctx context.Context, accessToken string, fileNam
 		return flaw.From(fmt.Errorf("failed to sync track file: %v", err)).Append(flawP)
 	}
 
+	if err := manifest.remove(); nil != err {
+		return must.BeFlaw(err).Append(flawP)
+	}
+
 	return nil
 }
 
 func (d *VndTrackStream) fileSize(ctx context.Context, accessToken string) (size int, err error) {
 	flawP := flaw.P{}
-	req, err := http.NewRequestWithContext(ctx, http.MethodHead, d.URL, nil)
+	resp, err := api.SendMedia(ctx, accessToken, api.MediaRequest{
+		Method:  http.MethodHead,
+		URL:     d.URL,
+		Header:  nil,
+		Timeout: config.GetTrackFileSizeRequestTimeout,
+		Status:  http.StatusOK,
+	})
 	if nil != err {
-		if errutil.IsContext(ctx) {
-			return 0, ctx.Err()
-		}
-
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return 0, flaw.From(fmt.Errorf("failed to create get track metada request: %v", err)).Append(flawP)
-	}
-	req.Header.Add("Authorization", "Bearer "+accessToken)
-
-	client := http.Client{Timeout: config.GetTrackFileSizeRequestTimeout} //nolint:exhaustruct
-	resp, err := client.Do(req)
-	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return 0, ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return 0, context.DeadlineExceeded
-		default:
-			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return 0, flaw.From(fmt.Errorf("failed to send get track metadata request: %v", err)).Append(flawP)
-		}
+		return 0, err
 	}
 	defer func() {
 		if closeErr := resp.Body.Close(); nil != closeErr {
@@ -198,8 +216,6 @@ func (d *VndTrackStream) fileSize(ctx context.Context, accessToken string) (size
 				err = flaw.From(errors.New("context was ended")).Join(closeErr)
 			case errors.Is(err, context.DeadlineExceeded):
 				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
-			case errors.Is(err, ErrTooManyRequests):
-				err = flaw.From(errors.New("too many requests")).Join(closeErr)
 			case errutil.IsFlaw(err):
 				err = must.BeFlaw(err).Join(closeErr)
 			default:
@@ -209,64 +225,17 @@ func (d *VndTrackStream) fileSize(ctx context.Context, accessToken string) (size
 	}()
 	flawP["response"] = errutil.HTTPResponseFlawPayload(resp)
 
-	switch code := resp.StatusCode; code {
-	case http.StatusOK:
-		size, err := strconv.Atoi(resp.Header.Get("Content-Length"))
-		if nil != err {
-			respBody, err := httputil.ReadOptionalResponseBody(ctx, resp)
-			if nil != err {
-				return 0, err
-			}
-			flawP["response_body"] = respBody
-			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return 0, flaw.From(fmt.Errorf("failed to parse content length: %v", err)).Append(flawP)
-		}
-		return size, nil
-	case http.StatusUnauthorized:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return 0, err
-		}
-
-		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return 0, err
-		} else if ok {
-			return 0, auth.ErrUnauthorized
-		}
-
-		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return 0, err
-		} else if ok {
-			return 0, auth.ErrUnauthorized
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return 0, flaw.From(errors.New("received 401 response")).Append(flawP)
-	case http.StatusTooManyRequests:
-		return 0, ErrTooManyRequests
-	case http.StatusForbidden:
-		respBody, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return 0, err
-		}
-
-		if ok, err := errutil.IsTooManyErrorResponse(resp, respBody); nil != err {
-			flawP["response_body"] = string(respBody)
-			return 0, must.BeFlaw(err)
-		} else if ok {
-			return 0, ErrTooManyRequests
-		}
-
-		flawP["response_body"] = string(respBody)
-		return 0, flaw.From(errors.New("unexpected 403 response")).Append(flawP)
-	default:
+	size, err = strconv.Atoi(resp.Header.Get("Content-Length"))
+	if nil != err {
+		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
 		respBody, err := httputil.ReadOptionalResponseBody(ctx, resp)
 		if nil != err {
 			return 0, err
 		}
 		flawP["response_body"] = respBody
-		return 0, flaw.From(fmt.Errorf("unexpected status code: %d", code)).Append(flawP)
+		return 0, flaw.From(errors.New("failed to parse content length")).Append(flawP)
 	}
+	return size, nil
 }
 
 type VNDManifest struct {
@@ -286,32 +255,17 @@ func (m *VNDManifest) FlawP() flaw.P {
 	}
 }
 
-func (d *VndTrackStream) downloadRange(ctx context.Context, accessToken string, start, end int, f *os.File) (err error) {
+func (d *VndTrackStream) downloadRange(ctx context.Context, accessToken string, start, end int, f io.Writer) (err error) {
 	flawP := flaw.P{}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.URL, nil)
-	if nil != err {
-		if errutil.IsContext(ctx) {
-			return ctx.Err()
-		}
-
-		flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-		return flaw.From(fmt.Errorf("failed to create get track part request: %v", err)).Append(flawP)
-	}
-	req.Header.Add("Authorization", "Bearer "+accessToken)
-	req.Header.Add("Range", fmt.Sprintf("bytes=%d-%d", start, end))
-
-	client := http.Client{Timeout: config.VNDSegmentDownloadTimeout} //nolint:exhaustruct
-	resp, err := client.Do(req)
+	resp, err := api.SendMedia(ctx, accessToken, api.MediaRequest{
+		Method:  http.MethodGet,
+		URL:     d.URL,
+		Header:  http.Header{"Range": []string{fmt.Sprintf("bytes=%d-%d", start, end)}},
+		Timeout: config.VNDSegmentDownloadTimeout,
+		Status:  http.StatusPartialContent,
+	})
 	if nil != err {
-		switch {
-		case errutil.IsContext(ctx):
-			return ctx.Err()
-		case errors.Is(err, context.DeadlineExceeded):
-			return context.DeadlineExceeded
-		default:
-			flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
-			return flaw.From(fmt.Errorf("failed to send get track part request: %v", err)).Append(flawP)
-		}
+		return err
 	}
 	defer func() {
 		if closeErr := resp.Body.Close(); nil != closeErr {
@@ -324,8 +278,6 @@ func (d *VndTrackStream) downloadRange(ctx context.Context, accessToken string,
 				err = flaw.From(errors.New("context was ended")).Join(closeErr)
 			case errors.Is(err, context.DeadlineExceeded):
 				err = flaw.From(errors.New("timeout has reached")).Join(closeErr)
-			case errors.Is(err, ErrTooManyRequests):
-				err = flaw.From(errors.New("too many requests")).Join(closeErr)
 			case errutil.IsFlaw(err):
 				err = must.BeFlaw(err).Join(closeErr)
 			default:
@@ -334,55 +286,6 @@ func (d *VndTrackStream) downloadRange(ctx context.Context, accessToken string,
 		}
 	}()
 
-	switch status := resp.StatusCode; status {
-	case http.StatusPartialContent:
-	case http.StatusUnauthorized:
-		respBytes, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return err
-		}
-
-		if ok, err := httputil.IsTokenExpiredUnauthorizedResponse(respBytes); nil != err {
-			return err
-		} else if ok {
-			return auth.ErrUnauthorized
-		}
-
-		if ok, err := httputil.IsTokenInvalidUnauthorizedResponse(respBytes); nil != err {
-			return err
-		} else if ok {
-			return auth.ErrUnauthorized
-		}
-
-		flawP["response_body"] = string(respBytes)
-		return flaw.From(errors.New("received 401 response")).Append(flawP)
-	case http.StatusTooManyRequests:
-		return ErrTooManyRequests
-	case http.StatusForbidden:
-		respBody, err := httputil.ReadResponseBody(ctx, resp)
-		if nil != err {
-			return err
-		}
-
-		if ok, err := errutil.IsTooManyErrorResponse(resp, respBody); nil != err {
-			flawP["response_body"] = string(respBody)
-			return must.BeFlaw(err).Append(flawP)
-		} else if ok {
-			return ErrTooManyRequests
-		}
-
-		flawP["response_body"] = string(respBody)
-		return flaw.From(errors.New("unexpected 403 response")).Append(flawP)
-	default:
-		respBody, err := httputil.ReadOptionalResponseBody(ctx, resp)
-		if nil != err {
-			return err
-		}
-
-		flawP["response_body"] = string(respBody)
-		return flaw.From(fmt.Errorf("unexpected status code received from get track part: %d", status)).Append(flawP)
-	}
-
 	respBody, err := httputil.ReadResponseBody(ctx, resp)
 	if nil != err {
 		return err
@@ -5,9 +5,20 @@ import (
 	"strings"
 )
 
+const (
+	AudioModeStereo     = "STEREO"
+	AudioModeDolbyAtmos = "DOLBY_ATMOS"
+)
+
 type TrackFormat struct {
-	MimeType string `json:"mime_type"`
-	Codec    string `json:"codec"`
+	MimeType  string  `json:"mime_type"`
+	Codec     string  `json:"codec"`
+	Quality   Quality `json:"quality"`
+	AudioMode string  `json:"audio_mode,omitempty"`
+}
+
+func (f TrackFormat) IsDolbyAtmos() bool {
+	return f.AudioMode == AudioModeDolbyAtmos
 }
 
 func (f TrackFormat) InferTrackExt() string {
@@ -27,7 +38,7 @@ func InferTrackExt(mimeType, codec string) (string, error) {
 	switch mimeType {
 	case "audio/mp4":
 		switch strings.ToLower(codec) {
-		case "eac3", "aac", "alac", "mp4a.40.2":
+		case "eac3", "ec-3", "aac", "alac", "mp4a.40.2", "mp4a.40.5":
 			return "m4a", nil
 		case codecFLAC:
 			return extFLAC, nil
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/xeptore/flaw/v8"
@@ -13,6 +14,11 @@ import (
 	"github.com/xeptore/tgtd/must"
 )
 
+// DownloadDir is the base directory of all downloads. It contains a workspace
+// per job, and the track store shared among jobs:
+//
+//	<base>/jobs/<job-id>/...        collection info files, covers, and videos
+//	<base>/tracks/<variant>/<key>   tracks, along with their info files
 type DownloadDir string
 
 func DownloadDirFrom(d string) DownloadDir {
@@ -23,23 +29,112 @@ func (dir DownloadDir) path() string {
 	return string(dir)
 }
 
-func (dir DownloadDir) Album(id string) Album {
-	dirPath := dir.path()
+// Job returns the workspace of the job with the given ID, which stores tracks
+// in the track store of variant, e.g., the audio quality of the job.
+func (dir DownloadDir) Job(id, variant string) JobDir {
+	return JobDir{
+		path:    filepath.Join(dir.path(), "jobs", id),
+		variant: variant,
+		tracks:  TrackStore{path: filepath.Join(dir.path(), "tracks", variant)},
+	}
+}
+
+// TrackStore stores downloaded tracks by their key, so that a track included in
+// several collections, or requested by several jobs is only downloaded once.
+type TrackStore struct {
+	path string
+}
+
+// TrackKey returns the key of a track in the track store. A cut of a track,
+// which only includes the audio between start and end offsets, is stored
+// separately from the full track. Zero start and end mean the full track.
+func TrackKey(id string, start, end time.Duration) string {
+	if start == 0 && end == 0 {
+		return id
+	}
+	return fmt.Sprintf("%s_%d-%d", id, start.Milliseconds(), end.Milliseconds())
+}
+
+func (s TrackStore) track(key string) string {
+	return filepath.Join(s.path, key)
+}
+
+// touchTrack marks the track as used now, so that it is evicted later than the
+// ones not used recently.
+func touchTrack(infoFilePath string) error {
+	now := time.Now()
+	if err := os.Chtimes(infoFilePath, now, now); nil != err {
+		flawP := flaw.P{"path": infoFilePath, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to update track info file times: %v", err)).Append(flawP)
+	}
+	return nil
+}
+
+// JobDir is the workspace of a single job.
+type JobDir struct {
+	path    string
+	variant string
+	tracks  TrackStore
+}
+
+// Variant returns the variant of tracks the job stores.
+func (j JobDir) Variant() string {
+	return j.variant
+}
+
+// Create creates the workspace, and the track store directories if they do not
+// exist.
+func (j JobDir) Create() error {
+	for _, dirPath := range []string{j.path, j.tracks.path} {
+		if err := os.MkdirAll(dirPath, 0o0700); nil != err {
+			flawP := flaw.P{"path": dirPath, "err_debug_tree": errutil.Tree(err).FlawP()}
+			return flaw.From(fmt.Errorf("failed to create download directory: %v", err)).Append(flawP)
+		}
+	}
+	return nil
+}
+
+// Remove removes the workspace, leaving stored tracks intact.
+func (j JobDir) Remove() error {
+	if err := os.RemoveAll(j.path); nil != err {
+		flawP := flaw.P{"path": j.path, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to remove job directory: %v", err)).Append(flawP)
+	}
+	return nil
+}
+
+// RemoveTracks removes tracks with the given keys, along with their info files
+// from the track store.
+func (j JobDir) RemoveTracks(keys []string) error {
+	for _, key := range keys {
+		trackPath := j.tracks.track(key)
+		for _, filePath := range []string{trackPath, trackPath + ".json"} {
+			if err := os.Remove(filePath); nil != err && !errors.Is(err, os.ErrNotExist) {
+				flawP := flaw.P{"path": filePath, "err_debug_tree": errutil.Tree(err).FlawP()}
+				return flaw.From(fmt.Errorf("failed to remove stored track file: %v", err)).Append(flawP)
+			}
+		}
+	}
+	return nil
+}
+
+func (j JobDir) Album(id string) Album {
+	filePath := filepath.Join(j.path, "album-"+id)
 	return Album{
-		DirPath:  dirPath,
-		InfoFile: InfoFile[StoredAlbum]{Path: filepath.Join(dirPath, id+".json")},
-		Cover:    Cover{Path: filepath.Join(dirPath, id+".jpg")},
+		InfoFile: InfoFile[StoredAlbum]{Path: filePath + ".json"},
+		Cover:    Cover{Path: filePath + ".jpg"},
+		tracks:   j.tracks,
 	}
 }
 
 type Album struct {
-	DirPath  string
 	InfoFile InfoFile[StoredAlbum]
 	Cover    Cover
+	tracks   TrackStore
 }
 
-func (a Album) Track(vol int, id string) AlbumTrack {
-	trackPath := filepath.Join(a.DirPath, id)
+func (a Album) Track(id string) AlbumTrack {
+	trackPath := a.tracks.track(TrackKey(id, 0, 0))
 	return AlbumTrack{
 		Path:     trackPath,
 		InfoFile: InfoFile[StoredSingleTrack]{Path: trackPath + ".json"},
@@ -52,7 +147,11 @@ type AlbumTrack struct {
 }
 
 func (t AlbumTrack) Exists() (bool, error) {
-	return fileExists(t.Path)
+	return trackExists(t.Path, t.InfoFile.Path)
+}
+
+func (t AlbumTrack) Touch() error {
+	return touchTrack(t.InfoFile.Path)
 }
 
 func (t AlbumTrack) Remove() error {
@@ -65,57 +164,85 @@ func (t AlbumTrack) Remove() error {
 	return nil
 }
 
-func (dir DownloadDir) Single(id string) SingleTrack {
-	trackPath := filepath.Join(dir.path(), id)
+func (j JobDir) Single(id string) SingleTrack {
+	trackPath := j.tracks.track(TrackKey(id, 0, 0))
 	return SingleTrack{
 		Path:     trackPath,
 		InfoFile: InfoFile[StoredSingleTrack]{Path: trackPath + ".json"},
-		Cover:    Cover{Path: trackPath + ".jpg"},
+		Cover:    Cover{Path: filepath.Join(j.path, "track-"+id+".jpg")},
 	}
 }
 
-func (dir DownloadDir) Playlist(id string) Playlist {
-	dirPath := dir.path()
+func (j JobDir) Playlist(id string) Playlist {
 	return Playlist{
-		DirPath:  dirPath,
-		InfoFile: InfoFile[StoredPlaylist]{Path: filepath.Join(dirPath, id+".json")},
+		InfoFile: InfoFile[StoredPlaylist]{Path: filepath.Join(j.path, "playlist-"+id+".json")},
+		job:      j,
 	}
 }
 
 type Playlist struct {
-	DirPath  string
 	InfoFile InfoFile[StoredPlaylist]
+	job      JobDir
 }
 
-func (p Playlist) Track(id string) SingleTrack {
-	trackPath := filepath.Join(p.DirPath, id)
-	return SingleTrack{
-		Path:     trackPath,
-		InfoFile: InfoFile[StoredSingleTrack]{Path: trackPath + ".json"},
-		Cover:    Cover{Path: trackPath + ".jpg"},
-	}
+// Track returns the track stored with key, as returned by TrackKey.
+func (p Playlist) Track(key string) SingleTrack {
+	return p.job.listTrack(key)
 }
 
-func (dir DownloadDir) Mix(id string) Mix {
-	dirPath := dir.path()
+func (j JobDir) Mix(id string) Mix {
 	return Mix{
-		DirPath:  dirPath,
-		InfoFile: InfoFile[StoredMix]{Path: filepath.Join(dirPath, id+".json")},
+		InfoFile: InfoFile[StoredMix]{Path: filepath.Join(j.path, "mix-"+id+".json")},
+		job:      j,
 	}
 }
 
 type Mix struct {
-	DirPath  string
 	InfoFile InfoFile[StoredMix]
+	job      JobDir
+}
+
+// Track returns the track stored with key, as returned by TrackKey.
+func (m Mix) Track(key string) SingleTrack {
+	return m.job.listTrack(key)
 }
 
-func (d Mix) Track(id string) SingleTrack {
-	trackPath := filepath.Join(d.DirPath, id)
+func (j JobDir) listTrack(key string) SingleTrack {
+	trackPath := j.tracks.track(key)
 	return SingleTrack{
 		Path:     trackPath,
 		InfoFile: InfoFile[StoredSingleTrack]{Path: trackPath + ".json"},
-		Cover:    Cover{Path: trackPath + ".jpg"},
+		Cover:    Cover{Path: filepath.Join(j.path, "track-"+key+".jpg")},
+	}
+}
+
+func (j JobDir) Video(id string) Video {
+	videoPath := filepath.Join(j.path, "video-"+id)
+	return Video{
+		Path:      videoPath,
+		InfoFile:  InfoFile[StoredVideo]{Path: videoPath + ".json"},
+		Thumbnail: Cover{Path: videoPath + ".jpg"},
+	}
+}
+
+type Video struct {
+	Path      string
+	InfoFile  InfoFile[StoredVideo]
+	Thumbnail Cover
+}
+
+func (v Video) Exists() (bool, error) {
+	return fileExists(v.Path)
+}
+
+func (v Video) Remove() error {
+	if err := os.Remove(v.Path); nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return flaw.From(fmt.Errorf("failed to remove video: %v", err))
 	}
+	return nil
 }
 
 type Cover struct {
@@ -126,6 +253,15 @@ func (c Cover) Exists() (bool, error) {
 	return fileExists(c.Path)
 }
 
+// trackExists reports whether the track is completely downloaded, i.e., both the
+// track, and its info file, which is written last, exist.
+func trackExists(trackPath, infoFilePath string) (bool, error) {
+	if exists, err := fileExists(infoFilePath); nil != err || !exists {
+		return false, err
+	}
+	return fileExists(trackPath)
+}
+
 func fileExists(path string) (bool, error) {
 	if _, err := os.Stat(path); nil != err {
 		if errors.Is(err, os.ErrNotExist) {
@@ -188,7 +324,11 @@ type SingleTrack struct {
 }
 
 func (t SingleTrack) Exists() (bool, error) {
-	return fileExists(t.Path)
+	return trackExists(t.Path, t.InfoFile.Path)
+}
+
+func (t SingleTrack) Touch() error {
+	return touchTrack(t.InfoFile.Path)
 }
 
 func (t SingleTrack) Remove() error {
@@ -0,0 +1,80 @@
+package fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/tidal/fs"
+)
+
+func TestTrackKey(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "123", fs.TrackKey("123", 0, 0))
+	assert.Equal(t, "123_1500-0", fs.TrackKey("123", 1500*time.Millisecond, 0))
+	assert.Equal(t, "123_0-90000", fs.TrackKey("123", 0, 90*time.Second))
+}
+
+func TestJobDir(t *testing.T) {
+	t.Parallel()
+
+	t.Run("jobs_share_track_store", func(t *testing.T) {
+		t.Parallel()
+
+		base := fs.DownloadDirFrom(t.TempDir())
+		first := base.Job("1", "lossless")
+		second := base.Job("2", "lossless")
+		require.NoError(t, first.Create())
+		require.NoError(t, second.Create())
+
+		assert.Equal(t, first.Album("9").Track("5").Path, second.Playlist("7").Track("5").Path)
+		assert.NotEqual(t, first.Album("9").InfoFile.Path, second.Album("9").InfoFile.Path)
+		assert.NotEqual(t, first.Album("5").InfoFile.Path, first.Single("5").InfoFile.Path)
+		assert.NotEqual(t, first.Album("5").Track("5").Path, base.Job("1", "hi_res_lossless").Album("5").Track("5").Path)
+	})
+
+	t.Run("remove_keeps_tracks", func(t *testing.T) {
+		t.Parallel()
+
+		base := fs.DownloadDirFrom(t.TempDir())
+		job := base.Job("1", "lossless")
+		require.NoError(t, job.Create())
+
+		track := job.Single("5")
+		require.NoError(t, os.WriteFile(track.Path, []byte("audio"), 0o600))
+		require.NoError(t, track.InfoFile.Write(fs.StoredSingleTrack{})) //nolint:exhaustruct
+		require.NoError(t, track.Cover.Write([]byte("cover")))
+
+		exists, err := track.Exists()
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		require.NoError(t, job.Remove())
+		assert.NoFileExists(t, track.Cover.Path)
+		assert.FileExists(t, track.Path)
+
+		exists, err = base.Job("2", "lossless").Single("5").Exists()
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("track_without_info_file_is_incomplete", func(t *testing.T) {
+		t.Parallel()
+
+		job := fs.DownloadDirFrom(t.TempDir()).Job("1", "lossless")
+		require.NoError(t, job.Create())
+
+		track := job.Album("9").Track("5")
+		require.NoError(t, os.WriteFile(track.Path, []byte("audio"), 0o600))
+
+		exists, err := track.Exists()
+		require.NoError(t, err)
+		assert.False(t, exists)
+		assert.Equal(t, "5", filepath.Base(track.Path))
+	})
+}
@@ -0,0 +1,389 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+)
+
+// Policy limits disk usage of a download directory.
+type Policy struct {
+	// MaxSize is the maximum total size of the download directory in bytes. Zero
+	// means no limit.
+	MaxSize int64
+	// MaxAge is the maximum time a job workspace, or a stored track is kept since
+	// it was last used. Zero means no limit.
+	MaxAge time.Duration
+}
+
+// Usage is the disk usage of a download directory.
+type Usage struct {
+	// Jobs is the usage of job workspaces, counted by workspace.
+	Jobs DirUsage
+	// Tracks is the usage of completely downloaded tracks in the track store.
+	Tracks DirUsage
+	// Incomplete is the usage of tracks in the track store which are not
+	// completely downloaded, e.g., part files of interrupted downloads.
+	Incomplete DirUsage
+}
+
+type DirUsage struct {
+	Count int
+	Bytes int64
+}
+
+func (u Usage) Total() int64 {
+	return u.Jobs.Bytes + u.Tracks.Bytes + u.Incomplete.Bytes
+}
+
+// Reclaimed summarizes what a cleanup removed.
+type Reclaimed struct {
+	// Entries is the number of removed job workspaces and tracks, or files for
+	// sweeps.
+	Entries int
+	Bytes   int64
+}
+
+type entryKind int
+
+const (
+	jobEntry entryKind = iota
+	trackEntry
+	incompleteEntry
+)
+
+// entry is a unit of eviction, i.e., a job workspace, or a stored track along
+// with all of its files.
+type entry struct {
+	kind     entryKind
+	name     string
+	paths    []string
+	size     int64
+	lastUsed time.Time
+}
+
+func (e entry) remove() error {
+	for _, p := range e.paths {
+		if err := os.RemoveAll(p); nil != err {
+			flawP := flaw.P{"path": p, "err_debug_tree": errutil.Tree(err).FlawP()}
+			return flaw.From(fmt.Errorf("failed to remove download directory entry: %v", err)).Append(flawP)
+		}
+	}
+	return nil
+}
+
+// Usage returns the disk usage of the download directory.
+func (dir DownloadDir) Usage() (*Usage, error) {
+	entries, err := dir.entries()
+	if nil != err {
+		return nil, err
+	}
+
+	var usage Usage
+	for _, e := range entries {
+		var u *DirUsage
+		switch e.kind {
+		case jobEntry:
+			u = &usage.Jobs
+		case trackEntry:
+			u = &usage.Tracks
+		case incompleteEntry:
+			u = &usage.Incomplete
+		}
+		u.Count++
+		u.Bytes += e.size
+	}
+	return &usage, nil
+}
+
+// Evict removes job workspaces, and stored tracks not used for longer than the
+// maximum age of policy, and then the least recently used ones until the total
+// size is within the maximum size of policy. Workspaces of jobs in keepJobs are
+// never removed, nor are incomplete tracks in the track store while there are
+// such jobs, as they may resume them. It must not be called while a job is
+// running.
+func (dir DownloadDir) Evict(policy Policy, keepJobs []string, now time.Time) (*Reclaimed, error) {
+	entries, err := dir.entries()
+	if nil != err {
+		return nil, err
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return a.lastUsed.Compare(b.lastUsed) })
+
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+
+	var reclaimed Reclaimed
+	for _, e := range entries {
+		switch {
+		case e.kind == jobEntry && slices.Contains(keepJobs, e.name):
+			continue
+		case e.kind == incompleteEntry && len(keepJobs) > 0:
+			// Left to SweepIncomplete, which only removes ones that cannot be resumed.
+			continue
+		}
+
+		expired := policy.MaxAge > 0 && now.Sub(e.lastUsed) > policy.MaxAge
+		oversized := policy.MaxSize > 0 && total > policy.MaxSize
+		if !expired && !oversized {
+			// Entries are sorted by last use, hence the rest are neither expired.
+			break
+		}
+
+		if err := e.remove(); nil != err {
+			return nil, err
+		}
+		total -= e.size
+		reclaimed.Entries++
+		reclaimed.Bytes += e.size
+	}
+	return &reclaimed, nil
+}
+
+// SweepIncomplete removes leftovers of interrupted downloads, i.e., temporary
+// files, and part files which cannot be resumed. Part files recorded in a parts
+// manifest are only kept if keepResumable is true, e.g., as there are queued
+// jobs that may resume them. It must not be called while a job is running.
+func (dir DownloadDir) SweepIncomplete(keepResumable bool) (*Reclaimed, error) {
+	var reclaimed Reclaimed
+	sweep := func(filePath string, info fs.FileInfo) error {
+		if err := os.Remove(filePath); nil != err && !errors.Is(err, os.ErrNotExist) {
+			flawP := flaw.P{"path": filePath, "err_debug_tree": errutil.Tree(err).FlawP()}
+			return flaw.From(fmt.Errorf("failed to remove incomplete download file: %v", err)).Append(flawP)
+		}
+		reclaimed.Entries++
+		reclaimed.Bytes += info.Size()
+		return nil
+	}
+
+	err := walkFiles(filepath.Join(dir.path(), "jobs"), func(filePath string, info fs.FileInfo) error {
+		// Videos are downloaded to job workspaces, and are never resumed.
+		if isTempFile(filePath) || isPartFile(filePath) {
+			return sweep(filePath, info)
+		}
+		return nil
+	})
+	if nil != err {
+		return nil, err
+	}
+
+	err = walkFiles(filepath.Join(dir.path(), "tracks"), func(filePath string, info fs.FileInfo) error {
+		switch {
+		case isTempFile(filePath):
+			return sweep(filePath, info)
+		case isPartFile(filePath), isPartsManifestFile(filePath):
+			if keepResumable {
+				trackPath := filepath.Join(filepath.Dir(filePath), trackKeyOf(filepath.Base(filePath)))
+				if exists, err := fileExists(trackPath + ".parts.json"); nil != err {
+					return err
+				} else if exists {
+					return nil
+				}
+			}
+			return sweep(filePath, info)
+		default:
+			return nil
+		}
+	})
+	if nil != err {
+		return nil, err
+	}
+
+	return &reclaimed, nil
+}
+
+func isTempFile(filePath string) bool {
+	return strings.HasSuffix(filePath, ".tmp")
+}
+
+func isPartFile(filePath string) bool {
+	ext := filepath.Ext(filePath)
+	if ext == "" || ext == filePath {
+		return false
+	}
+	return strings.HasSuffix(strings.TrimSuffix(filePath, ext), ".part")
+}
+
+func isPartsManifestFile(filePath string) bool {
+	return strings.HasSuffix(filePath, ".parts.json")
+}
+
+// trackKeyOf returns the key of the track a file of the track store belongs to,
+// as keys never contain dots.
+func trackKeyOf(fileName string) string {
+	key, _, _ := strings.Cut(fileName, ".")
+	return key
+}
+
+func (dir DownloadDir) entries() ([]entry, error) {
+	jobs, err := dir.jobEntries()
+	if nil != err {
+		return nil, err
+	}
+	tracks, err := dir.trackEntries()
+	if nil != err {
+		return nil, err
+	}
+	return append(jobs, tracks...), nil
+}
+
+func (dir DownloadDir) jobEntries() ([]entry, error) {
+	jobsPath := filepath.Join(dir.path(), "jobs")
+	dirEntries, err := readDir(jobsPath)
+	if nil != err {
+		return nil, err
+	}
+
+	entries := make([]entry, 0, len(dirEntries))
+	for _, d := range dirEntries {
+		if !d.IsDir() {
+			continue
+		}
+
+		jobPath := filepath.Join(jobsPath, d.Name())
+		e := entry{
+			kind:     jobEntry,
+			name:     d.Name(),
+			paths:    []string{jobPath},
+			size:     0,
+			lastUsed: time.Time{},
+		}
+		err := walkFiles(jobPath, func(_ string, info fs.FileInfo) error {
+			e.size += info.Size()
+			if info.ModTime().After(e.lastUsed) {
+				e.lastUsed = info.ModTime()
+			}
+			return nil
+		})
+		if nil != err {
+			return nil, err
+		}
+		if e.lastUsed.IsZero() {
+			// An empty workspace is last used when it is created.
+			if info, err := d.Info(); nil == err {
+				e.lastUsed = info.ModTime()
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (dir DownloadDir) trackEntries() ([]entry, error) {
+	storePath := filepath.Join(dir.path(), "tracks")
+	variants, err := readDir(storePath)
+	if nil != err {
+		return nil, err
+	}
+
+	var entries []entry
+	for _, variant := range variants {
+		if !variant.IsDir() {
+			continue
+		}
+
+		variantPath := filepath.Join(storePath, variant.Name())
+		files, err := readDir(variantPath)
+		if nil != err {
+			return nil, err
+		}
+
+		byKey := make(map[string]*entry)
+		var keys []string
+		for _, f := range files {
+			info, err := f.Info()
+			if nil != err {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				flawP := flaw.P{"path": filepath.Join(variantPath, f.Name()), "err_debug_tree": errutil.Tree(err).FlawP()}
+				return nil, flaw.From(fmt.Errorf("failed to stat track store file: %v", err)).Append(flawP)
+			}
+
+			key := trackKeyOf(f.Name())
+			e, ok := byKey[key]
+			if !ok {
+				e = &entry{
+					kind:     incompleteEntry,
+					name:     key,
+					paths:    nil,
+					size:     0,
+					lastUsed: time.Time{},
+				}
+				byKey[key] = e
+				keys = append(keys, key)
+			}
+			e.paths = append(e.paths, filepath.Join(variantPath, f.Name()))
+			e.size += info.Size()
+			if info.ModTime().After(e.lastUsed) {
+				e.lastUsed = info.ModTime()
+			}
+		}
+
+		for _, key := range keys {
+			e := byKey[key]
+			trackPath := filepath.Join(variantPath, key)
+			if slices.Contains(e.paths, trackPath) && slices.Contains(e.paths, trackPath+".json") {
+				e.kind = trackEntry
+			}
+			entries = append(entries, *e)
+		}
+	}
+	return entries, nil
+}
+
+// readDir returns entries of dirPath, or none if it does not exist.
+func readDir(dirPath string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dirPath)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		flawP := flaw.P{"path": dirPath, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return nil, flaw.From(fmt.Errorf("failed to read download directory: %v", err)).Append(flawP)
+	}
+	return entries, nil
+}
+
+// walkFiles calls fn with every regular file under dirPath. Files removed while
+// walking, and a missing dirPath are ignored.
+func walkFiles(dirPath string, fn func(filePath string, info fs.FileInfo) error) error {
+	err := filepath.WalkDir(dirPath, func(filePath string, d fs.DirEntry, err error) error {
+		if nil != err {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if nil != err {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		return fn(filePath, info)
+	})
+	if nil != err {
+		if errutil.IsFlaw(err) {
+			return err
+		}
+		flawP := flaw.P{"path": dirPath, "err_debug_tree": errutil.Tree(err).FlawP()}
+		return flaw.From(fmt.Errorf("failed to walk download directory: %v", err)).Append(flawP)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/tidal/fs"
+)
+
+func writeFile(t *testing.T, filePath string, size int, modTime time.Time) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0o700))
+	require.NoError(t, os.WriteFile(filePath, make([]byte, size), 0o600))
+	require.NoError(t, os.Chtimes(filePath, modTime, modTime))
+}
+
+func writeTrack(t *testing.T, base, key string, size int, modTime time.Time) string {
+	t.Helper()
+
+	trackPath := filepath.Join(base, "tracks", "lossless", key)
+	writeFile(t, trackPath, size, modTime)
+	writeFile(t, trackPath+".json", 0, modTime)
+	return trackPath
+}
+
+func TestUsage(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	now := time.Now()
+	writeTrack(t, base, "1", 100, now)
+	writeFile(t, filepath.Join(base, "tracks", "lossless", "2.part.0"), 30, now)
+	writeFile(t, filepath.Join(base, "jobs", "a", "album-9.jpg"), 10, now)
+
+	usage, err := fs.DownloadDirFrom(base).Usage()
+	require.NoError(t, err)
+	assert.Equal(t, fs.DirUsage{Count: 1, Bytes: 100}, usage.Tracks)
+	assert.Equal(t, fs.DirUsage{Count: 1, Bytes: 30}, usage.Incomplete)
+	assert.Equal(t, fs.DirUsage{Count: 1, Bytes: 10}, usage.Jobs)
+	assert.Equal(t, int64(140), usage.Total())
+
+	usage, err = fs.DownloadDirFrom(filepath.Join(base, "missing")).Usage()
+	require.NoError(t, err)
+	assert.Zero(t, usage.Total())
+}
+
+func TestEvict(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	t.Run("max_age", func(t *testing.T) {
+		t.Parallel()
+
+		base := t.TempDir()
+		oldTrack := writeTrack(t, base, "1", 10, now.Add(-48*time.Hour))
+		newTrack := writeTrack(t, base, "2", 10, now.Add(-time.Hour))
+		writeFile(t, filepath.Join(base, "jobs", "a", "album-9.json"), 10, now.Add(-72*time.Hour))
+
+		policy := fs.Policy{MaxSize: 0, MaxAge: 24 * time.Hour}
+		reclaimed, err := fs.DownloadDirFrom(base).Evict(policy, nil, now)
+		require.NoError(t, err)
+		assert.Equal(t, fs.Reclaimed{Entries: 2, Bytes: 20}, *reclaimed)
+		assert.NoFileExists(t, oldTrack)
+		assert.NoFileExists(t, oldTrack+".json")
+		assert.FileExists(t, newTrack)
+		assert.NoDirExists(t, filepath.Join(base, "jobs", "a"))
+	})
+
+	t.Run("max_size_evicts_least_recently_used", func(t *testing.T) {
+		t.Parallel()
+
+		base := t.TempDir()
+		first := writeTrack(t, base, "1", 100, now.Add(-3*time.Hour))
+		second := writeTrack(t, base, "2", 100, now.Add(-2*time.Hour))
+		third := writeTrack(t, base, "3", 100, now.Add(-time.Hour))
+
+		policy := fs.Policy{MaxSize: 250, MaxAge: 0}
+		reclaimed, err := fs.DownloadDirFrom(base).Evict(policy, nil, now)
+		require.NoError(t, err)
+		assert.Equal(t, fs.Reclaimed{Entries: 1, Bytes: 100}, *reclaimed)
+		assert.NoFileExists(t, first)
+		assert.FileExists(t, second)
+		assert.FileExists(t, third)
+	})
+
+	t.Run("touched_track_is_kept", func(t *testing.T) {
+		t.Parallel()
+
+		base := t.TempDir()
+		job := fs.DownloadDirFrom(base).Job("a", "lossless")
+		require.NoError(t, job.Create())
+		writeTrack(t, base, "1", 100, now.Add(-2*time.Hour))
+		second := writeTrack(t, base, "2", 100, now.Add(-time.Hour))
+		require.NoError(t, job.Single("1").Touch())
+
+		policy := fs.Policy{MaxSize: 150, MaxAge: 0}
+		_, err := fs.DownloadDirFrom(base).Evict(policy, []string{"a"}, now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.FileExists(t, job.Single("1").Path)
+		assert.NoFileExists(t, second)
+	})
+
+	t.Run("queued_jobs_are_kept", func(t *testing.T) {
+		t.Parallel()
+
+		base := t.TempDir()
+		writeFile(t, filepath.Join(base, "jobs", "a", "video-1"), 100, now.Add(-72*time.Hour))
+		writeFile(t, filepath.Join(base, "jobs", "b", "video-2"), 100, now.Add(-72*time.Hour))
+
+		policy := fs.Policy{MaxSize: 1, MaxAge: time.Hour}
+		_, err := fs.DownloadDirFrom(base).Evict(policy, []string{"a"}, now)
+		require.NoError(t, err)
+		assert.DirExists(t, filepath.Join(base, "jobs", "a"))
+		assert.NoDirExists(t, filepath.Join(base, "jobs", "b"))
+	})
+
+	t.Run("resumable_parts_are_kept_for_queued_jobs", func(t *testing.T) {
+		t.Parallel()
+
+		base := t.TempDir()
+		store := filepath.Join(base, "tracks", "lossless")
+		writeFile(t, filepath.Join(store, "1.part.0"), 100, now.Add(-72*time.Hour))
+		writeFile(t, filepath.Join(store, "1.parts.json"), 10, now.Add(-72*time.Hour))
+		track := writeTrack(t, base, "2", 100, now.Add(-72*time.Hour))
+
+		// As on startup, sweeping keeps resumable parts of queued jobs, and so does
+		// evicting right after it.
+		dir := fs.DownloadDirFrom(base)
+		_, err := dir.SweepIncomplete(true)
+		require.NoError(t, err)
+		policy := fs.Policy{MaxSize: 1, MaxAge: time.Hour}
+		reclaimed, err := dir.Evict(policy, []string{"a"}, now)
+		require.NoError(t, err)
+		assert.Equal(t, fs.Reclaimed{Entries: 1, Bytes: 100}, *reclaimed)
+		assert.FileExists(t, filepath.Join(store, "1.part.0"))
+		assert.FileExists(t, filepath.Join(store, "1.parts.json"))
+		assert.NoFileExists(t, track)
+
+		reclaimed, err = dir.Evict(policy, nil, now)
+		require.NoError(t, err)
+		assert.Equal(t, fs.Reclaimed{Entries: 1, Bytes: 110}, *reclaimed)
+		assert.NoFileExists(t, filepath.Join(store, "1.part.0"))
+	})
+}
+
+func TestSweepIncomplete(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	setup := func(t *testing.T) string {
+		t.Helper()
+
+		base := t.TempDir()
+		store := filepath.Join(base, "tracks", "lossless")
+		writeTrack(t, base, "1", 10, now)
+		writeFile(t, filepath.Join(store, "1.tag.tmp"), 10, now)
+		writeFile(t, filepath.Join(store, "2.part.0"), 10, now)
+		writeFile(t, filepath.Join(store, "2.parts.json"), 10, now)
+		writeFile(t, filepath.Join(store, "3.part.0"), 10, now)
+		writeFile(t, filepath.Join(base, "jobs", "a", "video-1.part.0"), 10, now)
+		return base
+	}
+
+	t.Run("keep_resumable", func(t *testing.T) {
+		t.Parallel()
+
+		base := setup(t)
+		store := filepath.Join(base, "tracks", "lossless")
+		reclaimed, err := fs.DownloadDirFrom(base).SweepIncomplete(true)
+		require.NoError(t, err)
+		assert.Equal(t, fs.Reclaimed{Entries: 3, Bytes: 30}, *reclaimed)
+		assert.FileExists(t, filepath.Join(store, "1"))
+		assert.NoFileExists(t, filepath.Join(store, "1.tag.tmp"))
+		assert.FileExists(t, filepath.Join(store, "2.part.0"))
+		assert.FileExists(t, filepath.Join(store, "2.parts.json"))
+		assert.NoFileExists(t, filepath.Join(store, "3.part.0"))
+		assert.NoFileExists(t, filepath.Join(base, "jobs", "a", "video-1.part.0"))
+	})
+
+	t.Run("remove_resumable", func(t *testing.T) {
+		t.Parallel()
+
+		base := setup(t)
+		store := filepath.Join(base, "tracks", "lossless")
+		reclaimed, err := fs.DownloadDirFrom(base).SweepIncomplete(false)
+		require.NoError(t, err)
+		assert.Equal(t, fs.Reclaimed{Entries: 5, Bytes: 50}, *reclaimed)
+		assert.FileExists(t, filepath.Join(store, "1"))
+		assert.FileExists(t, filepath.Join(store, "1.json"))
+		assert.NoFileExists(t, filepath.Join(store, "2.part.0"))
+		assert.NoFileExists(t, filepath.Join(store, "2.parts.json"))
+	})
+}
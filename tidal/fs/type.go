@@ -5,8 +5,9 @@ import (
 )
 
 type StoredMix struct {
-	Caption  string   `json:"caption"`
-	TrackIDs []string `json:"track_ids"`
+	Caption string `json:"caption"`
+	// TrackKeys are track store keys of tracks, as returned by TrackKey.
+	TrackKeys []string `json:"track_keys"`
 }
 
 type TrackInfo struct {
@@ -24,11 +25,22 @@ type StoredSingleTrack struct {
 }
 
 type StoredPlaylist struct {
-	Caption  string   `json:"caption"`
-	TrackIDs []string `json:"track_ids"`
+	Caption string `json:"caption"`
+	// TrackKeys are track store keys of tracks, as returned by TrackKey.
+	TrackKeys []string `json:"track_keys"`
 }
 
 type StoredAlbum struct {
 	Caption        string     `json:"caption"`
 	VolumeTrackIDs [][]string `json:"volume_track_ids"`
 }
+
+type StoredVideo struct {
+	Artists  []tidal.TrackArtist `json:"artists"`
+	Title    string              `json:"title"`
+	Version  *string             `json:"version"`
+	Duration int                 `json:"duration"`
+	Width    int                 `json:"width"`
+	Height   int                 `json:"height"`
+	Caption  string              `json:"caption"`
+}
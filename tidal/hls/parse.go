@@ -0,0 +1,222 @@
+package hls
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/xeptore/flaw/v8"
+
+	"github.com/xeptore/tgtd/errutil"
+	"github.com/xeptore/tgtd/must"
+)
+
+const (
+	tagHeader    = "#EXTM3U"
+	tagStreamInf = "#EXT-X-STREAM-INF:"
+	tagInf       = "#EXTINF:"
+)
+
+type Variant struct {
+	URL       string
+	Bandwidth int
+	Width     int
+	Height    int
+	Codecs    string
+}
+
+func (v Variant) FlawP() flaw.P {
+	return flaw.P{
+		"url":       v.URL,
+		"bandwidth": v.Bandwidth,
+		"width":     v.Width,
+		"height":    v.Height,
+		"codecs":    v.Codecs,
+	}
+}
+
+type MediaPlaylist struct {
+	SegmentURLs []string
+	Duration    float64
+}
+
+// ParseMaster parses a master playlist and returns its variant streams. Relative
+// variant URIs are resolved against base.
+func ParseMaster(r io.Reader, base *url.URL) ([]Variant, error) {
+	lines, err := readLines(r)
+	if nil != err {
+		return nil, err
+	}
+
+	var (
+		variants []Variant
+		pending  *Variant
+	)
+	for i, line := range lines {
+		flawP := flaw.P{"line_number": i + 1, "line": line}
+		switch {
+		case strings.HasPrefix(line, tagStreamInf):
+			v, err := parseStreamInf(strings.TrimPrefix(line, tagStreamInf))
+			if nil != err {
+				return nil, must.BeFlaw(err).Append(flawP)
+			}
+			pending = v
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			if nil == pending {
+				return nil, flaw.From(errors.New("variant URI without preceding stream info tag")).Append(flawP)
+			}
+			u, err := base.Parse(line)
+			if nil != err {
+				flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+				return nil, flaw.From(fmt.Errorf("failed to resolve variant URI: %v", err)).Append(flawP)
+			}
+			pending.URL = u.String()
+			variants = append(variants, *pending)
+			pending = nil
+		}
+	}
+
+	if len(variants) == 0 {
+		return nil, flaw.From(errors.New("master playlist has no variants"))
+	}
+
+	return variants, nil
+}
+
+// ParseMedia parses a media playlist and returns its segment URLs in order.
+// Relative segment URIs are resolved against base.
+func ParseMedia(r io.Reader, base *url.URL) (*MediaPlaylist, error) {
+	lines, err := readLines(r)
+	if nil != err {
+		return nil, err
+	}
+
+	var (
+		out         MediaPlaylist
+		expectedURI bool
+	)
+	for i, line := range lines {
+		flawP := flaw.P{"line_number": i + 1, "line": line}
+		switch {
+		case strings.HasPrefix(line, tagInf):
+			durationStr, _, _ := strings.Cut(strings.TrimPrefix(line, tagInf), ",")
+			duration, err := strconv.ParseFloat(durationStr, 64)
+			if nil != err {
+				flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+				return nil, flaw.From(fmt.Errorf("failed to parse segment duration: %v", err)).Append(flawP)
+			}
+			out.Duration += duration
+			expectedURI = true
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			if !expectedURI {
+				return nil, flaw.From(errors.New("segment URI without preceding duration tag")).Append(flawP)
+			}
+			u, err := base.Parse(line)
+			if nil != err {
+				flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+				return nil, flaw.From(fmt.Errorf("failed to resolve segment URI: %v", err)).Append(flawP)
+			}
+			out.SegmentURLs = append(out.SegmentURLs, u.String())
+			expectedURI = false
+		}
+	}
+
+	if len(out.SegmentURLs) == 0 {
+		return nil, flaw.From(errors.New("media playlist has no segments"))
+	}
+
+	return &out, nil
+}
+
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); nil != err {
+		flawP := flaw.P{"err_debug_tree": errutil.Tree(err).FlawP()}
+		return nil, flaw.From(fmt.Errorf("failed to read playlist: %v", err)).Append(flawP)
+	}
+
+	if len(lines) == 0 || lines[0] != tagHeader {
+		return nil, flaw.From(errors.New("missing playlist header"))
+	}
+
+	return lines[1:], nil
+}
+
+func parseStreamInf(attrs string) (*Variant, error) {
+	var v Variant
+	for key, value := range splitAttributes(attrs) {
+		flawP := flaw.P{"key": key, "value": value}
+		switch key {
+		case "BANDWIDTH":
+			bandwidth, err := strconv.Atoi(value)
+			if nil != err {
+				flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+				return nil, flaw.From(fmt.Errorf("failed to parse variant bandwidth: %v", err)).Append(flawP)
+			}
+			v.Bandwidth = bandwidth
+		case "RESOLUTION":
+			widthStr, heightStr, found := strings.Cut(value, "x")
+			if !found {
+				return nil, flaw.From(errors.New("invalid variant resolution")).Append(flawP)
+			}
+			width, err := strconv.Atoi(widthStr)
+			if nil != err {
+				flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+				return nil, flaw.From(fmt.Errorf("failed to parse variant width: %v", err)).Append(flawP)
+			}
+			height, err := strconv.Atoi(heightStr)
+			if nil != err {
+				flawP["err_debug_tree"] = errutil.Tree(err).FlawP()
+				return nil, flaw.From(fmt.Errorf("failed to parse variant height: %v", err)).Append(flawP)
+			}
+			v.Width, v.Height = width, height
+		case "CODECS":
+			v.Codecs = value
+		}
+	}
+	return &v, nil
+}
+
+// splitAttributes splits an attribute list into key-value pairs, honoring commas
+// inside quoted values.
+func splitAttributes(attrs string) map[string]string {
+	out := make(map[string]string)
+	var (
+		inQuotes bool
+		start    int
+	)
+	for i := 0; i <= len(attrs); i++ {
+		if i < len(attrs) {
+			switch attrs[i] {
+			case '"':
+				inQuotes = !inQuotes
+				continue
+			case ',':
+				if inQuotes {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		if key, value, found := strings.Cut(attrs[start:i], "="); found {
+			out[strings.TrimSpace(key)] = strings.Trim(value, `"`)
+		}
+		start = i + 1
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package hls_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/tidal/hls"
+)
+
+func TestParseMaster(t *testing.T) {
+	t.Parallel()
+
+	const playlist = `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1101000,AVERAGE-BANDWIDTH=1101000,CODECS="avc1.4d401f,mp4a.40.2",RESOLUTION=640x360
+https://vmz-ad-cf.video.tidal.com/360p/index.m3u8?token=abc
+#EXT-X-STREAM-INF:BANDWIDTH=4747000,CODECS="avc1.640028,mp4a.40.2",RESOLUTION=1920x1080
+1080p/index.m3u8
+`
+
+	base, err := url.Parse("https://vmz-ad-cf.video.tidal.com/master.m3u8")
+	require.NoError(t, err)
+
+	variants, err := hls.ParseMaster(strings.NewReader(playlist), base)
+	require.NoError(t, err)
+	require.Len(t, variants, 2)
+
+	assert.Exactly(t, "https://vmz-ad-cf.video.tidal.com/360p/index.m3u8?token=abc", variants[0].URL)
+	assert.Exactly(t, 1101000, variants[0].Bandwidth)
+	assert.Exactly(t, 640, variants[0].Width)
+	assert.Exactly(t, 360, variants[0].Height)
+	assert.Exactly(t, "avc1.4d401f,mp4a.40.2", variants[0].Codecs)
+
+	assert.Exactly(t, "https://vmz-ad-cf.video.tidal.com/1080p/index.m3u8", variants[1].URL)
+	assert.Exactly(t, 1920, variants[1].Width)
+	assert.Exactly(t, 1080, variants[1].Height)
+}
+
+func TestParseMedia(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+
+		const playlist = `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:10
+#EXT-X-MEDIA-SEQUENCE:0
+#EXTINF:10.0,
+segment-0.ts
+#EXTINF:4.5,
+https://cdn.example.com/segment-1.ts
+#EXT-X-ENDLIST
+`
+
+		base, err := url.Parse("https://vmz-ad-cf.video.tidal.com/1080p/index.m3u8")
+		require.NoError(t, err)
+
+		media, err := hls.ParseMedia(strings.NewReader(playlist), base)
+		require.NoError(t, err)
+		assert.Exactly(t, []string{
+			"https://vmz-ad-cf.video.tidal.com/1080p/segment-0.ts",
+			"https://cdn.example.com/segment-1.ts",
+		}, media.SegmentURLs)
+		assert.InDelta(t, 14.5, media.Duration, 0.001)
+	})
+
+	t.Run("missing_header", func(t *testing.T) {
+		t.Parallel()
+
+		base, err := url.Parse("https://vmz-ad-cf.video.tidal.com/1080p/index.m3u8")
+		require.NoError(t, err)
+
+		_, err = hls.ParseMedia(strings.NewReader("#EXTINF:10.0,\nsegment-0.ts\n"), base)
+		require.Error(t, err)
+	})
+}
@@ -5,6 +5,12 @@ import (
 	"strings"
 )
 
+// Link returns the TIDAL link of the item with the given kind, and ID, e.g.,
+// album, and its numeric ID.
+func Link(kind, id string) string {
+	return "https://tidal.com/browse/" + kind + "/" + url.PathEscape(id)
+}
+
 func IsLink(text string) bool {
 	u, err := url.Parse(text)
 	if nil != err {
@@ -25,3 +25,21 @@ func TestIsLink(t *testing.T) {
 		}
 	})
 }
+
+func TestLink(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string][2]string{
+		"https://tidal.com/browse/album/123456789":                               {"album", "123456789"},
+		"https://tidal.com/browse/playlist/0a1b2c3d-0a1b-0a1b-0a1b-0a1b2c3d4e5f": {"playlist", "0a1b2c3d-0a1b-0a1b-0a1b-0a1b2c3d4e5f"},
+	}
+	for expected, args := range tests {
+		link := tidal.Link(args[0], args[1])
+		if link != expected {
+			t.Errorf("expected link %s, got %s", expected, link)
+		}
+		if !tidal.IsLink(link) {
+			t.Errorf("expected %s to be a valid Tidal link", link)
+		}
+	}
+}
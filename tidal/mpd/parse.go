@@ -121,13 +121,16 @@ func (s S) flawP() flaw.P {
 type StreamInfo struct {
 	Codec    string
 	MimeType string
-	Parts    Parts
+	// Bandwidth is the bitrate of the stream in bits per second.
+	Bandwidth int
+	Parts     Parts
 }
 
 func (si StreamInfo) FlawP() flaw.P {
 	return flaw.P{
-		"codec": si.Codec,
-		"parts": si.Parts.FlawP(),
+		"codec":     si.Codec,
+		"bandwidth": si.Bandwidth,
+		"parts":     si.Parts.FlawP(),
 	}
 }
 
@@ -179,8 +182,9 @@ func ParseStreamInfo(r io.Reader) (*StreamInfo, error) {
 	}
 
 	return &StreamInfo{
-		Codec:    mpd.Period.AdaptationSet.Representation.Codecs,
-		MimeType: mpd.Period.AdaptationSet.MimeType,
-		Parts:    *parts,
+		Codec:     mpd.Period.AdaptationSet.Representation.Codecs,
+		MimeType:  mpd.Period.AdaptationSet.MimeType,
+		Bandwidth: mpd.Period.AdaptationSet.Representation.Bandwidth,
+		Parts:     *parts,
 	}, nil
 }
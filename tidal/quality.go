@@ -0,0 +1,45 @@
+package tidal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Quality string
+
+const (
+	QualityLow           Quality = "LOW"
+	QualityHigh          Quality = "HIGH"
+	QualityLossless      Quality = "LOSSLESS"
+	QualityHiResLossless Quality = "HI_RES_LOSSLESS"
+)
+
+// Qualities lists all supported audio quality tiers from the lowest to the highest.
+var Qualities = []Quality{QualityLow, QualityHigh, QualityLossless, QualityHiResLossless}
+
+// ParseQuality parses s case-insensitively into one of the supported quality tiers.
+// Dashes and spaces are accepted in place of underscores, e.g., hi-res-lossless.
+func ParseQuality(s string) (Quality, error) {
+	normalized := strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToUpper(strings.TrimSpace(s)))
+	switch q := Quality(normalized); q {
+	case QualityLow, QualityHigh, QualityLossless, QualityHiResLossless:
+		return q, nil
+	case "HIRES", "HI_RES":
+		return QualityHiResLossless, nil
+	default:
+		return "", fmt.Errorf("unsupported audio quality %q", s)
+	}
+}
+
+// Fallbacks returns q followed by the lower tiers in order they should be tried
+// if q is not available.
+func (q Quality) Fallbacks() []Quality {
+	idx := slices.Index(Qualities, q)
+	if idx == -1 {
+		return nil
+	}
+	out := slices.Clone(Qualities[:idx+1])
+	slices.Reverse(out)
+	return out
+}
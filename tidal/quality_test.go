@@ -0,0 +1,52 @@
+package tidal_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/tidal"
+)
+
+func TestParseQuality(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+
+		tests := map[string]tidal.Quality{
+			"low":             tidal.QualityLow,
+			"HIGH":            tidal.QualityHigh,
+			" Lossless ":      tidal.QualityLossless,
+			"hi_res_lossless": tidal.QualityHiResLossless,
+			"hi-res-lossless": tidal.QualityHiResLossless,
+			"hires":           tidal.QualityHiResLossless,
+		}
+		for input, expected := range tests {
+			q, err := tidal.ParseQuality(input)
+			require.NoError(t, err, input)
+			assert.Exactly(t, expected, q, input)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tidal.ParseQuality("master")
+		require.Error(t, err)
+	})
+}
+
+func TestQualityFallbacks(t *testing.T) {
+	t.Parallel()
+
+	assert.Exactly(
+		t,
+		[]tidal.Quality{tidal.QualityHiResLossless, tidal.QualityLossless, tidal.QualityHigh, tidal.QualityLow},
+		tidal.QualityHiResLossless.Fallbacks(),
+	)
+	assert.Exactly(t, []tidal.Quality{tidal.QualityHigh, tidal.QualityLow}, tidal.QualityHigh.Fallbacks())
+	assert.Exactly(t, []tidal.Quality{tidal.QualityLow}, tidal.QualityLow.Fallbacks())
+	assert.Nil(t, tidal.Quality("").Fallbacks())
+}
@@ -0,0 +1,12 @@
+package tidal
+
+const (
+	DefaultCountryCode = "US"
+	DefaultLocale      = "en_US"
+)
+
+// Region holds parameters TIDAL uses to localize catalog and playback responses.
+type Region struct {
+	CountryCode string
+	Locale      string
+}
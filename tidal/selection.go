@@ -0,0 +1,181 @@
+package tidal
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Selection limits an album, playlist, or mix to a subset of its tracks.
+type Selection struct {
+	// Ranges are the selected ranges of 1-based track positions. Empty Ranges
+	// select all tracks.
+	Ranges []TrackRange `json:"ranges,omitempty"`
+	// Disc is the album volume number tracks are selected from, or zero for all
+	// volumes. With Disc set, track positions are relative to the volume.
+	Disc int `json:"disc,omitempty"`
+	// TrackIDs are IDs of the selected tracks. Empty TrackIDs select tracks
+	// regardless of their IDs.
+	TrackIDs []string `json:"track_ids,omitempty"`
+}
+
+// TrackRange is an inclusive range of track positions.
+type TrackRange struct {
+	From int `json:"from"`
+	// To is zero for ranges lasting until the last track.
+	To int `json:"to,omitempty"`
+}
+
+func (r TrackRange) String() string {
+	switch r.To {
+	case 0:
+		return strconv.Itoa(r.From) + "-"
+	case r.From:
+		return strconv.Itoa(r.From)
+	default:
+		return strconv.Itoa(r.From) + "-" + strconv.Itoa(r.To)
+	}
+}
+
+// ParseSelection parses a selection of comma-separated track positions, and
+// ranges, e.g., 1-5,8,20-, optionally along with an album volume in the form of
+// --disc 2.
+func ParseSelection(s string) (*Selection, error) {
+	var (
+		sel    Selection
+		ranges []string
+		fields = strings.Fields(s)
+	)
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if disc, ok := strings.CutPrefix(field, "--disc"); ok {
+			switch {
+			case strings.HasPrefix(disc, "="):
+				disc = disc[1:]
+			case disc == "" && i+1 < len(fields):
+				i++
+				disc = fields[i]
+			case disc == "":
+				return nil, errors.New("missing --disc value")
+			default:
+				return nil, fmt.Errorf("unsupported option %q", field)
+			}
+			if sel.Disc != 0 {
+				return nil, errors.New("--disc is specified more than once")
+			}
+			n, err := strconv.Atoi(disc)
+			if nil != err || n < 1 {
+				return nil, fmt.Errorf("invalid disc number %q", disc)
+			}
+			sel.Disc = n
+			continue
+		}
+		if strings.HasPrefix(field, "-") {
+			return nil, fmt.Errorf("unsupported option %q", field)
+		}
+		ranges = append(ranges, field)
+	}
+
+	if len(ranges) > 0 {
+		for part := range strings.SplitSeq(strings.Join(ranges, ""), ",") {
+			r, err := parseTrackRange(part)
+			if nil != err {
+				return nil, err
+			}
+			sel.Ranges = append(sel.Ranges, *r)
+		}
+	}
+
+	if sel.Disc == 0 && len(sel.Ranges) == 0 {
+		return nil, errors.New("empty selection")
+	}
+	return &sel, nil
+}
+
+func parseTrackRange(s string) (*TrackRange, error) {
+	from, to, isRange := strings.Cut(s, "-")
+	start, err := strconv.Atoi(from)
+	if nil != err || start < 1 {
+		return nil, fmt.Errorf("invalid track position %q", s)
+	}
+	if !isRange {
+		return &TrackRange{From: start, To: start}, nil
+	}
+	if to == "" {
+		return &TrackRange{From: start, To: 0}, nil
+	}
+	end, err := strconv.Atoi(to)
+	if nil != err || end < start {
+		return nil, fmt.Errorf("invalid track range %q", s)
+	}
+	return &TrackRange{From: start, To: end}, nil
+}
+
+// Includes reports whether the track with id at position of album volume is
+// selected. Position is relative to the volume if Disc is set, and to the whole
+// album, playlist, or mix otherwise. Volume is zero for playlist, and mix tracks.
+// Matcher should be used instead to check many tracks against many TrackIDs.
+func (s Selection) Includes(volume, position int, id string) bool {
+	return s.includes(volume, position, len(s.TrackIDs) == 0 || slices.Contains(s.TrackIDs, id))
+}
+
+// Matcher returns a function which reports whether a track is selected the same
+// way as Includes does, looking IDs up in a set of TrackIDs built once. It
+// selects all tracks if s is nil.
+func (s *Selection) Matcher() func(volume, position int, id string) bool {
+	if nil == s {
+		return func(int, int, string) bool { return true }
+	}
+
+	ids := make(map[string]struct{}, len(s.TrackIDs))
+	for _, id := range s.TrackIDs {
+		ids[id] = struct{}{}
+	}
+	return func(volume, position int, id string) bool {
+		_, ok := ids[id]
+		return s.includes(volume, position, len(ids) == 0 || ok)
+	}
+}
+
+// includes reports whether the track at position of album volume is selected,
+// given whether its ID is selected.
+func (s Selection) includes(volume, position int, idSelected bool) bool {
+	if s.Disc != 0 && s.Disc != volume {
+		return false
+	}
+	if !idSelected {
+		return false
+	}
+	if len(s.Ranges) == 0 {
+		return true
+	}
+	for _, r := range s.Ranges {
+		if position >= r.From && (r.To == 0 || position <= r.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the selection, e.g., tracks 1-5, 8 of disc 2.
+func (s Selection) String() string {
+	var parts []string
+	if len(s.Ranges) > 0 {
+		ranges := make([]string, len(s.Ranges))
+		for i, r := range s.Ranges {
+			ranges[i] = r.String()
+		}
+		parts = append(parts, "tracks "+strings.Join(ranges, ", "))
+	}
+	if n := len(s.TrackIDs); n == 1 {
+		parts = append(parts, "1 specific track")
+	} else if n > 1 {
+		parts = append(parts, strconv.Itoa(n)+" specific tracks")
+	}
+	if s.Disc != 0 {
+		parts = append(parts, "disc "+strconv.Itoa(s.Disc))
+	}
+	return strings.Join(parts, " of ")
+}
@@ -0,0 +1,120 @@
+package tidal_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xeptore/tgtd/tidal"
+)
+
+func TestParseSelection(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+
+		tests := map[string]tidal.Selection{
+			"1-5,8": {
+				Ranges:   []tidal.TrackRange{{From: 1, To: 5}, {From: 8, To: 8}},
+				Disc:     0,
+				TrackIDs: nil,
+			},
+			"1-5, 8": {
+				Ranges:   []tidal.TrackRange{{From: 1, To: 5}, {From: 8, To: 8}},
+				Disc:     0,
+				TrackIDs: nil,
+			},
+			"20-": {
+				Ranges:   []tidal.TrackRange{{From: 20, To: 0}},
+				Disc:     0,
+				TrackIDs: nil,
+			},
+			"--disc 2": {
+				Ranges:   nil,
+				Disc:     2,
+				TrackIDs: nil,
+			},
+			"3-7 --disc=2": {
+				Ranges:   []tidal.TrackRange{{From: 3, To: 7}},
+				Disc:     2,
+				TrackIDs: nil,
+			},
+		}
+		for input, expected := range tests {
+			sel, err := tidal.ParseSelection(input)
+			require.NoError(t, err, input)
+			assert.Equal(t, expected, *sel, input)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []string{"", "0", "5-3", "a-b", "1,,2", "--disc", "--disc 0", "--disc 1 --disc 2", "--volume 1"}
+		for _, input := range tests {
+			_, err := tidal.ParseSelection(input)
+			require.Error(t, err, input)
+		}
+	})
+}
+
+func TestSelection(t *testing.T) {
+	t.Parallel()
+
+	t.Run("includes", func(t *testing.T) {
+		t.Parallel()
+
+		sel := tidal.Selection{Ranges: []tidal.TrackRange{{From: 1, To: 2}, {From: 5, To: 0}}, Disc: 0, TrackIDs: nil}
+		assert.True(t, sel.Includes(0, 1, "1"))
+		assert.True(t, sel.Includes(0, 2, "1"))
+		assert.False(t, sel.Includes(0, 3, "1"))
+		assert.True(t, sel.Includes(0, 100, "1"))
+
+		sel = tidal.Selection{Ranges: []tidal.TrackRange{{From: 2, To: 2}}, Disc: 2, TrackIDs: nil}
+		assert.False(t, sel.Includes(1, 2, "1"))
+		assert.True(t, sel.Includes(2, 2, "1"))
+		assert.False(t, sel.Includes(2, 1, "1"))
+
+		sel = tidal.Selection{Ranges: nil, Disc: 0, TrackIDs: []string{"10", "20"}}
+		assert.True(t, sel.Includes(0, 1, "10"))
+		assert.True(t, sel.Includes(0, 7, "20"))
+		assert.False(t, sel.Includes(0, 1, "30"))
+	})
+
+	t.Run("matcher", func(t *testing.T) {
+		t.Parallel()
+
+		sel := &tidal.Selection{Ranges: []tidal.TrackRange{{From: 2, To: 0}}, Disc: 1, TrackIDs: []string{"10", "20"}}
+		includes := sel.Matcher()
+		assert.True(t, includes(1, 2, "10"))
+		assert.False(t, includes(1, 1, "10"))
+		assert.False(t, includes(2, 2, "10"))
+		assert.False(t, includes(1, 2, "30"))
+
+		sel = &tidal.Selection{Ranges: []tidal.TrackRange{{From: 1, To: 1}}, Disc: 0, TrackIDs: nil}
+		includes = sel.Matcher()
+		assert.True(t, includes(0, 1, "30"))
+		assert.False(t, includes(0, 2, "30"))
+
+		sel = nil
+		assert.True(t, sel.Matcher()(3, 100, "30"))
+	})
+
+	t.Run("string", func(t *testing.T) {
+		t.Parallel()
+
+		sel := tidal.Selection{Ranges: []tidal.TrackRange{{From: 1, To: 5}, {From: 8, To: 8}, {From: 20, To: 0}}, Disc: 0, TrackIDs: nil}
+		assert.Equal(t, "tracks 1-5, 8, 20-", sel.String())
+
+		sel.Disc = 2
+		assert.Equal(t, "tracks 1-5, 8, 20- of disc 2", sel.String())
+
+		sel.Ranges = nil
+		assert.Equal(t, "disc 2", sel.String())
+
+		sel = tidal.Selection{Ranges: nil, Disc: 0, TrackIDs: []string{"10", "20"}}
+		assert.Equal(t, "2 specific tracks", sel.String())
+	})
+}